@@ -74,6 +74,8 @@ func checkTextAccess(c context.Context, r *http.Request, tag string, id int64) (
 		return nil, checkJobTextAccess(c, r, "Patch", id)
 	case textError:
 		return nil, checkJobTextAccess(c, r, "Error", id)
+	case textBisectLog:
+		return nil, checkJobTextAccess(c, r, "BisectLog", id)
 	case textKernelConfig:
 		// This is checked based on text namespace.
 		return nil, nil
@@ -93,7 +95,11 @@ func checkTextAccess(c context.Context, r *http.Request, tag string, id int64) (
 	case textReproSyz:
 		return checkCrashTextAccess(c, r, "ReproSyz", id)
 	case textReproC:
-		return checkCrashTextAccess(c, r, "ReproC", id)
+		crash, err := checkCrashTextAccess(c, r, "ReproC", id)
+		if err == nil || err == ErrAccess {
+			return crash, err
+		}
+		return checkCrashTextAccess(c, r, "ReproCExtra", id)
 	}
 }
 
@@ -223,7 +223,7 @@ func apiJobPoll(c context.Context, r *http.Request, payload []byte) (interface{}
 	if len(req.Managers) == 0 {
 		return nil, fmt.Errorf("no managers")
 	}
-	return pollPendingJobs(c, req.Managers)
+	return pollPendingJobs(c, req.Managers, req.BisectManagers)
 }
 
 func apiJobDone(c context.Context, r *http.Request, payload []byte) (interface{}, error) {
@@ -605,6 +605,20 @@ func reportCrash(c context.Context, build *Build, req *dashapi.Crash) (*Bug, err
 		if !stringInList(bug.HappenedOn, build.Manager) {
 			bug.HappenedOn = append(bug.HappenedOn, build.Manager)
 		}
+		if len(bug.Stack) == 0 {
+			bug.Stack = req.Stack
+		}
+		if bug.Class == "" {
+			bug.Class = crashClass(req.Details)
+		}
+		if severityLevel(req.Severity) > severityLevel(bug.Severity) {
+			bug.Severity = req.Severity
+		}
+		for _, label := range req.Labels {
+			if !stringInList(bug.Labels, label) {
+				bug.Labels = append(bug.Labels, label)
+			}
+		}
 		if _, err = datastore.Put(c, bugKey, bug); err != nil {
 			return fmt.Errorf("failed to put bug: %v", err)
 		}
@@ -636,8 +650,27 @@ func saveCrash(c context.Context, ns string, req *dashapi.Crash, bugKey *datasto
 		Time:        timeNow(c),
 		Maintainers: req.Maintainers,
 		ReproOpts:   req.ReproOpts,
+		Labels:      req.Labels,
 		ReportLen:   prio,
 	}
+	if req.ReproReliability != nil {
+		crash.ReproReliability = *req.ReproReliability
+	}
+	if d := req.Details; d != nil {
+		crash.Details = CrashDetails{
+			Tool:       d.Tool,
+			BugType:    d.BugType,
+			Access:     d.Access,
+			AccessSize: d.AccessSize,
+			Address:    int64(d.Address),
+			Task:       d.Task,
+			PID:        d.PID,
+			CallTrace:  d.CallTrace,
+			AllocStack: d.AllocStack,
+			FreeStack:  d.FreeStack,
+			LockChain:  d.LockChain,
+		}
+	}
 	var err error
 	if crash.Log, err = putText(c, ns, textCrashLog, req.Log, false); err != nil {
 		return err
@@ -651,6 +684,13 @@ func saveCrash(c context.Context, ns string, req *dashapi.Crash, bugKey *datasto
 	if crash.ReproC, err = putText(c, ns, textReproC, req.ReproC, false); err != nil {
 		return err
 	}
+	for _, reproC := range req.ReproCExtra {
+		id, err := putText(c, ns, textReproC, reproC, false)
+		if err != nil {
+			return err
+		}
+		crash.ReproCExtra = append(crash.ReproCExtra, id)
+	}
 	crashKey := datastore.NewIncompleteKey(c, "Crash", bugKey)
 	if _, err = datastore.Put(c, crashKey, crash); err != nil {
 		return fmt.Errorf("failed to put crash: %v", err)
@@ -658,6 +698,37 @@ func saveCrash(c context.Context, ns string, req *dashapi.Crash, bugKey *datasto
 	return nil
 }
 
+// crashClass returns bug class used to query/sort bugs (e.g. "KASAN: use-after-free"),
+// or empty string if the crash does not have structured details.
+func crashClass(details *dashapi.CrashDetails) string {
+	if details == nil || details.Tool == "" {
+		return ""
+	}
+	if details.BugType == "" {
+		return details.Tool
+	}
+	return details.Tool + ": " + details.BugType
+}
+
+// severityLevel returns relative order of crash severities (see dashapi.Crash.Severity),
+// unknown/empty severity is the lowest.
+func severityLevel(severity string) int {
+	switch severity {
+	case "low":
+		return 1
+	case "medium":
+		return 2
+	case "high":
+		return 3
+	case "severe":
+		return 4
+	case "critical":
+		return 5
+	default:
+		return 0
+	}
+}
+
 func purgeOldCrashes(c context.Context, bug *Bug, bugKey *datastore.Key) {
 	const purgeEvery = 10
 	if bug.NumCrashes <= 2*maxCrashes || (bug.NumCrashes-1)%purgeEvery != 0 {
@@ -715,6 +786,9 @@ func purgeOldCrashes(c context.Context, bug *Bug, bugKey *datastore.Key) {
 		if crash.ReproC != 0 {
 			toDelete = append(toDelete, datastore.NewKey(c, textReproC, "", crash.ReproC, nil))
 		}
+		for _, id := range crash.ReproCExtra {
+			toDelete = append(toDelete, datastore.NewKey(c, textReproC, "", id, nil))
+		}
 		deleted++
 		if deleted == 2*purgeEvery {
 			break
@@ -288,6 +288,99 @@ func TestApp(t *testing.T) {
 	})
 }
 
+// Test that crash details are stored and bugs can be filtered by class.
+func TestCrashDetails(t *testing.T) {
+	c := NewCtx(t)
+	defer c.Close()
+
+	build := testBuild(1)
+	c.client.UploadBuild(build)
+
+	var addr uint64 = 0xffff8801d3b1a1f8
+	crash1 := testCrash(build, 1)
+	crash1.Details = &dashapi.CrashDetails{
+		Tool:       "KASAN",
+		BugType:    "use-after-free",
+		Access:     "read",
+		AccessSize: 8,
+		Address:    addr,
+		Task:       "syz-executor0",
+		PID:        4411,
+		CallTrace:  []string{"tcp_sendmsg", "inet_sendmsg"},
+		AllocStack: []string{"kmem_cache_alloc", "sk_prot_alloc"},
+		FreeStack:  []string{"kmem_cache_free", "sk_destruct"},
+	}
+	c.client.ReportCrash(crash1)
+	rep1 := c.client.pollBug()
+
+	crash2 := testCrash(build, 2)
+	crash2.Details = &dashapi.CrashDetails{
+		Tool:      "LOCKDEP",
+		BugType:   "circular-locking",
+		Task:      "syz-executor1",
+		PID:       4412,
+		LockChain: []string{"sk_lock-AF_INET", "rtnl_mutex"},
+	}
+	c.client.ReportCrash(crash2)
+	c.client.pollBug()
+
+	crash3 := testCrash(build, 3)
+	c.client.ReportCrash(crash3)
+	c.client.pollBug()
+
+	bug, crash, _ := c.loadBug(rep1.ID)
+	c.expectEQ(bug.Class, "KASAN: use-after-free")
+	c.expectEQ(crash.Details, CrashDetails{
+		Tool:       "KASAN",
+		BugType:    "use-after-free",
+		Access:     "read",
+		AccessSize: 8,
+		Address:    int64(addr),
+		Task:       "syz-executor0",
+		PID:        4411,
+		CallTrace:  []string{"tcp_sendmsg", "inet_sendmsg"},
+		AllocStack: []string{"kmem_cache_alloc", "sk_prot_alloc"},
+		FreeStack:  []string{"kmem_cache_free", "sk_destruct"},
+	})
+
+	reply, err := c.AuthGET(AccessAdmin, "/?class=KASAN%3A+use-after-free")
+	c.expectOK(err)
+	c.expectTrue(strings.Contains(string(reply), crash1.Title))
+	c.expectTrue(!strings.Contains(string(reply), crash2.Title))
+	c.expectTrue(!strings.Contains(string(reply), crash3.Title))
+
+	reply, err = c.AuthGET(AccessAdmin, "/")
+	c.expectOK(err)
+	c.expectTrue(strings.Contains(string(reply), crash1.Title))
+	c.expectTrue(strings.Contains(string(reply), crash2.Title))
+	c.expectTrue(strings.Contains(string(reply), crash3.Title))
+}
+
+func TestCrashLabels(t *testing.T) {
+	c := NewCtx(t)
+	defer c.Close()
+
+	build := testBuild(1)
+	c.client.UploadBuild(build)
+
+	crash1 := testCrash(build, 1)
+	crash1.Labels = []string{"net", "flaky"}
+	c.client.ReportCrash(crash1)
+	rep := c.client.pollBug()
+
+	crash2 := testCrash(build, 1)
+	crash2.Labels = []string{"flaky", "needs-triage"}
+	c.client.ReportCrash(crash2)
+
+	bug, _, _ := c.loadBug(rep.ID)
+	c.expectEQ(bug.Labels, []string{"net", "flaky", "needs-triage"})
+
+	reply, err := c.AuthGET(AccessAdmin, "/bug?id="+bug.keyHash())
+	c.expectOK(err)
+	c.expectTrue(strings.Contains(string(reply), "net, flaky"))
+	c.expectTrue(strings.Contains(string(reply), "flaky, needs-triage"))
+}
+
 // Test purging of old crashes for bugs with lots of crashes.
 func TestPurgeOldCrashes(t *testing.T) {
 	if testing.Short() {
@@ -83,6 +83,10 @@ type Bug struct {
 	Commits        []string
 	HappenedOn     []string `datastore:",noindex"` // list of managers
 	PatchedOn      []string `datastore:",noindex"` // list of managers
+	Stack          []string // stack signature of the first crash that has one (indexed for possible dups lookup)
+	Class          string   // bug class, e.g. "KASAN: use-after-free" (see crashClass)
+	Severity       string   // highest severity of the crashes (low/medium/high/severe/critical)
+	Labels         []string `datastore:",noindex"` // union of labels of the crashes
 }
 
 type BugReporting struct {
@@ -108,12 +112,34 @@ type Crash struct {
 	ReproOpts   []byte    `datastore:",noindex"`
 	ReproSyz    int64     // reference to ReproSyz text entity
 	ReproC      int64     // reference to ReproC text entity
+	ReproCExtra []int64   // references to ReproC text entities of other programs of a multi-program repro
+	// Reliability of the reproducer (zero if not measured).
+	ReproReliability dashapi.ReproReliability `datastore:",noindex"`
+	// Structured KASAN/KMSAN/UBSAN/lockdep details (empty if the report was not parsed).
+	Details CrashDetails `datastore:",noindex"`
+	Labels  []string     `datastore:",noindex"` // attached by manager crash rules
 	// Custom crash priority for reporting (greater values are higher priority).
 	// For example, a crash in mainline kernel has higher priority than a crash in a side branch.
 	// For historical reasons this is called ReportLen.
 	ReportLen int64
 }
 
+// CrashDetails is datastore representation of dashapi.CrashDetails
+// (datastore does not support unsigned integers, so Address is stored as int64).
+type CrashDetails struct {
+	Tool       string
+	BugType    string
+	Access     string
+	AccessSize int
+	Address    int64
+	Task       string
+	PID        int
+	CallTrace  []string
+	AllocStack []string
+	FreeStack  []string
+	LockChain  []string
+}
+
 // ReportingState holds dynamic info associated with reporting.
 type ReportingState struct {
 	Entries []ReportingStateEntry
@@ -127,14 +153,14 @@ type ReportingStateEntry struct {
 	Date int // YYYYMMDD
 }
 
-// Job represent a single patch testing job for syz-ci.
+// Job represent a single patch testing or bisection job for syz-ci.
 // Later we may want to extend this to other types of jobs (hense the generic name):
 //   - test of a committed fix
 //   - reproduce crash
 //   - test that crash still happens on HEAD
-//   - crash bisect
 // Job has Bug as parent entity.
 type Job struct {
+	Type      dashapi.JobType
 	Created   time.Time
 	User      string
 	CC        []string
@@ -151,6 +177,9 @@ type Job struct {
 	KernelBranch string
 	Patch        int64 // reference to Patch text entity
 
+	// Provided by dashboard for bisection jobs:
+	KernelCommit string // commit where the crash happened
+
 	Attempts int // number of times we tried to execute this job
 	Started  time.Time
 	Finished time.Time // if set, job is finished
@@ -162,9 +191,27 @@ type Job struct {
 	BuildID     string
 	Error       int64 // reference to Error text entity, if set job failed
 
+	// Result of bisection:
+	BisectCommit      string          // empty if nothing was found
+	BisectCommitTitle string          `datastore:",noindex"`
+	BisectCC          []string        `datastore:",noindex"`
+	BisectConfidence  float64         `datastore:",noindex"`
+	BisectSteps       []JobBisectStep `datastore:",noindex"`
+	BisectLog         int64           // reference to BisectLog text entity
+
 	Reported bool // have we reported result back to user?
 }
 
+// JobBisectStep is result of testing of a single revision during bisection (see dashapi.BisectStep).
+type JobBisectStep struct {
+	Commit  string
+	Title   string
+	Result  string
+	Crashed int
+	Total   int
+	Titles  string // |-delimited list of crash titles
+}
+
 // Text holds text blobs (crash logs, reports, reproducers, etc).
 type Text struct {
 	Namespace string
@@ -179,6 +226,7 @@ const (
 	textKernelConfig = "KernelConfig"
 	textPatch        = "Patch"
 	textError        = "Error"
+	textBisectLog    = "BisectLog"
 )
 
 const (
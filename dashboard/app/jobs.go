@@ -186,9 +186,10 @@ func checkTestJob(c context.Context, bug *Bug, bugReporting *BugReporting, crash
 }
 
 // pollPendingJobs returns the next job to execute for the provided list of managers.
-func pollPendingJobs(c context.Context, managers []string) (interface{}, error) {
+// Bisection jobs are returned only for bisectManagers.
+func pollPendingJobs(c context.Context, managers, bisectManagers []string) (interface{}, error) {
 retry:
-	job, jobKey, err := loadPendingJob(c, managers)
+	job, jobKey, err := loadPendingJob(c, managers, bisectManagers)
 	if job == nil || err != nil {
 		return job, err
 	}
@@ -250,9 +251,11 @@ retry:
 	}
 	resp := &dashapi.JobPollResp{
 		ID:              jobID,
+		Type:            job.Type,
 		Manager:         job.Manager,
 		KernelRepo:      job.KernelRepo,
 		KernelBranch:    job.KernelBranch,
+		KernelCommit:    job.KernelCommit,
 		KernelConfig:    kernelConfig,
 		SyzkallerCommit: build.SyzkallerCommit,
 		Patch:           patch,
@@ -297,6 +300,11 @@ func doneJob(c context.Context, req *dashapi.JobDoneReq) error {
 		job.BuildID = req.Build.ID
 		job.CrashTitle = req.CrashTitle
 		job.Finished = now
+		if job.Type != dashapi.JobTestPatch {
+			if err := saveBisectResult(c, job, req); err != nil {
+				return err
+			}
+		}
 		if _, err := datastore.Put(c, jobKey, job); err != nil {
 			return fmt.Errorf("failed to put job: %v", err)
 		}
@@ -305,6 +313,35 @@ func doneJob(c context.Context, req *dashapi.JobDoneReq) error {
 	return datastore.RunInTransaction(c, tx, &datastore.TransactionOptions{XG: true, Attempts: 30})
 }
 
+// saveBisectResult stores bisection result in the job.
+// Bisection results are shown on the bug page, but they are not reported, so the job is marked as reported.
+func saveBisectResult(c context.Context, job *Job, req *dashapi.JobDoneReq) error {
+	job.Reported = true
+	res := req.Bisect
+	if res == nil {
+		return nil
+	}
+	var err error
+	if job.BisectLog, err = putText(c, job.Namespace, textBisectLog, res.Log, false); err != nil {
+		return err
+	}
+	job.BisectCommit = res.Commit
+	job.BisectCommitTitle = res.CommitTitle
+	job.BisectCC = res.CC
+	job.BisectConfidence = res.Confidence
+	for _, step := range res.Steps {
+		job.BisectSteps = append(job.BisectSteps, JobBisectStep{
+			Commit:  step.Commit,
+			Title:   step.Title,
+			Result:  step.Result,
+			Crashed: step.Crashed,
+			Total:   step.Total,
+			Titles:  strings.Join(step.Titles, "|"),
+		})
+	}
+	return nil
+}
+
 func pollCompletedJobs(c context.Context, typ string) ([]*dashapi.BugReport, error) {
 	var jobs []*Job
 	keys, err := datastore.NewQuery("Job").
@@ -426,7 +463,7 @@ func jobReported(c context.Context, jobID string) error {
 	return datastore.RunInTransaction(c, tx, nil)
 }
 
-func loadPendingJob(c context.Context, managers []string) (*Job, *datastore.Key, error) {
+func loadPendingJob(c context.Context, managers, bisectManagers []string) (*Job, *datastore.Key, error) {
 	var jobs []*Job
 	keys, err := datastore.NewQuery("Job").
 		Filter("Finished=", time.Time{}).
@@ -440,8 +477,13 @@ func loadPendingJob(c context.Context, managers []string) (*Job, *datastore.Key,
 	for _, mgr := range managers {
 		mgrs[mgr] = true
 	}
+	bisectMgrs := make(map[string]bool)
+	for _, mgr := range bisectManagers {
+		bisectMgrs[mgr] = true
+	}
 	for i, job := range jobs {
-		if !mgrs[job.Manager] {
+		if job.Type == dashapi.JobTestPatch && !mgrs[job.Manager] ||
+			job.Type != dashapi.JobTestPatch && !bisectMgrs[job.Manager] {
 			continue
 		}
 		return job, keys[i], nil
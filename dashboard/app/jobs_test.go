@@ -13,6 +13,7 @@ import (
 
 	"github.com/google/syzkaller/dashboard/dashapi"
 	"github.com/google/syzkaller/pkg/email"
+	"google.golang.org/appengine/datastore"
 )
 
 func TestJob(t *testing.T) {
@@ -82,7 +83,7 @@ func TestJob(t *testing.T) {
 		EmailOptFrom("\"foo\" <blAcklisteD@dOmain.COM>"))
 	c.expectOK(c.GET("/email_poll"))
 	c.expectEQ(len(c.emailSink), 0)
-	pollResp, _ := c.client2.JobPoll([]string{build.Manager})
+	pollResp, _ := c.client2.JobPoll([]string{build.Manager}, nil)
 	c.expectEQ(pollResp.ID, "")
 
 	c.incomingEmail(sender, "#syz test: git://git.git/git.git kernel-branch\n"+patch,
@@ -98,9 +99,9 @@ func TestJob(t *testing.T) {
 	c.expectOK(c.GET("/email_poll"))
 	c.expectEQ(len(c.emailSink), 0)
 
-	pollResp, _ = c.client2.JobPoll([]string{"foobar"})
+	pollResp, _ = c.client2.JobPoll([]string{"foobar"}, nil)
 	c.expectEQ(pollResp.ID, "")
-	pollResp, _ = c.client2.JobPoll([]string{build.Manager})
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, nil)
 	c.expectEQ(pollResp.ID != "", true)
 	c.expectEQ(pollResp.Manager, build.Manager)
 	c.expectEQ(pollResp.KernelRepo, "git://git.git/git.git")
@@ -112,7 +113,7 @@ func TestJob(t *testing.T) {
 	c.expectEQ(pollResp.ReproSyz, []byte("repro syz"))
 	c.expectEQ(pollResp.ReproC, []byte("repro C"))
 
-	pollResp2, _ := c.client2.JobPoll([]string{build.Manager})
+	pollResp2, _ := c.client2.JobPoll([]string{build.Manager}, nil)
 	c.expectEQ(pollResp2, pollResp)
 
 	jobDoneReq := &dashapi.JobDoneReq{
@@ -163,7 +164,7 @@ patch:          %[1]v
 
 	// Testing fails with an error.
 	c.incomingEmail(sender, "#syz test: git://git.git/git.git kernel-branch\n"+patch, EmailOptMessageID(2))
-	pollResp, _ = c.client2.JobPoll([]string{build.Manager})
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, nil)
 	jobDoneReq = &dashapi.JobDoneReq{
 		ID:    pollResp.ID,
 		Build: *build,
@@ -203,7 +204,7 @@ patch:          %[1]v
 
 	// Testing fails with a huge error that can't be inlined in email.
 	c.incomingEmail(sender, "#syz test: git://git.git/git.git kernel-branch\n"+patch, EmailOptMessageID(3))
-	pollResp, _ = c.client2.JobPoll([]string{build.Manager})
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, nil)
 	jobDoneReq = &dashapi.JobDoneReq{
 		ID:    pollResp.ID,
 		Build: *build,
@@ -248,7 +249,7 @@ patch:          %[3]v
 	}
 
 	c.incomingEmail(sender, "#syz test: git://git.git/git.git kernel-branch\n"+patch, EmailOptMessageID(4))
-	pollResp, _ = c.client2.JobPoll([]string{build.Manager})
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, nil)
 	jobDoneReq = &dashapi.JobDoneReq{
 		ID:    pollResp.ID,
 		Build: *build,
@@ -285,7 +286,7 @@ Note: testing is done by a robot and is best-effort only.
 		c.checkURLContents(kernelConfigLink, build.KernelConfig)
 	}
 
-	pollResp, _ = c.client2.JobPoll([]string{build.Manager})
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, nil)
 	c.expectEQ(pollResp.ID, "")
 }
 
@@ -311,7 +312,7 @@ func TestJobWithoutPatch(t *testing.T) {
 	}
 
 	c.incomingEmail(sender, "#syz test: git://mygit.com/git.git 5e6a2eea\n", EmailOptMessageID(1))
-	pollResp, _ := c.client2.JobPoll([]string{build.Manager})
+	pollResp, _ := c.client2.JobPoll([]string{build.Manager}, nil)
 	testBuild := testBuild(2)
 	testBuild.KernelRepo = "git://mygit.com/git.git"
 	testBuild.KernelBranch = ""
@@ -349,7 +350,7 @@ Note: testing is done by a robot and is best-effort only.
 		c.checkURLContents(kernelConfigLink, testBuild.KernelConfig)
 	}
 
-	pollResp, _ = c.client2.JobPoll([]string{build.Manager})
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, nil)
 	c.expectEQ(pollResp.ID, "")
 }
 
@@ -373,13 +374,143 @@ func TestJobRestrictedManager(t *testing.T) {
 	// Testing on a wrong repo must fail and no test jobs passed to manager.
 	c.incomingEmail(sender, "#syz test: git://mygit.com/git.git master\n", EmailOptMessageID(1))
 	c.expectEQ(strings.Contains((<-c.emailSink).Body, "you should test only on restricted.git"), true)
-	pollResp, _ := c.client2.JobPoll([]string{build.Manager})
+	pollResp, _ := c.client2.JobPoll([]string{build.Manager}, nil)
 	c.expectEQ(pollResp.ID, "")
 
 	// Testing on the right repo must succeed.
 	c.incomingEmail(sender, "#syz test: git://restricted.git/restricted.git master\n", EmailOptMessageID(2))
-	pollResp, _ = c.client2.JobPoll([]string{build.Manager})
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, nil)
 	c.expectEQ(pollResp.ID != "", true)
 	c.expectEQ(pollResp.Manager, build.Manager)
 	c.expectEQ(pollResp.KernelRepo, "git://restricted.git/restricted.git")
 }
+
+// addBisectJob puts a bisection job for the only bug in the datastore.
+func (c *Ctx) addBisectJob(typ dashapi.JobType) *datastore.Key {
+	var bugs []*Bug
+	bugKeys, err := datastore.NewQuery("Bug").GetAll(c.ctx, &bugs)
+	c.expectOK(err)
+	c.expectEQ(len(bugs), 1)
+	crashes, crashKeys, err := queryCrashesForBug(c.ctx, bugKeys[0], 1)
+	c.expectOK(err)
+	build, err := loadBuild(c.ctx, bugs[0].Namespace, crashes[0].BuildID)
+	c.expectOK(err)
+	job := &Job{
+		Type:         typ,
+		Created:      timeNow(c.ctx),
+		Namespace:    bugs[0].Namespace,
+		Manager:      crashes[0].Manager,
+		BugTitle:     bugs[0].displayTitle(),
+		CrashID:      crashKeys[0].IntID(),
+		KernelRepo:   build.KernelRepo,
+		KernelBranch: build.KernelBranch,
+		KernelCommit: build.KernelCommit,
+	}
+	_, err = datastore.Put(c.ctx, datastore.NewIncompleteKey(c.ctx, "Job", bugKeys[0]), job)
+	c.expectOK(err)
+	return bugKeys[0]
+}
+
+func TestBisectJob(t *testing.T) {
+	c := NewCtx(t)
+	defer c.Close()
+
+	build := testBuild(1)
+	c.client2.UploadBuild(build)
+	crash := testCrashWithRepro(build, 1)
+	c.client2.ReportCrash(crash)
+	bugKey := c.addBisectJob(dashapi.JobBisectCause)
+
+	// Bisection jobs are returned only for managers that can run them.
+	pollResp, _ := c.client2.JobPoll([]string{build.Manager}, nil)
+	c.expectEQ(pollResp.ID, "")
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, []string{"foobar"})
+	c.expectEQ(pollResp.ID, "")
+
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, []string{build.Manager})
+	c.expectEQ(pollResp.ID != "", true)
+	c.expectEQ(pollResp.Type, dashapi.JobBisectCause)
+	c.expectEQ(pollResp.Manager, build.Manager)
+	c.expectEQ(pollResp.KernelRepo, build.KernelRepo)
+	c.expectEQ(pollResp.KernelBranch, build.KernelBranch)
+	c.expectEQ(pollResp.KernelCommit, build.KernelCommit)
+	c.expectEQ(pollResp.KernelConfig, build.KernelConfig)
+	c.expectEQ(pollResp.SyzkallerCommit, build.SyzkallerCommit)
+	c.expectEQ(len(pollResp.Patch), 0)
+	c.expectEQ(pollResp.ReproOpts, crash.ReproOpts)
+	c.expectEQ(pollResp.ReproSyz, crash.ReproSyz)
+	c.expectEQ(pollResp.ReproC, crash.ReproC)
+
+	// The pending job is returned again.
+	pollResp2, _ := c.client2.JobPoll([]string{build.Manager}, []string{build.Manager})
+	c.expectEQ(pollResp2, pollResp)
+
+	jobDoneReq := &dashapi.JobDoneReq{
+		ID:    pollResp.ID,
+		Build: *build,
+		Bisect: &dashapi.BisectResult{
+			Commit:      "2222222222222222222222222222222222222222",
+			CommitTitle: "kernel: add the bug",
+			CC:          []string{"author@kernel.org"},
+			Confidence:  0.9,
+			Steps: []dashapi.BisectStep{
+				{
+					Commit:  "2222222222222222222222222222222222222222",
+					Title:   "kernel: add the bug",
+					Result:  "bad",
+					Crashed: 8,
+					Total:   10,
+					Titles:  []string{"title1", "title2"},
+				},
+				{
+					Commit: "3333333333333333333333333333333333333333",
+					Title:  "kernel: unrelated change",
+					Result: "good",
+					Total:  10,
+				},
+			},
+			Log: []byte("bisect log"),
+		},
+	}
+	c.client2.JobDone(jobDoneReq)
+	{
+		dbJob, _ := c.loadJob(pollResp.ID)
+		c.expectEQ(dbJob.Reported, true)
+		c.expectEQ(dbJob.BisectCommit, jobDoneReq.Bisect.Commit)
+		c.expectEQ(dbJob.BisectCommitTitle, jobDoneReq.Bisect.CommitTitle)
+		c.expectEQ(dbJob.BisectCC, jobDoneReq.Bisect.CC)
+		c.expectEQ(dbJob.BisectConfidence, jobDoneReq.Bisect.Confidence)
+		c.expectEQ(len(dbJob.BisectSteps), 2)
+		c.expectEQ(dbJob.BisectSteps[0].Titles, "title1|title2")
+		c.expectEQ(dbJob.BisectSteps[1].Titles, "")
+		c.checkURLContents(externalLink(c.ctx, textBisectLog, dbJob.BisectLog), []byte("bisect log"))
+
+		page, err := c.AuthGET(AccessAdmin, "/bug?id="+bugKey.StringID())
+		c.expectOK(err)
+		c.expectTrue(bytes.Contains(page, []byte("Cause bisection")))
+		c.expectTrue(bytes.Contains(page, []byte("kernel: add the bug")))
+		c.expectTrue(bytes.Contains(page, []byte("8/10")))
+	}
+
+	// The dashboard does not create bisection jobs on its own.
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, []string{build.Manager})
+	c.expectEQ(pollResp.ID, "")
+
+	c.addBisectJob(dashapi.JobBisectFix)
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, []string{build.Manager})
+	c.expectEQ(pollResp.ID != "", true)
+	c.expectEQ(pollResp.Type, dashapi.JobBisectFix)
+	c.client2.JobDone(&dashapi.JobDoneReq{
+		ID:    pollResp.ID,
+		Build: *build,
+		Error: []byte("bisection failed"),
+	})
+	{
+		dbJob, _ := c.loadJob(pollResp.ID)
+		c.expectEQ(dbJob.Reported, true)
+		c.expectEQ(dbJob.BisectCommit, "")
+	}
+
+	pollResp, _ = c.client2.JobPoll([]string{build.Manager}, []string{build.Manager})
+	c.expectEQ(pollResp.ID, "")
+}
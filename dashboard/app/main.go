@@ -15,6 +15,7 @@ import (
 	"github.com/google/syzkaller/dashboard/dashapi"
 	"github.com/google/syzkaller/pkg/email"
 	"github.com/google/syzkaller/pkg/html"
+	"github.com/google/syzkaller/pkg/report/stack"
 	"golang.org/x/net/context"
 	"google.golang.org/appengine/datastore"
 	"google.golang.org/appengine/log"
@@ -77,9 +78,12 @@ type uiBugPage struct {
 	DupOf          *uiBugGroup
 	Dups           *uiBugGroup
 	Similar        *uiBugGroup
+	PossibleDups   *uiBugGroup
 	SampleReport   []byte
 	HasMaintainers bool
+	HasLabels      bool
 	Crashes        []*uiCrash
+	Bisections     []*uiBisection
 }
 
 type uiBugNamespace struct {
@@ -107,6 +111,8 @@ type uiBugGroup struct {
 type uiBug struct {
 	Namespace      string
 	Title          string
+	Class          string
+	Severity       string
 	NumCrashes     int64
 	NumCrashesBad  bool
 	FirstTime      time.Time
@@ -129,13 +135,38 @@ type uiCrash struct {
 	Manager      string
 	Time         time.Time
 	Maintainers  string
+	Labels       string
 	LogLink      string
 	ReportLink   string
 	ReproSyzLink string
 	ReproCLink   string
+	// Links to other C programs of a multi-program repro (need to be run concurrently).
+	ReproCExtraLinks []string
+	ReproRate        string
 	*uiBuild
 }
 
+type uiBisection struct {
+	Caption     string
+	Finished    time.Time
+	Commit      string
+	CommitTitle string
+	CC          string
+	Confidence  string
+	LogLink     string
+	ErrorLink   string
+	Steps       []*uiBisectStep
+}
+
+type uiBisectStep struct {
+	Commit  string
+	Title   string
+	Result  string
+	Crashed int
+	Total   int
+	Titles  []string
+}
+
 type uiJob struct {
 	Created         time.Time
 	BugLink         string
@@ -262,11 +293,21 @@ func handleBug(c context.Context, w http.ResponseWriter, r *http.Request) error
 	if err != nil {
 		return err
 	}
-	hasMaintainers := false
+	possibleDups, err := loadPossibleDups(c, r, bug, state, managers)
+	if err != nil {
+		return err
+	}
+	bisections, err := loadBisectionsForBug(c, bug)
+	if err != nil {
+		return err
+	}
+	hasMaintainers, hasLabels := false, false
 	for _, crash := range crashes {
 		if len(crash.Maintainers) != 0 {
 			hasMaintainers = true
-			break
+		}
+		if len(crash.Labels) != 0 {
+			hasLabels = true
 		}
 	}
 	data := &uiBugPage{
@@ -276,9 +317,12 @@ func handleBug(c context.Context, w http.ResponseWriter, r *http.Request) error
 		DupOf:          dupOf,
 		Dups:           dups,
 		Similar:        similar,
+		PossibleDups:   possibleDups,
 		SampleReport:   sampleReport,
 		HasMaintainers: hasMaintainers,
+		HasLabels:      hasLabels,
 		Crashes:        crashes,
+		Bisections:     bisections,
 	}
 	return serveTemplate(w, "bug.html", data)
 }
@@ -352,6 +396,8 @@ func textFilename(tag string) string {
 		return "patch.diff"
 	case textError:
 		return "error.txt"
+	case textBisectLog:
+		return "bisect.txt"
 	default:
 		return "text.txt"
 	}
@@ -364,6 +410,7 @@ func fetchBugs(c context.Context, r *http.Request) ([]*uiBugNamespace, error) {
 	}
 	accessLevel := accessLevel(c, r)
 	onlyFixed := r.FormValue("fixed")
+	class := r.FormValue("class")
 	var res []*uiBugNamespace
 	for ns, cfg := range config.Namespaces {
 		if accessLevel < cfg.AccessLevel {
@@ -372,7 +419,7 @@ func fetchBugs(c context.Context, r *http.Request) ([]*uiBugNamespace, error) {
 		if onlyFixed != "" && onlyFixed != ns {
 			continue
 		}
-		uiNamespace, err := fetchNamespaceBugs(c, accessLevel, ns, state, onlyFixed != "")
+		uiNamespace, err := fetchNamespaceBugs(c, accessLevel, ns, state, onlyFixed != "", class)
 		if err != nil {
 			return nil, err
 		}
@@ -385,11 +432,14 @@ func fetchBugs(c context.Context, r *http.Request) ([]*uiBugNamespace, error) {
 }
 
 func fetchNamespaceBugs(c context.Context, accessLevel AccessLevel, ns string,
-	state *ReportingState, onlyFixed bool) (*uiBugNamespace, error) {
+	state *ReportingState, onlyFixed bool, class string) (*uiBugNamespace, error) {
 	query := datastore.NewQuery("Bug").Filter("Namespace=", ns)
 	if onlyFixed {
 		query = query.Filter("Status=", BugStatusFixed)
 	}
+	if class != "" {
+		query = query.Filter("Class=", class)
+	}
 	var bugs []*Bug
 	_, err := query.GetAll(c, &bugs)
 	if err != nil {
@@ -560,6 +610,59 @@ func loadSimilarBugs(c context.Context, r *http.Request, bug *Bug, state *Report
 	return group, nil
 }
 
+// maxPossibleDupCandidates bounds the number of bugs loaded per key frame for possible dups lookup
+// (frames common to lots of bugs are not informative anyway).
+const maxPossibleDupCandidates = 100
+
+// loadPossibleDups returns open bugs in the same namespace that have different titles,
+// but crash with similar stacks, these are candidates for manual dup'ing.
+func loadPossibleDups(c context.Context, r *http.Request, bug *Bug, state *ReportingState, managers []string) (
+	*uiBugGroup, error) {
+	if len(bug.Stack) == 0 {
+		return nil, nil
+	}
+	// Similar bugs necessarily contain one of the key frames, so we query only bugs with these frames.
+	var bugs []*Bug
+	seen := make(map[string]bool)
+	for _, frame := range stack.KeyFrames(bug.Stack) {
+		var candidates []*Bug
+		keys, err := datastore.NewQuery("Bug").
+			Filter("Namespace=", bug.Namespace).
+			Filter("Status=", BugStatusOpen).
+			Filter("Stack=", frame).
+			Limit(maxPossibleDupCandidates).
+			GetAll(c, &candidates)
+		if err != nil {
+			return nil, err
+		}
+		for i, candidate := range candidates {
+			if !seen[keys[i].StringID()] {
+				seen[keys[i].StringID()] = true
+				bugs = append(bugs, candidate)
+			}
+		}
+	}
+	var results []*uiBug
+	accessLevel := accessLevel(c, r)
+	for _, other := range bugs {
+		if other.Title == bug.Title || !stack.Similar(bug.Stack, other.Stack) {
+			continue
+		}
+		if accessLevel < other.sanitizeAccess(accessLevel) {
+			continue
+		}
+		results = append(results, createUIBug(c, other, state, managers))
+	}
+	group := &uiBugGroup{
+		Now:         timeNow(c),
+		Caption:     "possible duplicates (similar stacks)",
+		ShowPatched: true,
+		ShowStatus:  true,
+		Bugs:        results,
+	}
+	return group, nil
+}
+
 func createUIBug(c context.Context, bug *Bug, state *ReportingState, managers []string) *uiBug {
 	reportingIdx, status, link := 0, "", ""
 	var reported time.Time
@@ -607,6 +710,8 @@ func createUIBug(c context.Context, bug *Bug, state *ReportingState, managers []
 	uiBug := &uiBug{
 		Namespace:      bug.Namespace,
 		Title:          bug.displayTitle(),
+		Class:          bug.Class,
+		Severity:       bug.Severity,
 		NumCrashes:     bug.NumCrashes,
 		FirstTime:      bug.FirstTime,
 		LastTime:       bug.LastTime,
@@ -683,12 +788,17 @@ func loadCrashesForBug(c context.Context, bug *Bug) ([]*uiCrash, []byte, error)
 			Manager:      crash.Manager,
 			Time:         crash.Time,
 			Maintainers:  strings.Join(crash.Maintainers, ", "),
+			Labels:       strings.Join(crash.Labels, ", "),
 			LogLink:      textLink(textCrashLog, crash.Log),
 			ReportLink:   textLink(textCrashReport, crash.Report),
 			ReproSyzLink: textLink(textReproSyz, crash.ReproSyz),
 			ReproCLink:   textLink(textReproC, crash.ReproC),
+			ReproRate:    formatReproReliability(&crash.ReproReliability),
 			uiBuild:      makeUIBuild(build),
 		}
+		for _, id := range crash.ReproCExtra {
+			ui.ReproCExtraLinks = append(ui.ReproCExtraLinks, textLink(textReproC, id))
+		}
 		results = append(results, ui)
 	}
 	sampleReport, _, err := getText(c, textCrashReport, crashes[0].Report)
@@ -698,6 +808,25 @@ func loadCrashesForBug(c context.Context, bug *Bug) ([]*uiCrash, []byte, error)
 	return results, sampleReport, nil
 }
 
+// formatReproReliability returns short description of reproducer reliability,
+// e.g. "syz 3/5 (12s), C 5/5 (8s)", or empty string if it was not measured.
+func formatReproReliability(rel *dashapi.ReproReliability) string {
+	var parts []string
+	format := func(name string, runs, crashes int, mean time.Duration) {
+		if runs == 0 {
+			return
+		}
+		part := fmt.Sprintf("%v %v/%v", name, crashes, runs)
+		if crashes != 0 {
+			part += fmt.Sprintf(" (%v)", mean/time.Second*time.Second)
+		}
+		parts = append(parts, part)
+	}
+	format("syz", rel.SyzRuns, rel.SyzCrashes, rel.SyzMeanTimeToCrash)
+	format("C", rel.CRuns, rel.CCrashes, rel.CMeanTimeToCrash)
+	return strings.Join(parts, ", ")
+}
+
 func makeUIBuild(build *Build) *uiBuild {
 	return &uiBuild{
 		Time:             build.Time,
@@ -793,6 +922,59 @@ func loadManagers(c context.Context, accessLevel AccessLevel) ([]*uiManager, err
 	return results, nil
 }
 
+// loadBisectionsForBug returns results of the last finished cause and fix bisection jobs for the bug.
+func loadBisectionsForBug(c context.Context, bug *Bug) ([]*uiBisection, error) {
+	var jobs []*Job
+	_, err := datastore.NewQuery("Job").
+		Ancestor(bug.key(c)).
+		GetAll(c, &jobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %v", err)
+	}
+	var results []*uiBisection
+	for _, typ := range []dashapi.JobType{dashapi.JobBisectCause, dashapi.JobBisectFix} {
+		var last *Job
+		for _, job := range jobs {
+			if job.Type == typ && !job.Finished.IsZero() &&
+				(last == nil || last.Finished.Before(job.Finished)) {
+				last = job
+			}
+		}
+		if last == nil {
+			continue
+		}
+		ui := &uiBisection{
+			Caption:     "Cause bisection",
+			Finished:    last.Finished,
+			Commit:      last.BisectCommit,
+			CommitTitle: last.BisectCommitTitle,
+			CC:          strings.Join(last.BisectCC, ", "),
+			Confidence:  fmt.Sprintf("%.0f%%", last.BisectConfidence*100),
+			LogLink:     textLink(textBisectLog, last.BisectLog),
+			ErrorLink:   textLink(textError, last.Error),
+		}
+		if typ == dashapi.JobBisectFix {
+			ui.Caption = "Fix bisection"
+		}
+		for _, step := range last.BisectSteps {
+			var titles []string
+			if step.Titles != "" {
+				titles = strings.Split(step.Titles, "|")
+			}
+			ui.Steps = append(ui.Steps, &uiBisectStep{
+				Commit:  step.Commit,
+				Title:   step.Title,
+				Result:  step.Result,
+				Crashed: step.Crashed,
+				Total:   step.Total,
+				Titles:  titles,
+			})
+		}
+		results = append(results, ui)
+	}
+	return results, nil
+}
+
 func loadRecentJobs(c context.Context) ([]*uiJob, error) {
 	var jobs []*Job
 	keys, err := datastore.NewQuery("Job").
@@ -215,6 +215,16 @@ func createBugReport(c context.Context, bug *Bug, crash *Crash, crashKey *datast
 	if err != nil {
 		return nil, err
 	}
+	var reproCExtra [][]byte
+	var reproCExtraLinks []string
+	for _, id := range crash.ReproCExtra {
+		text, _, err := getText(c, textReproC, id)
+		if err != nil {
+			return nil, err
+		}
+		reproCExtra = append(reproCExtra, text)
+		reproCExtraLinks = append(reproCExtraLinks, externalLink(c, textReproC, id))
+	}
 	reproSyz, _, err := getText(c, textReproSyz, crash.ReproSyz)
 	if err != nil {
 		return nil, err
@@ -264,6 +274,8 @@ func createBugReport(c context.Context, bug *Bug, crash *Crash, crashKey *datast
 		KernelConfigLink:  externalLink(c, textKernelConfig, build.KernelConfig),
 		ReproC:            reproC,
 		ReproCLink:        externalLink(c, textReproC, crash.ReproC),
+		ReproCExtra:       reproCExtra,
+		ReproCExtraLinks:  reproCExtraLinks,
 		ReproSyz:          reproSyz,
 		ReproSyzLink:      externalLink(c, textReproSyz, crash.ReproSyz),
 		CrashID:           crashKey.IntID(),
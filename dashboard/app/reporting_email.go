@@ -199,6 +199,7 @@ func emailReport(c context.Context, rep *dashapi.BugReport, templ string) error
 		KernelConfigLink  string
 		ReproSyzLink      string
 		ReproCLink        string
+		ReproCExtraLinks  []string
 		NumCrashes        int64
 		HappenedOn        []string
 		PatchLink         string
@@ -223,6 +224,7 @@ func emailReport(c context.Context, rep *dashapi.BugReport, templ string) error
 		KernelConfigLink:  rep.KernelConfigLink,
 		ReproSyzLink:      rep.ReproSyzLink,
 		ReproCLink:        rep.ReproCLink,
+		ReproCExtraLinks:  rep.ReproCExtraLinks,
 		NumCrashes:        rep.NumCrashes,
 		HappenedOn:        rep.HappenedOn,
 		PatchLink:         rep.PatchLink,
@@ -6,6 +6,7 @@
 package dash
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -417,3 +418,25 @@ func TestReportingFilter(t *testing.T) {
 	rep4 := c.client.pollBug()
 	c.expectEQ(string(rep4.Config), `{"Index":2}`)
 }
+
+func TestReportingMultiProgRepro(t *testing.T) {
+	c := NewCtx(t)
+	defer c.Close()
+
+	build := testBuild(1)
+	c.client.UploadBuild(build)
+
+	crash := testCrashWithRepro(build, 1)
+	crash.ReproCExtra = [][]byte{[]byte("int main() { return 2; }"), []byte("int main() { return 3; }")}
+	c.client.ReportCrash(crash)
+
+	rep := c.client.pollBug()
+	c.expectEQ(rep.ReproC, crash.ReproC)
+	c.expectEQ(rep.ReproCExtra, crash.ReproCExtra)
+	c.expectEQ(len(rep.ReproCExtraLinks), 2)
+	for i, link := range rep.ReproCExtraLinks {
+		text, err := c.AuthGET(AccessAdmin, link[strings.Index(link, "/x/"):])
+		c.expectOK(err)
+		c.expectEQ(text, crash.ReproCExtra[i])
+	}
+}
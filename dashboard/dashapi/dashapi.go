@@ -108,22 +108,35 @@ func (dash *Dashboard) BuilderPoll(manager string) (*BuilderPollResp, error) {
 
 // Jobs workflow:
 //   - syz-ci sends JobPollReq periodically to check for new jobs,
-//     request contains list of managers that this syz-ci runs.
+//     request contains list of managers that this syz-ci runs
+//     and list of managers for which it can run bisection jobs.
 //   - dashboard replies with JobPollResp that contains job details,
 //     if no new jobs available ID is set to empty string.
 //   - when syz-ci finishes the job, it sends JobDoneReq which contains
 //     job execution result (Build, Crash or Error details),
 //     ID must match JobPollResp.ID.
+//   - for bisection jobs JobDoneReq also contains Bisect result.
 
 type JobPollReq struct {
-	Managers []string
+	Managers       []string
+	BisectManagers []string
 }
 
+type JobType int
+
+const (
+	JobTestPatch JobType = iota
+	JobBisectCause
+	JobBisectFix
+)
+
 type JobPollResp struct {
 	ID              string
+	Type            JobType
 	Manager         string
 	KernelRepo      string
 	KernelBranch    string
+	KernelCommit    string // commit where the crash happened (bisection jobs only)
 	KernelConfig    []byte
 	SyzkallerCommit string
 	Patch           []byte
@@ -139,10 +152,35 @@ type JobDoneReq struct {
 	CrashTitle  string
 	CrashLog    []byte
 	CrashReport []byte
-}
-
-func (dash *Dashboard) JobPoll(managers []string) (*JobPollResp, error) {
-	req := &JobPollReq{Managers: managers}
+	Bisect      *BisectResult
+}
+
+type BisectResult struct {
+	// Found commit, empty if the crash is still unfixed (fix bisection).
+	Commit      string
+	CommitTitle string
+	CC          []string
+	// Estimated probability that the result is correct, see bisect.Result.
+	Confidence float64
+	Steps      []BisectStep
+	Log        []byte
+}
+
+// BisectStep is result of testing of a single revision during bisection.
+type BisectStep struct {
+	Commit  string
+	Title   string
+	Result  string // good/bad/skip
+	Crashed int
+	Total   int
+	Titles  []string // crash titles seen
+}
+
+func (dash *Dashboard) JobPoll(managers, bisectManagers []string) (*JobPollResp, error) {
+	req := &JobPollReq{
+		Managers:       managers,
+		BisectManagers: bisectManagers,
+	}
 	resp := new(JobPollResp)
 	err := dash.Query("job_poll", req, resp)
 	return resp, err
@@ -169,10 +207,44 @@ type Crash struct {
 	Maintainers []string
 	Log         []byte
 	Report      []byte
+	Stack       []string // stack signature of the report (see report.Report.Stack)
+	Details     *CrashDetails
+	Severity    string   // low, medium, high, severe or critical (see report.Report.Severity)
+	Labels      []string // attached by crash_rules in manager config (see report.Report.Labels)
 	// The following is optional and is filled only after repro.
 	ReproOpts []byte
 	ReproSyz  []byte
 	ReproC    []byte
+	// Other C programs of a multi-program repro, they need to be run concurrently with ReproC.
+	ReproCExtra [][]byte
+	// Results of re-running the reproducer several times (optional).
+	ReproReliability *ReproReliability
+}
+
+// ReproReliability describes how reliably syz and C reproducers trigger the crash.
+type ReproReliability struct {
+	SyzRuns            int
+	SyzCrashes         int
+	SyzMeanTimeToCrash time.Duration
+	CRuns              int
+	CCrashes           int
+	CMeanTimeToCrash   time.Duration
+}
+
+// CrashDetails is structured information about KASAN/KMSAN/UBSAN/lockdep crashes
+// (mirrors report.Details).
+type CrashDetails struct {
+	Tool       string // KASAN, KMSAN, UBSAN, LOCKDEP
+	BugType    string // e.g. use-after-free, uninit-value, circular-locking
+	Access     string // read/write
+	AccessSize int
+	Address    uint64
+	Task       string
+	PID        int
+	CallTrace  []string
+	AllocStack []string
+	FreeStack  []string
+	LockChain  []string
 }
 
 type ReportCrashResp struct {
@@ -252,6 +324,8 @@ type BugReport struct {
 	ReportLink        string
 	ReproC            []byte
 	ReproCLink        string
+	ReproCExtra       [][]byte // see Crash.ReproCExtra
+	ReproCExtraLinks  []string
 	ReproSyz          []byte
 	ReproSyzLink      string
 	CrashID           int64 // returned back in BugUpdate
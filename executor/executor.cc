@@ -98,6 +98,8 @@ const int kInFd = 3;
 const int kOutFd = 4;
 static uint32* output_data;
 static uint32* output_pos;
+// In remote mode test processes publish size of the output region here (in words).
+static uint32* output_size;
 static uint32* write_output(uint32 v);
 static void write_completed(uint32 completed);
 static uint32 hash(uint32 a);
@@ -119,6 +121,18 @@ static sandbox_type flag_sandbox;
 static bool flag_enable_tun;
 static bool flag_enable_net_dev;
 static bool flag_enable_fault_injection;
+// Executor runs on a different machine than ipc (e.g. over ssh), so shmem is emulated:
+// programs come over the control pipe and output is sent back after each program.
+static bool flag_remote;
+
+// Granularity of feedback signal, see signal.Mode in pkg/signal.
+enum signal_mode_type {
+	signal_mode_edge,
+	signal_mode_pc,
+	signal_mode_ngram,
+	signal_mode_call_context,
+};
+static signal_mode_type flag_signal_mode;
 
 static bool flag_collect_cover;
 static bool flag_dedup_cover;
@@ -202,6 +216,9 @@ struct thread_t {
 	long res;
 	uint32 reserrno;
 	bool fault_injected;
+	uint64 start_time_us;
+	uint64 wall_time_us;
+	uint64 cpu_time_us;
 	cover_t cov;
 };
 
@@ -256,6 +273,8 @@ struct call_reply {
 	uint32 call_num;
 	uint32 reserrno;
 	uint32 flags;
+	uint32 wall_time_us;
+	uint32 cpu_time_us;
 	uint32 signal_size;
 	uint32 cover_size;
 	uint32 comps_size;
@@ -299,6 +318,9 @@ static uint64 swap(uint64 v, uint64 size, uint64 bf);
 static void copyin(char* addr, uint64 val, uint64 size, uint64 bf, uint64 bf_off, uint64 bf_len);
 static bool copyout(char* addr, uint64 size, uint64* res);
 static void setup_control_pipes();
+static void setup_shmem();
+static uint64 current_time_us();
+static uint64 thread_cpu_time_us();
 
 #include "syscalls.h"
 
@@ -333,34 +355,15 @@ int main(int argc, char** argv)
 
 	os_init(argc, argv, (void*)SYZ_DATA_OFFSET, SYZ_NUM_PAGES * SYZ_PAGE_SIZE);
 
-#if SYZ_EXECUTOR_USES_SHMEM
-	if (mmap(&input_data[0], kMaxInput, PROT_READ, MAP_PRIVATE | MAP_FIXED, kInFd, 0) != &input_data[0])
-		fail("mmap of input file failed");
-	// The output region is the only thing in executor process for which consistency matters.
-	// If it is corrupted ipc package will fail to parse its contents and panic.
-	// But fuzzer constantly invents new ways of how to currupt the region,
-	// so we map the region at a (hopefully) hard to guess address with random offset,
-	// surrounded by unmapped pages.
-	// The address chosen must also work on 32-bit kernels with 1GB user address space.
-	void* preferred = (void*)(0x1b2bc20000ull + (1 << 20) * (getpid() % 128));
-	output_data = (uint32*)mmap(preferred, kMaxOutput,
-				    PROT_READ | PROT_WRITE, MAP_SHARED | MAP_FIXED, kOutFd, 0);
-	if (output_data != preferred)
-		fail("mmap of output file failed");
-
-	// Prevent test programs to mess with these fds.
-	// Due to races in collider mode, a program can e.g. ftruncate one of these fds,
-	// which will cause fuzzer to crash.
-	close(kInFd);
-	close(kOutFd);
-#endif
-
 	use_temporary_dir();
 	install_segv_handler();
 	setup_control_pipes();
 #if SYZ_EXECUTOR_USES_FORK_SERVER
+	// The handshake tells us if we are remote, so shmem is set up only after it.
 	receive_handshake();
+	setup_shmem();
 #else
+	setup_shmem();
 	receive_execute();
 #endif
 	if (flag_cover) {
@@ -434,6 +437,45 @@ void setup_control_pipes()
 		fail("dup2(2, 0) failed");
 }
 
+void setup_shmem()
+{
+#if SYZ_EXECUTOR_USES_SHMEM
+	// The output region is the only thing in executor process for which consistency matters.
+	// If it is corrupted ipc package will fail to parse its contents and panic.
+	// But fuzzer constantly invents new ways of how to currupt the region,
+	// so we map the region at a (hopefully) hard to guess address with random offset,
+	// surrounded by unmapped pages.
+	// The address chosen must also work on 32-bit kernels with 1GB user address space.
+	void* preferred = (void*)(0x1b2bc20000ull + (1 << 20) * (getpid() % 128));
+	if (flag_remote) {
+		// There are no shmem files, the input is read into input_data from the control pipe
+		// and the output is sent back in reply_execute. The region still needs to be shared
+		// because it is written by the forked test processes.
+		output_data = (uint32*)mmap(preferred, kMaxOutput,
+					    PROT_READ | PROT_WRITE, MAP_SHARED | MAP_ANON | MAP_FIXED, -1, 0);
+		if (output_data != preferred)
+			fail("mmap of output region failed");
+		output_size = (uint32*)mmap(NULL, sizeof(*output_size),
+					    PROT_READ | PROT_WRITE, MAP_SHARED | MAP_ANON, -1, 0);
+		if (output_size == MAP_FAILED)
+			fail("mmap of output size failed");
+		return;
+	}
+	if (mmap(&input_data[0], kMaxInput, PROT_READ, MAP_PRIVATE | MAP_FIXED, kInFd, 0) != &input_data[0])
+		fail("mmap of input file failed");
+	output_data = (uint32*)mmap(preferred, kMaxOutput,
+				    PROT_READ | PROT_WRITE, MAP_SHARED | MAP_FIXED, kOutFd, 0);
+	if (output_data != preferred)
+		fail("mmap of output file failed");
+
+	// Prevent test programs to mess with these fds.
+	// Due to races in collider mode, a program can e.g. ftruncate one of these fds,
+	// which will cause fuzzer to crash.
+	close(kInFd);
+	close(kOutFd);
+#endif
+}
+
 void parse_env_flags(uint64 flags)
 {
 	// Note: Values correspond to ordering in pkg/ipc/ipc.go, e.g. FlagSandboxNamespace
@@ -449,6 +491,11 @@ void parse_env_flags(uint64 flags)
 	flag_enable_tun = flags & (1 << 5);
 	flag_enable_net_dev = flags & (1 << 6);
 	flag_enable_fault_injection = flags & (1 << 7);
+	flag_remote = flags & (1 << 8);
+	// Signal mode is passed in the upper half (see signalModeShift in pkg/ipc/ipc.go).
+	flag_signal_mode = (signal_mode_type)((flags >> 32) & 0xff);
+	if (flag_signal_mode > signal_mode_call_context)
+		fail("bad signal mode %d", flag_signal_mode);
 }
 
 #if SYZ_EXECUTOR_USES_FORK_SERVER
@@ -500,7 +547,7 @@ void receive_execute()
 	      current_time_ms() - start_time_ms, procid, flag_threaded, flag_collide,
 	      flag_collect_cover, flag_collect_comps, flag_dedup_cover, flag_inject_fault,
 	      flag_fault_call, flag_fault_nth, req.prog_size);
-	if (SYZ_EXECUTOR_USES_SHMEM) {
+	if (SYZ_EXECUTOR_USES_SHMEM && !flag_remote) {
 		if (req.prog_size)
 			fail("need_prog: no program");
 		return;
@@ -539,6 +586,23 @@ void reply_execute(int status)
 	reply.status = status;
 	if (write(kOutPipeFd, &reply, sizeof(reply)) != sizeof(reply))
 		fail("control pipe write failed");
+#if SYZ_EXECUTOR_USES_SHMEM
+	if (flag_remote && status == 0) {
+		// The size is written by the test process, so it can be corrupted.
+		uint32 size = __atomic_load_n(output_size, __ATOMIC_ACQUIRE);
+		if (size < 1 || size >= kMaxOutput / sizeof(uint32))
+			exitf("bad output size %u", size);
+		if (write(kOutPipeFd, &size, sizeof(size)) != sizeof(size))
+			fail("control pipe output write failed");
+		char* data = (char*)output_data;
+		for (uint64 pos = 0; pos < size * sizeof(uint32);) {
+			ssize_t rv = write(kOutPipeFd, data + pos, size * sizeof(uint32) - pos);
+			if (rv <= 0)
+				fail("control pipe output write failed");
+			pos += rv;
+		}
+	}
+#endif
 }
 
 // execute_one executes program stored in input_data.
@@ -551,6 +615,7 @@ void execute_one()
 #if SYZ_EXECUTOR_USES_SHMEM
 	output_pos = output_data;
 	write_output(0); // Number of executed syscalls (updated later).
+	write_completed(0);
 #endif
 	uint64 start = current_time_ms();
 
@@ -758,6 +823,7 @@ thread_t* schedule_call(int call_index, int call_num, bool colliding, uint64 cop
 	th->call_index = call_index;
 	th->call_num = call_num;
 	th->num_args = num_args;
+	th->start_time_us = current_time_us();
 	for (int i = 0; i < kMaxArgs; i++)
 		th->args[i] = args[i];
 	event_set(&th->ready);
@@ -770,17 +836,32 @@ template <typename cover_t>
 void write_coverage_signal(thread_t* th, uint32* signal_count_pos, uint32* cover_count_pos)
 {
 	// Write out feedback signals.
-	// Currently it is code edges computed as xor of two subsequent basic block PCs.
+	// By default it is code edges computed as xor of two subsequent basic block PCs.
+	// Must be in sync with signal.Mode.Trace in pkg/signal.
 	cover_t* cover_data = ((cover_t*)th->cov.data) + 1;
 	uint32 nsig = 0;
-	cover_t prev = 0;
+	cover_t prev = 0, prev2 = 0;
 	for (uint32 i = 0; i < th->cov.size; i++) {
 		cover_t pc = cover_data[i];
 		if (!cover_check(pc)) {
 			debug("got bad pc: 0x%llx\n", (uint64)pc);
 			doexit(0);
 		}
-		cover_t sig = pc ^ prev;
+		cover_t sig = pc;
+		switch (flag_signal_mode) {
+		case signal_mode_edge:
+			sig ^= prev;
+			break;
+		case signal_mode_pc:
+			break;
+		case signal_mode_ngram:
+			sig ^= prev ^ (prev2 >> 1);
+			break;
+		case signal_mode_call_context:
+			sig ^= prev ^ hash(th->call_num + 1);
+			break;
+		}
+		prev2 = prev;
 		prev = hash(pc);
 		if (dedup(sig))
 			continue;
@@ -859,17 +940,30 @@ void write_call_output(thread_t* th, bool finished)
 	uint32 reserrno = 999;
 	uint32 call_flags = call_flag_executed;
 	const bool blocked = th != last_scheduled;
+	// For unfinished calls we report time spent so far and no CPU time
+	// (we can't query CPU time of another thread portably).
+	uint64 wall_time_us = current_time_us() - th->start_time_us;
+	uint64 cpu_time_us = 0;
 	if (finished) {
 		reserrno = th->res != -1 ? 0 : th->reserrno;
 		call_flags |= call_flag_finished |
 			      (blocked ? call_flag_blocked : 0) |
 			      (th->fault_injected ? call_flag_fault_injected : 0);
+		wall_time_us = th->wall_time_us;
+		cpu_time_us = th->cpu_time_us;
 	}
+	// Saturate times, 32 bits of microseconds is more than an hour.
+	if (wall_time_us > (uint32)-1)
+		wall_time_us = (uint32)-1;
+	if (cpu_time_us > (uint32)-1)
+		cpu_time_us = (uint32)-1;
 #if SYZ_EXECUTOR_USES_SHMEM
 	write_output(th->call_index);
 	write_output(th->call_num);
 	write_output(reserrno);
 	write_output(call_flags);
+	write_output(wall_time_us);
+	write_output(cpu_time_us);
 	uint32* signal_count_pos = write_output(0); // filled in later
 	uint32* cover_count_pos = write_output(0); // filled in later
 	uint32* comps_count_pos = write_output(0); // filled in later
@@ -898,9 +992,9 @@ void write_call_output(thread_t* th, bool finished)
 		else
 			write_coverage_signal<uint32>(th, signal_count_pos, cover_count_pos);
 	}
-	debug_verbose("out #%u: index=%u num=%u errno=%d finished=%d blocked=%d sig=%u cover=%u comps=%u\n",
+	debug_verbose("out #%u: index=%u num=%u errno=%d finished=%d blocked=%d time=%llu/%llu sig=%u cover=%u comps=%u\n",
 		      completed, th->call_index, th->call_num, reserrno, finished, blocked,
-		      *signal_count_pos, *cover_count_pos, *comps_count_pos);
+		      wall_time_us, cpu_time_us, *signal_count_pos, *cover_count_pos, *comps_count_pos);
 	completed++;
 	write_completed(completed);
 #else
@@ -912,6 +1006,8 @@ void write_call_output(thread_t* th, bool finished)
 	reply.call_num = th->call_num;
 	reply.reserrno = reserrno;
 	reply.flags = call_flags;
+	reply.wall_time_us = wall_time_us;
+	reply.cpu_time_us = cpu_time_us;
 	reply.signal_size = 0;
 	reply.cover_size = 0;
 	reply.comps_size = 0;
@@ -970,9 +1066,17 @@ void execute_call(thread_t* th)
 
 	if (flag_cover)
 		cover_reset(&th->cov);
+	// CPU time is measured inside of the wall time interval, so that it's never larger.
+	uint64 wall_start = current_time_us();
+	uint64 cpu_start = thread_cpu_time_us();
 	errno = 0;
 	th->res = execute_syscall(call, th->args);
 	th->reserrno = errno;
+	th->cpu_time_us = thread_cpu_time_us() - cpu_start;
+	th->wall_time_us = current_time_us() - wall_start;
+	// Both clocks are truncated to microseconds, which still can give 1us of excess.
+	if (th->cpu_time_us > th->wall_time_us)
+		th->cpu_time_us = th->wall_time_us;
 	if (th->res == -1 && th->reserrno == 0)
 		th->reserrno = EINVAL; // our syz syscalls may misbehave
 	if (flag_cover) {
@@ -1204,6 +1308,8 @@ uint32* write_output(uint32 v)
 void write_completed(uint32 completed)
 {
 	__atomic_store_n(output_data, completed, __ATOMIC_RELEASE);
+	if (flag_remote)
+		__atomic_store_n(output_size, (uint32)(output_pos - output_data), __ATOMIC_RELEASE);
 }
 #endif
 
@@ -1297,6 +1403,32 @@ bool kcov_comparison_t::operator<(const struct kcov_comparison_t& other) const
 }
 #endif
 
+uint64 current_time_us()
+{
+#if GOOS_windows
+	return current_time_ms() * 1000;
+#else
+	struct timespec ts;
+	if (clock_gettime(CLOCK_MONOTONIC, &ts))
+		fail("clock_gettime failed");
+	return (uint64)ts.tv_sec * 1000000 + (uint64)ts.tv_nsec / 1000;
+#endif
+}
+
+// thread_cpu_time_us returns CPU time consumed by the current thread,
+// or 0 if the OS does not support per-thread CPU clocks.
+uint64 thread_cpu_time_us()
+{
+#if GOOS_linux || GOOS_freebsd || GOOS_netbsd || GOOS_openbsd
+	struct timespec ts;
+	if (clock_gettime(CLOCK_THREAD_CPUTIME_ID, &ts))
+		return 0;
+	return (uint64)ts.tv_sec * 1000000 + (uint64)ts.tv_nsec / 1000;
+#else
+	return 0;
+#endif
+}
+
 void fail(const char* msg, ...)
 {
 	int e = errno;
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/syzkaller/pkg/build"
@@ -25,16 +26,24 @@ type Config struct {
 	Syzkaller SyzkallerConfig
 	Repro     ReproConfig
 	Manager   mgrconfig.Config
+	// BuildCache is an optional cache of kernel builds shared between bisections.
+	BuildCache *build.Cache
 }
 
 type KernelConfig struct {
 	Repo      string
+	RepoType  string // vcs.TypeGit (default) or vcs.TypeHg
 	Branch    string
 	Commit    string
 	Cmdline   string
 	Sysctl    string
 	Config    []byte
 	Userspace string
+	// BaselineConfig is a config on which the crash does not happen,
+	// used only for config bisection (see RunConfig).
+	BaselineConfig []byte
+	// Build contains custom kernel build commands (optional, see build.CommandConfig).
+	Build *build.CommandConfig
 }
 
 type SyzkallerConfig struct {
@@ -57,17 +66,21 @@ type env struct {
 	numTests  int
 	buildTime time.Duration
 	testTime  time.Duration
+	steps     []*Step
+	// inconsistent is set if retesting of the bisection boundary contradicts the bisection result.
+	inconsistent bool
 }
 
 type buildEnv struct {
 	compiler string
 }
 
-func Run(cfg *Config) (*vcs.Commit, error) {
+func Run(cfg *Config) (*Result, error) {
 	if err := checkConfig(cfg); err != nil {
 		return nil, err
 	}
-	repo, err := vcs.NewRepo(cfg.Manager.TargetOS, cfg.Manager.Type, cfg.Manager.KernelSrc)
+	repo, err := vcs.NewRepo(cfg.Manager.TargetOS, cfg.Manager.Type, cfg.Kernel.RepoType,
+		cfg.Manager.KernelSrc)
 	if err != nil {
 		return nil, err
 	}
@@ -81,27 +94,102 @@ func Run(cfg *Config) (*vcs.Commit, error) {
 		env.log("searching for guilty commit starting from %v", cfg.Kernel.Commit)
 	}
 	start := time.Now()
-	res, err := env.bisect()
+	commits, err := env.bisect()
 	env.log("revisions tested: %v, total time: %v (build: %v, test: %v)",
 		env.numTests, time.Since(start), env.buildTime, env.testTime)
+	if cfg.BuildCache != nil {
+		env.log("build cache %v", cfg.BuildCache.Stats())
+	}
 	if err != nil {
 		env.log("error: %v", err)
 		return nil, err
 	}
-	if res == nil {
-		env.log("the crash is still unfixed")
-		return nil, nil
+	res := &Result{
+		Confidence: confidence(env.steps),
+		Steps:      env.steps,
+	}
+	if len(commits) == 1 {
+		res.Commit = commits[0]
+	} else if len(commits) > 1 {
+		res.Candidates = commits
 	}
+	if env.inconsistent || len(res.Candidates) != 0 {
+		res.Confidence = 0
+	}
+	env.logSteps(res.Steps)
+	env.log("confidence: %.0f%% (crash rate %.0f%%)", res.Confidence*100, crashRate(res.Steps)*100)
 	what := "bad"
 	if cfg.Fix {
 		what = "good"
 	}
-	env.log("first %v commit: %v %v", what, res.Hash, res.Title)
-	env.log("cc: %q", res.CC)
+	switch {
+	case len(commits) == 0:
+		env.log("the crash is still unfixed")
+	case len(commits) == 1:
+		env.log("first %v commit: %v %v", what, res.Commit.Hash, res.Commit.Title)
+		env.log("cc: %q", res.Commit.CC)
+	default:
+		env.log("bisection is inconclusive, the first %v commit could be any of:", what)
+		for _, com := range commits {
+			env.log("  %v %v", com.Hash, com.Title)
+		}
+	}
+	return res, nil
+}
+
+// RunConfig bisects differences between cfg.Kernel.BaselineConfig and cfg.Kernel.Config
+// on cfg.Kernel.Commit and returns the config options that cause the crash
+// (or fix it if cfg.Fix is set).
+func RunConfig(cfg *Config) (*ConfigResult, error) {
+	if err := checkConfig(cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Kernel.BaselineConfig) == 0 {
+		return nil, fmt.Errorf("no baseline kernel config")
+	}
+	repo, err := vcs.NewRepo(cfg.Manager.TargetOS, cfg.Manager.Type, cfg.Kernel.RepoType,
+		cfg.Manager.KernelSrc)
+	if err != nil {
+		return nil, err
+	}
+	env := &env{
+		cfg:  cfg,
+		repo: repo,
+	}
+	if cfg.Fix {
+		env.log("searching for fixing config options on %v", cfg.Kernel.Commit)
+	} else {
+		env.log("searching for guilty config options on %v", cfg.Kernel.Commit)
+	}
+	start := time.Now()
+	res, err := env.bisectConfig()
+	env.log("configs tested: %v, total time: %v (build: %v, test: %v)",
+		env.numTests, time.Since(start), env.buildTime, env.testTime)
+	if cfg.BuildCache != nil {
+		env.log("build cache %v", cfg.BuildCache.Stats())
+	}
+	if err != nil {
+		env.log("error: %v", err)
+		return nil, err
+	}
+	env.logSteps(env.steps)
+	env.log("confidence: %.0f%% (crash rate %.0f%%)", confidence(env.steps)*100, crashRate(env.steps)*100)
+	env.log("responsible config options:")
+	for _, opt := range res.Options {
+		env.log("%v", opt)
+	}
+	if len(res.Unverified) != 0 {
+		env.log("options that may be not needed (testing without them failed): %v",
+			strings.Join(res.Unverified, " "))
+	}
+	if len(res.Drifted) != 0 {
+		env.log("options that did not get the requested values in the built kernel: %v",
+			strings.Join(res.Drifted, " "))
+	}
 	return res, nil
 }
 
-func (env *env) bisect() (*vcs.Commit, error) {
+func (env *env) bisect() ([]*vcs.Commit, error) {
 	cfg := env.cfg
 	var err error
 	if env.inst, err = instance.NewEnv(&cfg.Manager); err != nil {
@@ -111,7 +199,7 @@ func (env *env) bisect() (*vcs.Commit, error) {
 		return nil, err
 	}
 	if err := build.Clean(cfg.Manager.TargetOS, cfg.Manager.TargetVMArch,
-		cfg.Manager.Type, cfg.Manager.KernelSrc); err != nil {
+		cfg.Manager.Type, cfg.Manager.KernelSrc, cfg.Kernel.Build); err != nil {
 		return nil, fmt.Errorf("kernel clean failed: %v", err)
 	}
 	env.log("building syzkaller on %v", cfg.Syzkaller.Commit)
@@ -121,7 +209,7 @@ func (env *env) bisect() (*vcs.Commit, error) {
 	if _, err := env.repo.SwitchCommit(cfg.Kernel.Commit); err != nil {
 		return nil, err
 	}
-	if res, err := env.test(); err != nil {
+	if res, err := env.test(cfg.Kernel.Config); err != nil {
 		return nil, err
 	} else if res != vcs.BisectBad {
 		return nil, fmt.Errorf("the crash wasn't reproduced on the original commit")
@@ -131,13 +219,13 @@ func (env *env) bisect() (*vcs.Commit, error) {
 		return nil, err
 	}
 	if res != nil {
-		return res, nil // happens on the oldest release
+		return []*vcs.Commit{res}, nil // happens on the oldest release
 	}
 	if good == "" {
 		return nil, nil // still not fixed
 	}
-	return env.repo.Bisect(bad, good, cfg.Trace, func() (vcs.BisectResult, error) {
-		res, err := env.test()
+	commits, err := env.repo.Bisect(bad, good, cfg.Trace, func() (vcs.BisectResult, error) {
+		res, err := env.test(cfg.Kernel.Config)
 		if cfg.Fix {
 			if res == vcs.BisectBad {
 				res = vcs.BisectGood
@@ -147,6 +235,53 @@ func (env *env) bisect() (*vcs.Commit, error) {
 		}
 		return res, err
 	})
+	if err != nil || len(commits) != 1 {
+		return commits, err
+	}
+	if err := env.retestBoundary(commits[0]); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// retestBoundary retests the found commit and its parent once more
+// to catch wrong verdicts caused by flaky crashes on the bisection boundary.
+func (env *env) retestBoundary(commit *vcs.Commit) error {
+	// The found commit must crash and its parent must not (vice versa for fix bisection).
+	want, wantParent := vcs.BisectBad, vcs.BisectGood
+	if env.cfg.Fix {
+		want, wantParent = wantParent, want
+	}
+	env.log("retesting %v", commit.Hash)
+	com, err := env.repo.SwitchCommit(commit.Hash)
+	if err != nil {
+		return err
+	}
+	if err := env.retest(com.Hash, want); err != nil {
+		return err
+	}
+	if len(com.Parents) == 0 {
+		env.log("commit %v has no parents, not retesting the parent", com.Hash)
+		return nil
+	}
+	parent := com.Parents[0]
+	env.log("retesting parent %v", parent)
+	if _, err := env.repo.SwitchCommit(parent); err != nil {
+		return err
+	}
+	return env.retest(parent, wantParent)
+}
+
+func (env *env) retest(rev string, want vcs.BisectResult) error {
+	res, err := env.test(env.cfg.Kernel.Config)
+	if err != nil {
+		return err
+	}
+	if res != vcs.BisectSkip && res != want {
+		env.log("retesting %v gave %v, bisection result is inconsistent", rev, res)
+		env.inconsistent = true
+	}
+	return nil
 }
 
 func (env *env) commitRange() (*vcs.Commit, string, string, error) {
@@ -161,7 +296,7 @@ func (env *env) commitRangeForFix() (*vcs.Commit, string, string, error) {
 	if _, err := env.repo.SwitchCommit(env.head.Hash); err != nil {
 		return nil, "", "", err
 	}
-	res, err := env.test()
+	res, err := env.test(env.cfg.Kernel.Config)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -196,7 +331,7 @@ func (env *env) commitRangeForBug() (*vcs.Commit, string, string, error) {
 		if err != nil {
 			return nil, "", "", err
 		}
-		res, err := env.test()
+		res, err := env.test(cfg.Kernel.Config)
 		if err != nil {
 			return nil, "", "", err
 		}
@@ -213,7 +348,7 @@ func (env *env) commitRangeForBug() (*vcs.Commit, string, string, error) {
 	panic("unreachable")
 }
 
-func (env *env) test() (vcs.BisectResult, error) {
+func (env *env) test(kernelConfig []byte) (vcs.BisectResult, error) {
 	cfg := env.cfg
 	env.numTests++
 	current, err := env.repo.HeadCommit()
@@ -229,14 +364,26 @@ func (env *env) test() (vcs.BisectResult, error) {
 		return 0, err
 	}
 	env.log("testing commit %v with %v", current.Hash, compilerID)
+	step := &Step{
+		Commit: current.Hash,
+		Title:  current.Title,
+		Result: vcs.BisectSkip,
+	}
+	env.steps = append(env.steps, step)
 	buildStart := time.Now()
-	if err := build.Clean(cfg.Manager.TargetOS, cfg.Manager.TargetVMArch,
-		cfg.Manager.Type, cfg.Manager.KernelSrc); err != nil {
-		return 0, fmt.Errorf("kernel clean failed: %v", err)
+	if cfg.BuildCache == nil {
+		// With the cache the kernel is cleaned only if it needs to be rebuilt.
+		if err := build.Clean(cfg.Manager.TargetOS, cfg.Manager.TargetVMArch,
+			cfg.Manager.Type, cfg.Manager.KernelSrc, cfg.Kernel.Build); err != nil {
+			return 0, fmt.Errorf("kernel clean failed: %v", err)
+		}
 	}
-	err = env.inst.BuildKernel(be.compiler, cfg.Kernel.Userspace,
-		cfg.Kernel.Cmdline, cfg.Kernel.Sysctl, cfg.Kernel.Config)
+	cached, err := env.inst.BuildKernelCached(cfg.BuildCache, current.Hash, nil, be.compiler,
+		cfg.Kernel.Userspace, cfg.Kernel.Cmdline, cfg.Kernel.Sysctl, kernelConfig, cfg.Kernel.Build)
 	env.buildTime += time.Since(buildStart)
+	if cached {
+		env.log("using cached kernel build")
+	}
 	if err != nil {
 		if verr, ok := err.(*osutil.VerboseError); ok {
 			env.log("%v", verr.Title)
@@ -246,28 +393,39 @@ func (env *env) test() (vcs.BisectResult, error) {
 		}
 		return vcs.BisectSkip, nil
 	}
-	testStart := time.Now()
-	results, err := env.inst.Test(8, cfg.Repro.Syz, cfg.Repro.Opts, cfg.Repro.C)
-	env.testTime += time.Since(testStart)
-	if err != nil {
-		env.log("failed: %v", err)
-		return vcs.BisectSkip, nil
+	for round := 1; ; round++ {
+		testStart := time.Now()
+		results, err := env.inst.Test(numTestVMs, cfg.Repro.Syz, cfg.Repro.Opts, cfg.Repro.C)
+		env.testTime += time.Since(testStart)
+		if err != nil {
+			env.log("failed: %v", err)
+			break
+		}
+		env.processResults(current, step, results)
+		if step.Crashed != 0 || step.OK == 0 || round == maxTestRounds {
+			break
+		}
+		// A flaky crash could be missed, retest if that's likely.
+		miss := missProbability(crashRate(env.steps), step.OK)
+		if miss <= maxMissProbability {
+			break
+		}
+		env.log("no crashes, but the crash could be missed with probability %.0f%%, retesting", miss*100)
 	}
-	bad, good := env.processResults(current, results)
-	res := vcs.BisectSkip
-	if bad != 0 {
-		res = vcs.BisectBad
-	} else if good != 0 {
-		res = vcs.BisectGood
+	if step.Crashed != 0 {
+		step.Result = vcs.BisectBad
+	} else if step.OK != 0 {
+		step.Result = vcs.BisectGood
 	}
-	return res, nil
+	return step.Result, nil
 }
 
-func (env *env) processResults(current *vcs.Commit, results []error) (bad, good int) {
+func (env *env) processResults(current *vcs.Commit, step *Step, results []error) {
 	var verdicts []string
 	for i, res := range results {
+		idx := step.Total + i
 		if res == nil {
-			good++
+			step.OK++
 			verdicts = append(verdicts, "OK")
 			continue
 		}
@@ -282,19 +440,21 @@ func (env *env) processResults(current *vcs.Commit, results []error) (bad, good
 			if err.Report != nil {
 				output = err.Report.Output
 			}
-			env.saveDebugFile(current.Hash, i, output)
+			env.saveDebugFile(current.Hash, idx, output)
 		case *instance.CrashError:
-			bad++
+			step.Crashed++
+			step.addTitle(err.Report.Title)
 			verdicts = append(verdicts, fmt.Sprintf("crashed: %v", err))
 			output := err.Report.Report
 			if len(output) == 0 {
 				output = err.Report.Output
 			}
-			env.saveDebugFile(current.Hash, i, output)
+			env.saveDebugFile(current.Hash, idx, output)
 		default:
 			verdicts = append(verdicts, fmt.Sprintf("failed: %v", err))
 		}
 	}
+	step.Total += len(results)
 	unique := make(map[string]bool)
 	for _, verdict := range verdicts {
 		unique[verdict] = true
@@ -306,7 +466,6 @@ func (env *env) processResults(current *vcs.Commit, results []error) (bad, good
 			env.log("run #%v: %v", i, verdict)
 		}
 	}
-	return
 }
 
 // Note: linux-specific.
@@ -0,0 +1,313 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package bisect
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/instance"
+	"github.com/google/syzkaller/pkg/vcs"
+)
+
+// kconfig is a parsed kernel .config: option name (with CONFIG_ prefix) -> value.
+// Options that are explicitly not set have value "n".
+type kconfig struct {
+	values map[string]string
+	order  []string // options in the order of appearance
+}
+
+const kconfigNotSet = "n"
+
+func parseKconfig(data []byte) (*kconfig, error) {
+	cfg := &kconfig{values: make(map[string]string)}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		name, value := "", ""
+		if strings.HasPrefix(line, "# CONFIG_") && strings.HasSuffix(line, " is not set") {
+			name = strings.TrimSuffix(strings.TrimPrefix(line, "# "), " is not set")
+			value = kconfigNotSet
+		} else if strings.HasPrefix(line, "CONFIG_") {
+			eq := strings.IndexByte(line, '=')
+			if eq == -1 {
+				return nil, fmt.Errorf("bad kernel config line: %q", line)
+			}
+			name, value = line[:eq], line[eq+1:]
+		} else {
+			continue
+		}
+		if _, ok := cfg.values[name]; !ok {
+			cfg.order = append(cfg.order, name)
+		}
+		cfg.values[name] = value
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (cfg *kconfig) value(name string) string {
+	if v, ok := cfg.values[name]; ok {
+		return v
+	}
+	return kconfigNotSet
+}
+
+// diff returns sorted names of options that have different values in cfg and other.
+func (cfg *kconfig) diff(other *kconfig) []string {
+	var names []string
+	for _, name := range cfg.order {
+		if cfg.value(name) != other.value(name) {
+			names = append(names, name)
+		}
+	}
+	for _, name := range other.order {
+		if _, ok := cfg.values[name]; !ok && other.value(name) != kconfigNotSet {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// apply returns a copy of cfg with values of the options taken from other.
+func (cfg *kconfig) apply(other *kconfig, names []string) *kconfig {
+	res := &kconfig{
+		values: make(map[string]string),
+		order:  append([]string{}, cfg.order...),
+	}
+	for name, value := range cfg.values {
+		res.values[name] = value
+	}
+	for _, name := range names {
+		if _, ok := res.values[name]; !ok {
+			res.order = append(res.order, name)
+		}
+		res.values[name] = other.value(name)
+	}
+	return res
+}
+
+// option returns textual representation of the option as it appears in .config.
+func (cfg *kconfig) option(name string) string {
+	value := cfg.value(name)
+	if value == kconfigNotSet {
+		return fmt.Sprintf("# %v is not set", name)
+	}
+	return fmt.Sprintf("%v=%v", name, value)
+}
+
+func (cfg *kconfig) serialize() []byte {
+	buf := new(bytes.Buffer)
+	for _, name := range cfg.order {
+		fmt.Fprintf(buf, "%v\n", cfg.option(name))
+	}
+	return buf.Bytes()
+}
+
+// bisectConfig searches for the kernel config options that are responsible for the crash:
+// options of cfg.Kernel.Config that make the crash appear when applied to cfg.Kernel.BaselineConfig,
+// or options of cfg.Kernel.BaselineConfig that make the crash disappear when applied
+// to cfg.Kernel.Config if cfg.Fix is set.
+func (env *env) bisectConfig() (*ConfigResult, error) {
+	cfg := env.cfg
+	bad, err := parseKconfig(cfg.Kernel.Config)
+	if err != nil {
+		return nil, err
+	}
+	good, err := parseKconfig(cfg.Kernel.BaselineConfig)
+	if err != nil {
+		return nil, err
+	}
+	if env.inst, err = instance.NewEnv(&cfg.Manager); err != nil {
+		return nil, err
+	}
+	if env.head, err = env.repo.Poll(cfg.Kernel.Repo, cfg.Kernel.Branch); err != nil {
+		return nil, err
+	}
+	env.log("building syzkaller on %v", cfg.Syzkaller.Commit)
+	if err := env.inst.BuildSyzkaller(cfg.Syzkaller.Repo, cfg.Syzkaller.Commit); err != nil {
+		return nil, err
+	}
+	if _, err := env.repo.SwitchCommit(cfg.Kernel.Commit); err != nil {
+		return nil, err
+	}
+	base, target, want := good, bad, vcs.BisectBad
+	if cfg.Fix {
+		base, target, want = bad, good, vcs.BisectGood
+	}
+	names := base.diff(target)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("the configs do not differ")
+	}
+	env.log("testing the original config")
+	if res, err := env.test(cfg.Kernel.Config); err != nil {
+		return nil, err
+	} else if res != vcs.BisectBad {
+		return nil, fmt.Errorf("the crash wasn't reproduced with the original config")
+	}
+	env.log("testing the baseline config")
+	if res, err := env.test(cfg.Kernel.BaselineConfig); err != nil {
+		return nil, err
+	} else if res != vcs.BisectGood {
+		return nil, fmt.Errorf("the baseline config is not good: %v", res)
+	}
+	env.log("bisecting %v differing options", len(names))
+	drifted := make(map[string]bool)
+	allNames := names
+	names, unverified, err := env.minimizeOptions(names, want, func(names []string) (vcs.BisectResult, error) {
+		config := base.apply(target, names)
+		res, err := env.test(config.serialize())
+		if err == nil && res != vcs.BisectSkip {
+			env.checkConfigDrift(config, allNames, drifted)
+		}
+		return res, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := &ConfigResult{
+		Unverified: unverified,
+	}
+	for _, name := range names {
+		res.Options = append(res.Options, target.option(name))
+	}
+	for name := range drifted {
+		res.Drifted = append(res.Drifted, name)
+	}
+	sort.Strings(res.Drifted)
+	return res, nil
+}
+
+// checkConfigDrift compares values of the options in the built kernel config with the requested values
+// and adds options with different values to drifted. The kernel build system silently changes
+// options with unmet dependencies (e.g. olddefconfig), so such options were not actually tested.
+func (env *env) checkConfigDrift(requested *kconfig, names []string, drifted map[string]bool) {
+	data, err := ioutil.ReadFile(filepath.Join(env.cfg.Manager.Workdir, "image", "kernel.config"))
+	if err != nil {
+		env.log("can't check config drift: %v", err)
+		return
+	}
+	built, err := parseKconfig(data)
+	if err != nil {
+		env.log("can't check config drift: %v", err)
+		return
+	}
+	for _, name := range names {
+		if requested.value(name) == built.value(name) || drifted[name] {
+			continue
+		}
+		env.log("config drift: requested %q, built with %q", requested.option(name), built.option(name))
+		drifted[name] = true
+	}
+}
+
+// minimizeOptions returns a minimal subset of names for which test returns want
+// (it is assumed to do so for all names). Chunks of options that are not needed
+// are evicted, the rest are split until only single options remain.
+// If a test is skipped (e.g. the kernel does not build), the chunk is conservatively kept.
+// unverified contains the resulting options that are kept only due to skipped tests.
+func (env *env) minimizeOptions(names []string, want vcs.BisectResult,
+	test func([]string) (vcs.BisectResult, error)) (res, unverified []string, err error) {
+	skipped := make(map[string]bool)
+	// needed contains sets of options that were shown to contain at least one needed option.
+	var needed [][]string
+	// evict tests the chunks without evicted and returns true if evicted are not needed.
+	evict := func(chunks [][]string, evicted []string) (bool, error) {
+		res, err := test(flattenOptionChunks(chunks))
+		if err != nil {
+			return false, err
+		}
+		switch res {
+		case want:
+			env.log("options %v are not needed", strings.Join(evicted, " "))
+			return true, nil
+		case vcs.BisectSkip:
+			env.log("testing without options %v failed, keeping them", strings.Join(evicted, " "))
+			for _, name := range evicted {
+				skipped[name] = true
+			}
+		default:
+			needed = append(needed, evicted)
+		}
+		return false, nil
+	}
+	guilty := [][]string{names}
+again:
+	env.log("guilty chunks: %v", optionChunksToStr(guilty))
+	for i, chunk := range guilty {
+		if len(chunk) == 1 {
+			continue
+		}
+		guilty1 := guilty[:i]
+		guilty2 := guilty[i+1:]
+		chunk1 := chunk[:len(chunk)/2]
+		chunk2 := chunk[len(chunk)/2:]
+
+		ok, err := evict(append(append(append([][]string{}, guilty1...), chunk2), guilty2...), chunk1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			guilty = append(append(append([][]string{}, guilty1...), chunk2), guilty2...)
+			goto again
+		}
+		ok, err = evict(append(append(append([][]string{}, guilty1...), chunk1), guilty2...), chunk2)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			guilty = append(append(append([][]string{}, guilty1...), chunk1), guilty2...)
+			goto again
+		}
+		guilty = append(append(append([][]string{}, guilty1...), chunk1, chunk2), guilty2...)
+		goto again
+	}
+	res = flattenOptionChunks(guilty)
+	inRes := make(map[string]bool)
+	for _, name := range res {
+		inRes[name] = true
+	}
+	for _, set := range needed {
+		var left []string
+		for _, name := range set {
+			if inRes[name] {
+				left = append(left, name)
+			}
+		}
+		if len(left) == 1 {
+			delete(skipped, left[0])
+		}
+	}
+	for _, name := range res {
+		if skipped[name] {
+			unverified = append(unverified, name)
+		}
+	}
+	return res, unverified, nil
+}
+
+func flattenOptionChunks(chunks [][]string) []string {
+	var names []string
+	for _, c := range chunks {
+		names = append(names, c...)
+	}
+	return names
+}
+
+func optionChunksToStr(chunks [][]string) string {
+	var parts []string
+	for _, chunk := range chunks {
+		parts = append(parts, fmt.Sprintf("<%v>", len(chunk)))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package bisect
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/pkg/vcs"
+)
+
+func TestKconfig(t *testing.T) {
+	good, err := parseKconfig([]byte(`
+#
+# Automatically generated file; DO NOT EDIT.
+#
+CONFIG_A=y
+CONFIG_B=m
+# CONFIG_C is not set
+CONFIG_D="foo bar"
+CONFIG_E=1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad, err := parseKconfig([]byte(`
+CONFIG_A=y
+# CONFIG_B is not set
+CONFIG_C=y
+CONFIG_D="foo bar"
+CONFIG_E=2
+CONFIG_F=y
+# CONFIG_G is not set
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := good.diff(bad)
+	if want := []string{"CONFIG_B", "CONFIG_C", "CONFIG_E", "CONFIG_F"}; !reflect.DeepEqual(diff, want) {
+		t.Fatalf("bad diff: %q, want %q", diff, want)
+	}
+	if !reflect.DeepEqual(diff, bad.diff(good)) {
+		t.Fatalf("diff is not symmetric: %q vs %q", diff, bad.diff(good))
+	}
+	res := good.apply(bad, []string{"CONFIG_B", "CONFIG_F"})
+	want := `CONFIG_A=y
+# CONFIG_B is not set
+# CONFIG_C is not set
+CONFIG_D="foo bar"
+CONFIG_E=1
+CONFIG_F=y
+`
+	if got := string(res.serialize()); got != want {
+		t.Fatalf("bad config:\n%v\nwant:\n%v", got, want)
+	}
+	if len(good.apply(bad, diff).diff(bad)) != 0 {
+		t.Fatalf("applying all differences does not produce the same config")
+	}
+	if _, err := parseKconfig([]byte("CONFIG_FOO\n")); err == nil {
+		t.Fatalf("parsed bad config")
+	}
+}
+
+func TestMinimizeOptions(t *testing.T) {
+	env := &env{cfg: &Config{Trace: ioutil.Discard}}
+	seed := time.Now().UnixNano()
+	t.Logf("seed=%v", seed)
+	rnd := rand.New(rand.NewSource(seed))
+	for iter := 0; iter < 100; iter++ {
+		var names []string
+		guilty := make(map[string]bool)
+		// Kernel does not build without required options.
+		required := make(map[string]bool)
+		for i := rnd.Intn(200); i >= 0; i-- {
+			name := string(rune('A'+i%26)) + string(rune('A'+i/26))
+			names = append(names, name)
+			switch rnd.Intn(30) {
+			case 0:
+				guilty[name] = true
+			case 1:
+				required[name] = true
+			}
+		}
+		if len(guilty) == 0 {
+			name := names[rnd.Intn(len(names))]
+			delete(required, name)
+			guilty[name] = true
+		}
+		res, unverified, err := env.minimizeOptions(names, vcs.BisectBad,
+			func(names []string) (vcs.BisectResult, error) {
+				guiltyN, requiredN := 0, 0
+				for _, name := range names {
+					if guilty[name] {
+						guiltyN++
+					}
+					if required[name] {
+						requiredN++
+					}
+				}
+				if requiredN != len(required) {
+					return vcs.BisectSkip, nil
+				}
+				if guiltyN != len(guilty) {
+					return vcs.BisectGood, nil
+				}
+				return vcs.BisectBad, nil
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res) != len(guilty)+len(required) {
+			t.Fatalf("got %v options, want %v", len(res), len(guilty)+len(required))
+		}
+		for _, name := range res {
+			if !guilty[name] && !required[name] {
+				t.Fatalf("option %v is not guilty", name)
+			}
+		}
+		if len(unverified) != len(required) {
+			t.Fatalf("got %v unverified options, want %v", len(unverified), len(required))
+		}
+		for _, name := range unverified {
+			if !required[name] {
+				t.Fatalf("option %v is not unverified", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package bisect
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/vcs"
+)
+
+const (
+	// If a revision did not crash, but the probability that we missed the crash
+	// (given the observed crash rate) is higher than this, the revision is retested.
+	maxMissProbability = 0.05
+	// Max number of test rounds (numTestVMs runs each) for a single revision.
+	maxTestRounds = 3
+	numTestVMs    = 8
+)
+
+// Result is the result of a bisection.
+type Result struct {
+	// Commit is the first bad (or good for fix bisection) commit,
+	// nil if the crash is still unfixed or the bisection is inconclusive.
+	Commit *vcs.Commit
+	// Candidates are the commits that can be the result if the bisection is inconclusive
+	// because some revisions were skipped.
+	Candidates []*vcs.Commit
+	// Confidence is the estimated probability that none of the good verdicts
+	// was caused by a missed crash (the only kind of mistake we can detect).
+	// It's 0 if retesting of the found commit and its parent gave contradicting results
+	// or if the bisection is inconclusive.
+	Confidence float64
+	// Steps are all tested revisions in the order of testing.
+	Steps []*Step
+}
+
+// ConfigResult is the result of a config bisection.
+type ConfigResult struct {
+	// Options are the responsible config options in .config format.
+	Options []string
+	// Unverified are names of options from Options that are kept only because
+	// testing without them failed (the kernel did not build or boot).
+	Unverified []string
+	// Drifted are names of the bisected options that did not have the requested values
+	// in the built kernel config (e.g. olddefconfig dropped them because of unmet dependencies).
+	Drifted []string
+}
+
+// Step is the result of testing of a single revision.
+type Step struct {
+	Commit  string
+	Title   string // commit title
+	Result  vcs.BisectResult
+	Crashed int      // number of runs that crashed
+	OK      int      // number of runs that finished without a crash
+	Total   int      // total number of runs (including failed to boot, etc)
+	Titles  []string // unique titles of crashes
+}
+
+func (step *Step) addTitle(title string) {
+	for _, t := range step.Titles {
+		if t == title {
+			return
+		}
+	}
+	step.Titles = append(step.Titles, title)
+	sort.Strings(step.Titles)
+}
+
+// crashRate returns the estimated probability of a single run to crash on a bad revision.
+func crashRate(steps []*Step) float64 {
+	crashed, runs := 0, 0
+	for _, step := range steps {
+		if step.Crashed != 0 {
+			crashed += step.Crashed
+			runs += step.Crashed + step.OK
+		}
+	}
+	if runs == 0 {
+		return 0
+	}
+	return float64(crashed) / float64(runs)
+}
+
+// missProbability returns probability that ok runs without crashes were a bad revision.
+// Returns 0 if the crash rate is unknown.
+func missProbability(rate float64, ok int) float64 {
+	if rate == 0 {
+		return 0
+	}
+	return math.Pow(1-rate, float64(ok))
+}
+
+// confidence returns probability that all good verdicts in steps are correct.
+func confidence(steps []*Step) float64 {
+	rate := crashRate(steps)
+	conf := 1.0
+	for _, step := range steps {
+		if step.Result == vcs.BisectGood {
+			conf *= 1 - missProbability(rate, step.OK)
+		}
+	}
+	return conf
+}
+
+func (env *env) logSteps(steps []*Step) {
+	env.log("tested revisions:")
+	for _, step := range steps {
+		commit := step.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		env.log("  %v %-4v %v/%v crashed %v", commit, step.Result,
+			step.Crashed, step.Total, strings.Join(step.Titles, ", "))
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package bisect
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/vcs"
+)
+
+func TestConfidence(t *testing.T) {
+	steps := []*Step{
+		{Result: vcs.BisectBad, Crashed: 4, OK: 4, Total: 8},
+		{Result: vcs.BisectSkip, Total: 8},
+		{Result: vcs.BisectBad, Crashed: 2, OK: 6, Total: 8},
+		{Result: vcs.BisectGood, OK: 8, Total: 8},
+		{Result: vcs.BisectGood, OK: 2, Total: 8},
+	}
+	rate := crashRate(steps)
+	if rate != 6.0/16 {
+		t.Fatalf("bad crash rate %v", rate)
+	}
+	want := (1 - math.Pow(1-rate, 8)) * (1 - math.Pow(1-rate, 2))
+	if conf := confidence(steps); math.Abs(conf-want) > 1e-9 {
+		t.Fatalf("bad confidence %v, want %v", conf, want)
+	}
+	if miss := missProbability(rate, 16); miss > maxMissProbability {
+		t.Fatalf("16 runs are not enough to reach the threshold: %v", miss)
+	}
+	if miss := missProbability(rate, 2); miss <= maxMissProbability {
+		t.Fatalf("2 runs are enough to reach the threshold: %v", miss)
+	}
+	// No crashes observed: nothing to estimate.
+	if conf := confidence(steps[3:]); conf != 1 {
+		t.Fatalf("bad confidence without crashes %v", conf)
+	}
+}
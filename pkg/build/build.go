@@ -25,9 +25,11 @@ import (
 //  - kernel.config: actual kernel config used during build
 //  - obj/: directory with kernel object files (this should match KernelObject
 //    specified in sys/targets, e.g. vmlinux for linux)
+// If buildCmd is not nil, the kernel is built with the specified commands instead
+// of the OS-specific build procedure (see CommandConfig).
 func Image(targetOS, targetArch, vmType, kernelDir, outputDir, compiler, userspaceDir,
-	cmdlineFile, sysctlFile string, config []byte) error {
-	builder, err := getBuilder(targetOS, targetArch, vmType)
+	cmdlineFile, sysctlFile string, config []byte, buildCmd *CommandConfig) error {
+	builder, err := getBuilder(targetOS, targetArch, vmType, buildCmd)
 	if err != nil {
 		return err
 	}
@@ -43,8 +45,8 @@ func Image(targetOS, targetArch, vmType, kernelDir, outputDir, compiler, userspa
 	return builder.build(targetArch, vmType, kernelDir, outputDir, compiler, userspaceDir, cmdlineFile, sysctlFile, config)
 }
 
-func Clean(targetOS, targetArch, vmType, kernelDir string) error {
-	builder, err := getBuilder(targetOS, targetArch, vmType)
+func Clean(targetOS, targetArch, vmType, kernelDir string, buildCmd *CommandConfig) error {
+	builder, err := getBuilder(targetOS, targetArch, vmType, buildCmd)
 	if err != nil {
 		return err
 	}
@@ -61,7 +63,13 @@ type builder interface {
 	clean(kernelDir string) error
 }
 
-func getBuilder(targetOS, targetArch, vmType string) (builder, error) {
+func getBuilder(targetOS, targetArch, vmType string, buildCmd *CommandConfig) (builder, error) {
+	if buildCmd != nil {
+		if err := checkCommandConfig(buildCmd); err != nil {
+			return nil, err
+		}
+		return command{buildCmd}, nil
+	}
 	switch {
 	case targetOS == "linux" && targetArch == "amd64" && vmType == "gvisor":
 		return gvisor{}, nil
@@ -0,0 +1,295 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package build
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+// Cache is a content-addressed on-disk cache of kernel images produced by Image.
+// Entries are keyed by hash of all build inputs (see CacheKey), so the same cache dir
+// can be shared by several bisections and syz-ci jobs (including concurrently running ones).
+// When the total size of the cache exceeds the limit, least recently used entries are evicted.
+type Cache struct {
+	dir     string
+	maxSize int64
+	mu      sync.Mutex
+	stats   CacheStats
+}
+
+type CacheStats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+}
+
+func (stats CacheStats) HitRate() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+func (stats CacheStats) String() string {
+	return fmt.Sprintf("hits: %v, misses: %v (hit rate %.0f%%), evictions: %v",
+		stats.Hits, stats.Misses, stats.HitRate()*100, stats.Evictions)
+}
+
+// CacheKey describes all inputs of a kernel image build.
+type CacheKey struct {
+	TargetOS   string
+	TargetArch string
+	VMType     string
+	Commit     string
+	Patch      []byte // patch applied on top of Commit, if any
+	CompilerID string // see CompilerIdentity
+	Config     []byte
+	Userspace  string // userspace image file or dir, names and contents of files are hashed
+	Cmdline    string // file with kernel cmdline, contents are hashed
+	Sysctl     string // file with sysctl values, contents are hashed
+	Command    *CommandConfig
+}
+
+func (key *CacheKey) hash() (string, error) {
+	pieces := [][]byte{
+		[]byte(key.TargetOS), []byte(key.TargetArch), []byte(key.VMType),
+		[]byte(key.Commit), key.Patch, []byte(key.CompilerID), key.Config, []byte(key.Userspace),
+	}
+	if key.Userspace != "" {
+		// Images are hashed on every lookup, but that's still negligible compared to a kernel build.
+		sums, err := fileHashes(key.Userspace)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash userspace %v: %v", key.Userspace, err)
+		}
+		pieces = append(pieces, sums)
+	}
+	if key.Command != nil {
+		data, err := json.Marshal(key.Command)
+		if err != nil {
+			return "", err
+		}
+		pieces = append(pieces, data)
+	}
+	for _, file := range []string{key.Cmdline, key.Sysctl} {
+		var data []byte
+		if file != "" {
+			var err error
+			if data, err = ioutil.ReadFile(file); err != nil {
+				return "", fmt.Errorf("failed to read %v: %v", file, err)
+			}
+		}
+		pieces = append(pieces, data)
+	}
+	// Hash lengths as well, so that moving bytes between pieces changes the hash.
+	var lens []byte
+	for _, piece := range pieces {
+		lens = append(lens, fmt.Sprintf("%v,", len(piece))...)
+	}
+	return hash.String(append(pieces, lens)...), nil
+}
+
+const cacheTmpPrefix = "tmp-"
+
+// NewCache creates a cache in dir that holds at most maxSize bytes (0 means no limit).
+func NewCache(dir string, maxSize int64) (*Cache, error) {
+	if err := osutil.MkdirAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to create build cache dir: %v", err)
+	}
+	return &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+	}, nil
+}
+
+// Get copies the cached image for key into outputDir. Returns false if there is no such image.
+func (c *Cache) Get(key *CacheKey, outputDir string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sig, err := key.hash()
+	if err != nil {
+		return false, err
+	}
+	entry := filepath.Join(c.dir, sig)
+	if !osutil.IsExist(entry) {
+		c.stats.Misses++
+		return false, nil
+	}
+	if err := copyDir(entry, outputDir); err != nil {
+		if !osutil.IsExist(entry) {
+			// Evicted by a concurrent user of the cache.
+			c.stats.Misses++
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to copy cached build: %v", err)
+	}
+	// Modification time is used as last access time for eviction.
+	now := time.Now()
+	os.Chtimes(entry, now, now)
+	c.stats.Hits++
+	return true, nil
+}
+
+// Put stores the image in outputDir (produced by Image) in the cache under key.
+func (c *Cache) Put(key *CacheKey, outputDir string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sig, err := key.hash()
+	if err != nil {
+		return err
+	}
+	entry := filepath.Join(c.dir, sig)
+	if osutil.IsExist(entry) {
+		return nil
+	}
+	// Copy to a temp dir first so that other users of the cache never observe partial entries.
+	tmpDir, err := ioutil.TempDir(c.dir, cacheTmpPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := copyDir(outputDir, tmpDir); err != nil {
+		return fmt.Errorf("failed to copy build to cache: %v", err)
+	}
+	if err := os.Rename(tmpDir, entry); err != nil {
+		if osutil.IsExist(entry) {
+			return nil // stored concurrently by somebody else
+		}
+		return err
+	}
+	return c.evict(sig)
+}
+
+// evict removes least recently used entries (except for keep) until the cache fits into maxSize.
+func (c *Cache) evict(keep string) error {
+	if c.maxSize == 0 {
+		return nil
+	}
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	type entry struct {
+		name string
+		size int64
+		used time.Time
+	}
+	var entries []entry
+	var total int64
+	for _, f := range files {
+		if !f.IsDir() || strings.HasPrefix(f.Name(), cacheTmpPrefix) {
+			continue
+		}
+		size, err := dirSize(filepath.Join(c.dir, f.Name()))
+		if err != nil {
+			continue // concurrently evicted
+		}
+		total += size
+		if f.Name() != keep {
+			entries = append(entries, entry{f.Name(), size, f.ModTime()})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].used.Before(entries[j].used)
+	})
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if err := os.RemoveAll(filepath.Join(c.dir, e.name)); err != nil {
+			return err
+		}
+		total -= e.size
+		c.stats.Evictions++
+	}
+	return nil
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// copyDir recursively copies contents of srcDir into dstDir, overwriting existing files.
+func copyDir(srcDir, dstDir string) error {
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dstDir, rel)
+		if info.IsDir() {
+			return osutil.MkdirAll(dst)
+		}
+		return osutil.CopyFile(path, dst)
+	})
+}
+
+// fileStamps returns relative names, sizes and modification times of all files in path (file or dir).
+// fileHashes returns names, modes and hashes of contents of all files in path.
+func fileHashes(path string) ([]byte, error) {
+	var sums []byte
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		sum := ""
+		if info.Mode().IsRegular() {
+			if sum, err = fileHash(file); err != nil {
+				return err
+			}
+		}
+		sums = append(sums, fmt.Sprintf("%v:%v:%v\n", rel, info.Mode(), sum)...)
+		return nil
+	})
+	return sums, err
+}
+
+func fileHash(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syz-build-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache, err := NewCache(filepath.Join(dir, "cache"), 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	makeBuild := func(name string) string {
+		buildDir := filepath.Join(dir, name)
+		if err := osutil.MkdirAll(filepath.Join(buildDir, "obj")); err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, 50)
+		copy(data, name)
+		for _, file := range []string{"image", filepath.Join("obj", "vmlinux")} {
+			if err := osutil.WriteFile(filepath.Join(buildDir, file), data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return buildDir
+	}
+	checkBuild := func(key *CacheKey, name string, want bool) {
+		out := filepath.Join(dir, "out")
+		os.RemoveAll(out)
+		ok, err := cache.Get(key, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != want {
+			t.Fatalf("build %v: cached %v, want %v", name, ok, want)
+		}
+		if !ok {
+			return
+		}
+		data, err := ioutil.ReadFile(filepath.Join(out, "obj", "vmlinux"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data[:len(name)]) != name {
+			t.Fatalf("got wrong build %q, want %q", data[:len(name)], name)
+		}
+	}
+	key1 := &CacheKey{Commit: "1", Config: []byte("CONFIG_FOO=y")}
+	key2 := &CacheKey{Commit: "1", Config: []byte("CONFIG_FOO=n")}
+	key3 := &CacheKey{Commit: "1", Config: []byte("CONFIG_FOO=n"), Patch: []byte("patch")}
+	checkBuild(key1, "build1", false)
+	if err := cache.Put(key1, makeBuild("build1")); err != nil {
+		t.Fatal(err)
+	}
+	checkBuild(key1, "build1", true)
+	checkBuild(key2, "build2", false)
+	if err := cache.Put(key2, makeBuild("build2")); err != nil {
+		t.Fatal(err)
+	}
+	checkBuild(key2, "build2", true)
+	checkBuild(key1, "build1", true)
+	// Make build2 least recently used regardless of timestamp granularity.
+	past := time.Now().Add(-time.Hour)
+	entries, err := ioutil.ReadDir(filepath.Join(dir, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig2, _ := key2.hash()
+	for _, entry := range entries {
+		if entry.Name() == sig2 {
+			os.Chtimes(filepath.Join(dir, "cache", entry.Name()), past, past)
+		}
+	}
+	// Each build is 100 bytes, so the third build evicts the least recently used one.
+	if err := cache.Put(key3, makeBuild("build3")); err != nil {
+		t.Fatal(err)
+	}
+	checkBuild(key3, "build3", true)
+	checkBuild(key1, "build1", true)
+	checkBuild(key2, "build2", false)
+	stats := cache.Stats()
+	if stats.Hits != 5 || stats.Misses != 3 || stats.Evictions != 1 {
+		t.Fatalf("bad stats: %+v", stats)
+	}
+}
+
+func TestCacheKeyUserspace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syz-build-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	image := filepath.Join(dir, "image")
+	if err := osutil.WriteFile(image, []byte("image")); err != nil {
+		t.Fatal(err)
+	}
+	key := &CacheKey{Commit: "1", Userspace: dir}
+	sig1, err := key.hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Userspace image is updated in place.
+	if err := osutil.WriteFile(image, []byte("new image")); err != nil {
+		t.Fatal(err)
+	}
+	sig2, err := key.hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig1 == sig2 {
+		t.Fatalf("hash did not change after userspace update")
+	}
+	// Update that preserves size and modification time.
+	info, err := os.Stat(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := osutil.WriteFile(image, []byte("old image")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(image, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	sig3, err := key.hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig3 == sig2 {
+		t.Fatalf("hash did not change after userspace update with the same size and mtime")
+	}
+	// Touching the image does not invalidate the cache.
+	now := time.Now().Add(time.Hour)
+	if err := os.Chtimes(image, now, now); err != nil {
+		t.Fatal(err)
+	}
+	sig4, err := key.hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig4 != sig3 {
+		t.Fatalf("hash changed after userspace touch")
+	}
+	os.RemoveAll(dir)
+	if _, err := key.hash(); err == nil {
+		t.Fatalf("no error for missing userspace")
+	}
+}
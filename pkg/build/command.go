@@ -0,0 +1,148 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+// CommandConfig describes a generic build that runs the given shell commands instead of
+// the built-in OS-specific build procedure. This allows to build kernels with out-of-tree
+// toolchains, custom make targets or custom image creation scripts without changes to syzkaller.
+//
+// Steps and clean commands are executed with sh -c in the kernel dir with the following
+// additional env vars (plus Env):
+//  - SYZ_TARGET_ARCH: target arch (e.g. amd64)
+//  - SYZ_VM_TYPE: VM type (e.g. qemu)
+//  - SYZ_KERNEL_DIR: kernel source dir
+//  - SYZ_OUTPUT_DIR: output dir (see Image)
+//  - SYZ_COMPILER: compiler binary
+//  - SYZ_KERNEL_CONFIG: file with the kernel config (empty if no config is provided)
+//  - SYZ_USERSPACE: userspace system dir
+//  - SYZ_CMDLINE_FILE: file with additional kernel command line (optional)
+//  - SYZ_SYSCTL_FILE: file with additional sysctl values (optional)
+// Artifact paths are relative to the kernel dir (or absolute).
+type CommandConfig struct {
+	Steps []string `json:"steps"`
+	Clean []string `json:"clean"`
+	// Env vars in NAME=VALUE format, e.g. PATH of the cross toolchain or ARCH/CROSS_COMPILE.
+	Env []string `json:"env"`
+	// Step timeout in minutes (60 by default).
+	Timeout int `json:"timeout"`
+	// Image is the only required artifact.
+	Image string `json:"image"`
+	// Kernel and initrd for injected boot (optional).
+	Kernel string `json:"kernel"`
+	Initrd string `json:"initrd"`
+	// SSH key for the image (optional).
+	Key string `json:"key"`
+	// Kernel object file with debug info (e.g. vmlinux), copied to obj/ dir (optional).
+	KernelObject string `json:"kernel_object"`
+	// Actual kernel config used during build (e.g. .config), copied as kernel.config (optional).
+	KernelConfig string `json:"kernel_config"`
+}
+
+func checkCommandConfig(cfg *CommandConfig) error {
+	if len(cfg.Steps) == 0 {
+		return fmt.Errorf("build command config: no build steps")
+	}
+	if cfg.Image == "" {
+		return fmt.Errorf("build command config: image is not specified")
+	}
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("build command config: negative timeout")
+	}
+	return nil
+}
+
+type command struct {
+	cfg *CommandConfig
+}
+
+func (c command) build(targetArch, vmType, kernelDir, outputDir, compiler, userspaceDir,
+	cmdlineFile, sysctlFile string, config []byte) error {
+	configFile := ""
+	if len(config) != 0 {
+		configFile = filepath.Join(outputDir, "kernel.config")
+	}
+	env := []string{
+		"SYZ_TARGET_ARCH=" + targetArch,
+		"SYZ_VM_TYPE=" + vmType,
+		"SYZ_KERNEL_DIR=" + osutil.Abs(kernelDir),
+		"SYZ_OUTPUT_DIR=" + osutil.Abs(outputDir),
+		"SYZ_COMPILER=" + compiler,
+		"SYZ_KERNEL_CONFIG=" + osutil.Abs(configFile),
+		"SYZ_USERSPACE=" + osutil.Abs(userspaceDir),
+		"SYZ_CMDLINE_FILE=" + osutil.Abs(cmdlineFile),
+		"SYZ_SYSCTL_FILE=" + osutil.Abs(sysctlFile),
+	}
+	if err := c.run(kernelDir, c.cfg.Steps, env); err != nil {
+		return extractRootCause(err)
+	}
+	artifacts := []struct {
+		src      string
+		dst      string
+		required bool
+	}{
+		{c.cfg.Image, "image", true},
+		{c.cfg.Kernel, "kernel", false},
+		{c.cfg.Initrd, "initrd", false},
+		{c.cfg.Key, "key", false},
+		{c.cfg.KernelObject, filepath.Join("obj", filepath.Base(c.cfg.KernelObject)), false},
+		{c.cfg.KernelConfig, "kernel.config", false},
+	}
+	for _, art := range artifacts {
+		if art.src == "" {
+			continue
+		}
+		src := art.src
+		if !filepath.IsAbs(src) {
+			src = filepath.Join(kernelDir, src)
+		}
+		if !osutil.IsExist(src) {
+			if art.required {
+				return fmt.Errorf("build did not produce %v", art.src)
+			}
+			continue
+		}
+		// Note: we use CopyFile instead of Rename because src and dst can be on different filesystems.
+		if err := osutil.CopyFile(src, filepath.Join(outputDir, art.dst)); err != nil {
+			return err
+		}
+	}
+	if c.cfg.Key != "" {
+		if err := os.Chmod(filepath.Join(outputDir, "key"), 0600); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c command) clean(kernelDir string) error {
+	return c.run(kernelDir, c.cfg.Clean, []string{"SYZ_KERNEL_DIR=" + osutil.Abs(kernelDir)})
+}
+
+func (c command) run(dir string, steps, env []string) error {
+	timeout := time.Hour
+	if c.cfg.Timeout != 0 {
+		timeout = time.Duration(c.cfg.Timeout) * time.Minute
+	}
+	for _, step := range steps {
+		cmd := osutil.Command("sh", "-c", step)
+		if err := osutil.Sandbox(cmd, true, true); err != nil {
+			return err
+		}
+		cmd.Dir = dir
+		cmd.Env = append(append(append([]string{}, os.Environ()...), env...), c.cfg.Env...)
+		if _, err := osutil.Run(timeout, cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
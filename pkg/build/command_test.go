@@ -0,0 +1,86 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+func init() {
+	// Disable sandboxing because the test kernel dir is created without sandboxing.
+	os.Setenv("SYZ_DISABLE_SANDBOXING", "yes")
+}
+
+func TestCommandBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syz-build-command-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	kernelDir := filepath.Join(dir, "kernel")
+	outputDir := filepath.Join(dir, "output")
+	if err := osutil.MkdirAll(kernelDir); err != nil {
+		t.Fatal(err)
+	}
+	cmd := &CommandConfig{
+		Steps: []string{
+			"mkdir -p out",
+			"echo $SYZ_TARGET_ARCH-$SYZ_VM_TYPE-$FOO > out/image",
+			"cp $SYZ_KERNEL_CONFIG .config",
+			"echo vmlinux > out/vmlinux",
+		},
+		Clean:        []string{"rm -rf out .config"},
+		Env:          []string{"FOO=bar"},
+		Image:        "out/image",
+		Kernel:       "out/bzImage",
+		KernelObject: "out/vmlinux",
+		KernelConfig: ".config",
+	}
+	if err := Image("linux", "amd64", "qemu", kernelDir, outputDir, "", "", "", "",
+		[]byte("CONFIG_FOO=y\n"), cmd); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{
+		"image":                         "amd64-qemu-bar\n",
+		filepath.Join("obj", "vmlinux"): "vmlinux\n",
+		"kernel.config":                 "CONFIG_FOO=y\n",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(outputDir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%v: got %q, want %q", file, data, want)
+		}
+	}
+	if osutil.IsExist(filepath.Join(outputDir, "kernel")) {
+		t.Errorf("kernel is copied, but it was not built")
+	}
+	if err := Clean("linux", "amd64", "qemu", kernelDir, cmd); err != nil {
+		t.Fatal(err)
+	}
+	if osutil.IsExist(filepath.Join(kernelDir, "out")) {
+		t.Errorf("clean did not remove build output")
+	}
+	// Missing image must fail the build.
+	cmd.Steps = []string{"true"}
+	if err := Image("linux", "amd64", "qemu", kernelDir, outputDir, "", "", "", "", nil, cmd); err == nil {
+		t.Errorf("build without image succeeded")
+	}
+	// Failing step must fail the build.
+	cmd.Steps = []string{"echo error: foo; exit 1"}
+	if err := Image("linux", "amd64", "qemu", kernelDir, outputDir, "", "", "", "", nil, cmd); err == nil {
+		t.Errorf("failing build succeeded")
+	}
+	// Config without steps is rejected.
+	cmd.Steps = nil
+	if err := Clean("linux", "amd64", "qemu", kernelDir, cmd); err == nil {
+		t.Errorf("config without steps is accepted")
+	}
+}
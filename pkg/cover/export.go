@@ -0,0 +1,263 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/google/syzkaller/pkg/symbolizer"
+)
+
+// Supported coverage report formats.
+const (
+	FormatHTML  = "html"
+	FormatLCOV  = "lcov"
+	FormatJSON  = "json"
+	FormatFuncs = "funcs"
+)
+
+var Formats = []string{FormatHTML, FormatLCOV, FormatJSON, FormatFuncs}
+
+// FileCoverage is per-file coverage summary.
+// Lines are source lines that contain coverage points, all files and functions
+// with coverage points in the kernel are accounted (including completely uncovered).
+type FileCoverage struct {
+	File         string          `json:"file"`
+	CoveredLines int             `json:"covered_lines"`
+	TotalLines   int             `json:"total_lines"`
+	Functions    []*FuncCoverage `json:"functions"`
+}
+
+type FuncCoverage struct {
+	Name         string `json:"name"`
+	Line         int    `json:"line"` // first line of the function with a coverage point
+	CoveredLines int    `json:"covered_lines"`
+	TotalLines   int    `json:"total_lines"`
+}
+
+type fileSummary struct {
+	FileCoverage
+	path  string // path to the source file on the local machine
+	lines []coverage
+}
+
+// DoFormat writes coverage report for pcs in the given format (one of Formats).
+func (rg *ReportGenerator) DoFormat(w io.Writer, pcs []uint64, format string) error {
+	switch format {
+	case FormatHTML:
+		return rg.Do(w, pcs)
+	case FormatLCOV:
+		return rg.DoLCOV(w, pcs)
+	case FormatJSON:
+		return rg.DoJSON(w, pcs)
+	case FormatFuncs:
+		funcs, err := rg.FuncStats(pcs)
+		if err != nil {
+			return err
+		}
+		return WriteFuncStats(w, funcs)
+	default:
+		return fmt.Errorf("unknown coverage format %q, supported: %v", format, Formats)
+	}
+}
+
+// DoLCOV writes coverage for pcs as lcov tracefile (can be consumed by genhtml and other tools).
+func (rg *ReportGenerator) DoLCOV(w io.Writer, pcs []uint64) error {
+	files, err := rg.summarize(pcs)
+	if err != nil {
+		return err
+	}
+	return writeLCOV(w, files)
+}
+
+// DoJSON writes per-file/per-function coverage summary for pcs as JSON.
+func (rg *ReportGenerator) DoJSON(w io.Writer, pcs []uint64) error {
+	files, err := rg.summarize(pcs)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, files)
+}
+
+// Summary returns per-file/per-function coverage for pcs sorted by file name.
+func (rg *ReportGenerator) Summary(pcs []uint64) ([]*FileCoverage, error) {
+	files, err := rg.summarize(pcs)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*FileCoverage, len(files))
+	for i, f := range files {
+		res[i] = &f.FileCoverage
+	}
+	return res, nil
+}
+
+func (rg *ReportGenerator) summarize(pcs []uint64) ([]*fileSummary, error) {
+	if len(pcs) == 0 {
+		return nil, fmt.Errorf("no coverage data available")
+	}
+	coveredPCs := make(map[uint64]bool)
+	for _, pc := range pcs {
+		coveredPCs[PreviousInstructionPC(rg.arch, pc)] = true
+	}
+	var coveredList, uncoveredPCs []uint64
+	for pc := range coveredPCs {
+		coveredList = append(coveredList, pc)
+	}
+	for _, pc := range rg.coverPCs {
+		if !coveredPCs[pc] {
+			uncoveredPCs = append(uncoveredPCs, pc)
+		}
+	}
+	covered, _, err := rg.symbolize(coveredList)
+	if err != nil {
+		return nil, err
+	}
+	if len(covered) == 0 {
+		return nil, fmt.Errorf("'%s' does not have debug info (set CONFIG_DEBUG_INFO=y)", rg.vmlinux)
+	}
+	uncovered, _, err := rg.symbolize(uncoveredPCs)
+	if err != nil {
+		return nil, err
+	}
+	prefix := filePrefix(append(append([]symbolizer.Frame{}, covered...), uncovered...))
+	files := summarizeFrames(covered, uncovered)
+	for _, f := range files {
+		f.File, f.path = rg.fileNames(prefix, f.File)
+	}
+	sortSummary(files)
+	return files, nil
+}
+
+func sortSummary(files []*fileSummary) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].File < files[j].File
+	})
+}
+
+// summarizeFrames groups lines by files and functions. Unlike fileSet, uncovered frames
+// are accounted for all functions, so that the summary includes completely uncovered functions and files.
+func summarizeFrames(covered, uncovered []symbolizer.Frame) []*fileSummary {
+	type funcKey struct {
+		file string
+		name string
+	}
+	funcLines := make(map[funcKey]map[int]bool)
+	fileLines := make(map[string]map[int]bool)
+	add := func(frame symbolizer.Frame, cov bool) {
+		key := funcKey{frame.File, frame.Func}
+		if funcLines[key] == nil {
+			funcLines[key] = make(map[int]bool)
+		}
+		if cov || !funcLines[key][frame.Line] {
+			funcLines[key][frame.Line] = cov
+		}
+		if fileLines[frame.File] == nil {
+			fileLines[frame.File] = make(map[int]bool)
+		}
+		if cov || !fileLines[frame.File][frame.Line] {
+			fileLines[frame.File][frame.Line] = cov
+		}
+	}
+	for _, frame := range covered {
+		add(frame, true)
+	}
+	for _, frame := range uncovered {
+		add(frame, false)
+	}
+	files := make(map[string]*fileSummary)
+	for f, lines := range fileLines {
+		file := &fileSummary{
+			FileCoverage: FileCoverage{File: f},
+		}
+		for ln, cov := range lines {
+			file.lines = append(file.lines, coverage{ln, cov})
+			file.TotalLines++
+			if cov {
+				file.CoveredLines++
+			}
+		}
+		sort.Slice(file.lines, func(i, j int) bool {
+			return file.lines[i].line < file.lines[j].line
+		})
+		files[f] = file
+	}
+	for key, lines := range funcLines {
+		fn := &FuncCoverage{Name: key.name}
+		for line, cov := range lines {
+			if fn.Line == 0 || line < fn.Line {
+				fn.Line = line
+			}
+			fn.TotalLines++
+			if cov {
+				fn.CoveredLines++
+			}
+		}
+		file := files[key.file]
+		file.Functions = append(file.Functions, fn)
+	}
+	res := make([]*fileSummary, 0, len(files))
+	for _, file := range files {
+		sort.Slice(file.Functions, func(i, j int) bool {
+			f1, f2 := file.Functions[i], file.Functions[j]
+			if f1.Line != f2.Line {
+				return f1.Line < f2.Line
+			}
+			return f1.Name < f2.Name
+		})
+		res = append(res, file)
+	}
+	return res
+}
+
+func writeLCOV(w io.Writer, files []*fileSummary) error {
+	buf := bufio.NewWriter(w)
+	for _, f := range files {
+		fmt.Fprintf(buf, "TN:\nSF:%v\n", f.path)
+		hit := 0
+		for _, fn := range f.Functions {
+			fmt.Fprintf(buf, "FN:%v,%v\n", fn.Line, fn.Name)
+		}
+		for _, fn := range f.Functions {
+			count := 0
+			if fn.CoveredLines != 0 {
+				count = 1
+				hit++
+			}
+			fmt.Fprintf(buf, "FNDA:%v,%v\n", count, fn.Name)
+		}
+		fmt.Fprintf(buf, "FNF:%v\nFNH:%v\n", len(f.Functions), hit)
+		for _, ln := range f.lines {
+			count := 0
+			if ln.covered {
+				count = 1
+			}
+			fmt.Fprintf(buf, "DA:%v,%v\n", ln.line, count)
+		}
+		fmt.Fprintf(buf, "LF:%v\nLH:%v\nend_of_record\n", f.TotalLines, f.CoveredLines)
+	}
+	return buf.Flush()
+}
+
+func writeJSON(w io.Writer, files []*fileSummary) error {
+	res := struct {
+		CoveredLines int             `json:"covered_lines"`
+		TotalLines   int             `json:"total_lines"`
+		Files        []*FileCoverage `json:"files"`
+	}{
+		Files: make([]*FileCoverage, len(files)),
+	}
+	for i, f := range files {
+		res.Files[i] = &f.FileCoverage
+		res.CoveredLines += f.CoveredLines
+		res.TotalLines += f.TotalLines
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(res)
+}
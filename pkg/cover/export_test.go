@@ -0,0 +1,119 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/symbolizer"
+)
+
+func testFrames() ([]symbolizer.Frame, []symbolizer.Frame) {
+	covered := []symbolizer.Frame{
+		{Func: "foo", File: "a.c", Line: 10},
+		{Func: "foo", File: "a.c", Line: 12},
+		{Func: "bar", File: "b.c", Line: 5},
+		{Func: "inl", File: "a.h", Line: 3, Inline: true},
+	}
+	uncovered := []symbolizer.Frame{
+		{Func: "foo", File: "a.c", Line: 11},
+		{Func: "foo", File: "a.c", Line: 12},
+		{Func: "bar", File: "b.c", Line: 7},
+		{Func: "baz", File: "b.c", Line: 20},
+		{Func: "qux", File: "c.c", Line: 1},
+	}
+	return covered, uncovered
+}
+
+func TestWriteLCOV(t *testing.T) {
+	files := summarizeFrames(testFrames())
+	for _, f := range files {
+		f.path = "/src/" + f.File
+	}
+	sortSummary(files)
+	buf := new(bytes.Buffer)
+	if err := writeLCOV(buf, files); err != nil {
+		t.Fatal(err)
+	}
+	want := `TN:
+SF:/src/a.c
+FN:10,foo
+FNDA:1,foo
+FNF:1
+FNH:1
+DA:10,1
+DA:11,0
+DA:12,1
+LF:3
+LH:2
+end_of_record
+TN:
+SF:/src/a.h
+FN:3,inl
+FNDA:1,inl
+FNF:1
+FNH:1
+DA:3,1
+LF:1
+LH:1
+end_of_record
+TN:
+SF:/src/b.c
+FN:5,bar
+FN:20,baz
+FNDA:1,bar
+FNDA:0,baz
+FNF:2
+FNH:1
+DA:5,1
+DA:7,0
+DA:20,0
+LF:3
+LH:1
+end_of_record
+TN:
+SF:/src/c.c
+FN:1,qux
+FNDA:0,qux
+FNF:1
+FNH:0
+DA:1,0
+LF:1
+LH:0
+end_of_record
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	files := summarizeFrames(testFrames())
+	sortSummary(files)
+	buf := new(bytes.Buffer)
+	if err := writeJSON(buf, files); err != nil {
+		t.Fatal(err)
+	}
+	var res struct {
+		CoveredLines int             `json:"covered_lines"`
+		TotalLines   int             `json:"total_lines"`
+		Files        []*FileCoverage `json:"files"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.CoveredLines != 4 || res.TotalLines != 8 || len(res.Files) != 4 {
+		t.Fatalf("bad summary: %+v", res)
+	}
+	foo := res.Files[0].Functions[0]
+	if foo.Name != "foo" || foo.Line != 10 || foo.CoveredLines != 2 || foo.TotalLines != 3 {
+		t.Fatalf("bad function summary: %+v", foo)
+	}
+	qux := res.Files[3].Functions[0]
+	if qux.Name != "qux" || qux.CoveredLines != 0 || qux.TotalLines != 1 {
+		t.Fatalf("bad uncovered function summary: %+v", qux)
+	}
+}
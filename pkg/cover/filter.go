@@ -0,0 +1,63 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"path"
+)
+
+// PCRange is a half-open range [Start, End) of kernel PCs.
+type PCRange struct {
+	Start uint64
+	End   uint64
+}
+
+// FilterRanges returns PC ranges of functions which name matches one of funcs glob patterns
+// or which source file matches one of files glob patterns (see matchFile).
+func (rg *ReportGenerator) FilterRanges(files, funcs []string) ([]PCRange, error) {
+	var res []PCRange
+	var all []int
+	for idx, s := range rg.symbols {
+		if matchAny(funcs, s.name) {
+			res = append(res, PCRange{s.start, s.end})
+			continue
+		}
+		all = append(all, idx)
+	}
+	if len(files) == 0 || len(all) == 0 {
+		return res, nil
+	}
+	funcFiles, err := rg.funcFiles(all)
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range all {
+		if matchFile(files, funcFiles[idx]) {
+			s := rg.symbols[idx]
+			res = append(res, PCRange{s.start, s.end})
+		}
+	}
+	return res, nil
+}
+
+// matchFile returns true if file or any of its parent directories matches one of patterns.
+func matchFile(patterns []string, file string) bool {
+	for ; file != "." && file != "/" && file != ""; file = path.Dir(file) {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(path.Clean(pattern), file); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
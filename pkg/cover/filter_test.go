@@ -0,0 +1,34 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"testing"
+)
+
+func TestMatchFile(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		result  bool
+	}{
+		{"net/sctp", "net/sctp/socket.c", true},
+		{"net/sctp/", "net/sctp/socket.c", true},
+		{"net/sctp/*.c", "net/sctp/socket.c", true},
+		{"net/ipv4/tcp*.c", "net/ipv4/tcp_input.c", true},
+		{"net/ipv4/tcp*.c", "net/ipv4/udp.c", false},
+		{"net", "net/ipv4/udp.c", true},
+		{"mm", "net/ipv4/udp.c", false},
+		{"net/*", "net/ipv4/udp.c", true},
+		{"include/linux/*.h", "include/linux/skbuff.h", true},
+		{"*.h", "include/linux/skbuff.h", false},
+		{"net", "", false},
+	}
+	for i, test := range tests {
+		if res := matchFile([]string{test.pattern}, test.file); res != test.result {
+			t.Errorf("#%v: pattern=%q file=%q want=%v got=%v",
+				i, test.pattern, test.file, test.result, res)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// FuncStats is coverage of a single function in terms of coverage points (PCs).
+type FuncStats struct {
+	Name    string
+	File    string
+	Covered int
+	Total   int
+}
+
+func (fs *FuncStats) Percent() int {
+	if fs.Total == 0 {
+		return 0
+	}
+	return fs.Covered * 100 / fs.Total
+}
+
+// FuncDiff describes how coverage of a single function changed between two coverage sets.
+type FuncDiff struct {
+	Name       string
+	File       string
+	Total      int
+	OldCovered int
+	NewCovered int
+	Gained     []string // source lines (file:line) covered only in the new set
+	Lost       []string // source lines (file:line) covered only in the old set
+}
+
+// FuncStats returns coverage of all functions that contain pcs, sorted by file and function name.
+func (rg *ReportGenerator) FuncStats(pcs []uint64) ([]*FuncStats, error) {
+	if len(pcs) == 0 {
+		return nil, fmt.Errorf("no coverage data available")
+	}
+	covered := rg.coveredPCsPerFunc(rg.coverPoints(pcs))
+	var funcs []int
+	for idx := range covered {
+		funcs = append(funcs, idx)
+	}
+	files, err := rg.funcFiles(funcs)
+	if err != nil {
+		return nil, err
+	}
+	var res []*FuncStats
+	for _, idx := range funcs {
+		res = append(res, &FuncStats{
+			Name:    rg.symbols[idx].name,
+			File:    files[idx],
+			Covered: covered[idx],
+			Total:   rg.totalPCs(idx),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].File != res[j].File {
+			return res[i].File < res[j].File
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res, nil
+}
+
+// Diff returns functions which coverage differs between oldPCs and newPCs,
+// sorted by file and function name.
+func (rg *ReportGenerator) Diff(oldPCs, newPCs []uint64) ([]*FuncDiff, error) {
+	oldSet, newSet := rg.coverPoints(oldPCs), rg.coverPoints(newPCs)
+	var gained, lost []uint64
+	for pc := range newSet {
+		if !oldSet[pc] {
+			gained = append(gained, pc)
+		}
+	}
+	for pc := range oldSet {
+		if !newSet[pc] {
+			lost = append(lost, pc)
+		}
+	}
+	oldCovered, newCovered := rg.coveredPCsPerFunc(oldSet), rg.coveredPCsPerFunc(newSet)
+	diffs := make(map[int]*FuncDiff)
+	var funcs []int
+	for _, pc := range append(append([]uint64{}, gained...), lost...) {
+		idx := rg.findSymbol(pc)
+		if idx == -1 || diffs[idx] != nil {
+			continue
+		}
+		diffs[idx] = &FuncDiff{
+			Name:       rg.symbols[idx].name,
+			Total:      rg.totalPCs(idx),
+			OldCovered: oldCovered[idx],
+			NewCovered: newCovered[idx],
+		}
+		funcs = append(funcs, idx)
+	}
+	files, err := rg.funcFiles(funcs)
+	if err != nil {
+		return nil, err
+	}
+	for idx, diff := range diffs {
+		diff.File = files[idx]
+	}
+	if err := rg.diffLines(diffs, gained, func(d *FuncDiff) *[]string { return &d.Gained }); err != nil {
+		return nil, err
+	}
+	if err := rg.diffLines(diffs, lost, func(d *FuncDiff) *[]string { return &d.Lost }); err != nil {
+		return nil, err
+	}
+	var res []*FuncDiff
+	for _, diff := range diffs {
+		res = append(res, diff)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].File != res[j].File {
+			return res[i].File < res[j].File
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res, nil
+}
+
+// diffLines symbolizes pcs and appends their (innermost) source lines to the corresponding functions.
+func (rg *ReportGenerator) diffLines(diffs map[int]*FuncDiff, pcs []uint64, lines func(*FuncDiff) *[]string) error {
+	if len(pcs) == 0 {
+		return nil
+	}
+	frames, prefix, err := rg.symbolize(pcs)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for i, frame := range frames {
+		if i != 0 && frames[i-1].PC == frame.PC {
+			// Take only the innermost frame for each PC.
+			continue
+		}
+		diff := diffs[rg.findSymbol(frame.PC)]
+		if diff == nil {
+			continue
+		}
+		file, _ := rg.fileNames(prefix, frame.File)
+		line := fmt.Sprintf("%v:%v", file, frame.Line)
+		key := diff.Name + " " + line
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		list := lines(diff)
+		*list = append(*list, line)
+	}
+	for _, diff := range diffs {
+		sort.Strings(*lines(diff))
+	}
+	return nil
+}
+
+// coverPoints converts raw coverage PCs (return addresses) to a set of coverage point PCs.
+func (rg *ReportGenerator) coverPoints(pcs []uint64) map[uint64]bool {
+	res := make(map[uint64]bool, len(pcs))
+	for _, pc := range pcs {
+		res[PreviousInstructionPC(rg.arch, pc)] = true
+	}
+	return res
+}
+
+// coveredPCsPerFunc returns number of covered PCs per function (index in rg.symbols).
+func (rg *ReportGenerator) coveredPCsPerFunc(pcs map[uint64]bool) map[int]int {
+	res := make(map[int]int)
+	for pc := range pcs {
+		if idx := rg.findSymbol(pc); idx != -1 {
+			res[idx]++
+		}
+	}
+	return res
+}
+
+// findSymbol returns index of the function that contains pc in rg.symbols, or -1.
+func (rg *ReportGenerator) findSymbol(pc uint64) int {
+	idx := sort.Search(len(rg.symbols), func(i int) bool {
+		return pc < rg.symbols[i].end
+	})
+	if idx == len(rg.symbols) || pc < rg.symbols[idx].start {
+		return -1
+	}
+	return idx
+}
+
+// totalPCs returns number of coverage points in the function.
+func (rg *ReportGenerator) totalPCs(idx int) int {
+	s := rg.symbols[idx]
+	start := sort.Search(len(rg.coverPCs), func(i int) bool {
+		return s.start <= rg.coverPCs[i]
+	})
+	end := sort.Search(len(rg.coverPCs), func(i int) bool {
+		return s.end <= rg.coverPCs[i]
+	})
+	return end - start
+}
+
+// funcFiles returns source files (relative to the common prefix) of the functions.
+func (rg *ReportGenerator) funcFiles(funcs []int) (map[int]string, error) {
+	if len(funcs) == 0 {
+		return nil, nil
+	}
+	pcs := make([]uint64, len(funcs))
+	for i, idx := range funcs {
+		pcs[i] = rg.symbols[idx].start
+	}
+	frames, prefix, err := rg.symbolize(pcs)
+	if err != nil {
+		return nil, err
+	}
+	// The last frame for a PC is the non-inlined function itself.
+	// Note: symbolize decrements frame PCs, so the function start is at PC+1.
+	files := make(map[uint64]string)
+	for _, frame := range frames {
+		files[frame.PC+1] = frame.File
+	}
+	res := make(map[int]string)
+	for _, idx := range funcs {
+		if file := files[rg.symbols[idx].start]; file != "" {
+			res[idx], _ = rg.fileNames(prefix, file)
+		}
+	}
+	return res, nil
+}
+
+// WriteFuncStats writes function coverage table as text.
+func WriteFuncStats(w io.Writer, funcs []*FuncStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "FUNCTION\tFILE\tCOVERED\tTOTAL\tPERCENT\n")
+	for _, fn := range funcs {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v%%\n", fn.Name, fn.File, fn.Covered, fn.Total, fn.Percent())
+	}
+	return tw.Flush()
+}
+
+// WriteDiff writes coverage diff as text: one line per function with old/new coverage,
+// followed by newly covered (+) and lost (-) source lines.
+func WriteDiff(w io.Writer, diffs []*FuncDiff) error {
+	bw := bufio.NewWriter(w)
+	for _, diff := range diffs {
+		fmt.Fprintf(bw, "%v %v: %v/%v -> %v/%v\n", diff.Name, diff.File,
+			diff.OldCovered, diff.Total, diff.NewCovered, diff.Total)
+		for _, line := range diff.Gained {
+			fmt.Fprintf(bw, "\t+ %v\n", line)
+		}
+		for _, line := range diff.Lost {
+			fmt.Fprintf(bw, "\t- %v\n", line)
+		}
+	}
+	return bw.Flush()
+}
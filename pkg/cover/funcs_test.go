@@ -0,0 +1,83 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFuncPCs(t *testing.T) {
+	rg := &ReportGenerator{
+		arch: "amd64",
+		symbols: []symbol{
+			{start: 0x100, end: 0x200, name: "foo"},
+			{start: 0x200, end: 0x300, name: "bar"},
+			{start: 0x400, end: 0x500, name: "baz"},
+		},
+		// 0x200 is the end of foo and the start of bar, it belongs only to bar.
+		coverPCs: []uint64{0x110, 0x120, 0x130, 0x200, 0x210, 0x410, 0x420},
+	}
+	// Raw coverage contains return addresses, i.e. coverage point + call instruction size.
+	pcs := []uint64{0x110 + 5, 0x130 + 5, 0x130 + 5, 0x410 + 5, 0x350 + 5}
+	covered := rg.coveredPCsPerFunc(rg.coverPoints(pcs))
+	want := map[int]int{0: 2, 2: 1}
+	if len(covered) != len(want) {
+		t.Fatalf("covered %v, want %v", covered, want)
+	}
+	for idx, n := range want {
+		if covered[idx] != n {
+			t.Fatalf("covered %v, want %v", covered, want)
+		}
+	}
+	for idx, total := range []int{3, 2, 2} {
+		if got := rg.totalPCs(idx); got != total {
+			t.Errorf("func %v: total %v, want %v", rg.symbols[idx].name, got, total)
+		}
+	}
+	if idx := rg.findSymbol(0x350); idx != -1 {
+		t.Errorf("found symbol %v for pc outside of functions", idx)
+	}
+	if idx := rg.findSymbol(0x200); idx != 1 {
+		t.Errorf("found symbol %v for pc at the start of bar", idx)
+	}
+	if idx := rg.findSymbol(0x500); idx != -1 {
+		t.Errorf("found symbol %v for pc at the end of baz", idx)
+	}
+}
+
+func TestWriteFuncStats(t *testing.T) {
+	buf := new(bytes.Buffer)
+	WriteFuncStats(buf, []*FuncStats{
+		{Name: "foo", File: "a.c", Covered: 2, Total: 3},
+		{Name: "longer_name", File: "b.c", Covered: 1, Total: 1},
+	})
+	want := `FUNCTION    FILE COVERED TOTAL PERCENT
+foo         a.c  2       3     66%
+longer_name b.c  1       1     100%
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := WriteDiff(buf, []*FuncDiff{{
+		Name:       "foo",
+		File:       "a.c",
+		Total:      3,
+		OldCovered: 1,
+		NewCovered: 2,
+		Gained:     []string{"a.c:10", "a.c:12"},
+		Lost:       []string{"a.c:11"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "foo a.c: 1/3 -> 2/3\n\t+ a.c:10\n\t+ a.c:12\n\t- a.c:11\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
@@ -4,19 +4,18 @@
 package cover
 
 import (
-	"bufio"
 	"bytes"
+	"debug/elf"
+	"encoding/binary"
 	"fmt"
 	"html/template"
 	"io"
 	"io/ioutil"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 
 	"github.com/google/syzkaller/pkg/hash"
-	"github.com/google/syzkaller/pkg/osutil"
 	"github.com/google/syzkaller/pkg/symbolizer"
 )
 
@@ -54,35 +53,42 @@ func MakeReportGenerator(vmlinux, srcDir, arch string) (*ReportGenerator, error)
 	return rg, nil
 }
 
+// Do writes HTML coverage report for pcs to w.
 func (rg *ReportGenerator) Do(w io.Writer, pcs []uint64) error {
+	prefix, covered, uncovered, err := rg.prepare(pcs)
+	if err != nil {
+		return err
+	}
+	return rg.generate(w, prefix, covered, uncovered)
+}
+
+// prepare symbolizes covered pcs and uncovered coverage points in functions that contain pcs.
+func (rg *ReportGenerator) prepare(pcs []uint64) (string, []symbolizer.Frame, []symbolizer.Frame, error) {
 	if len(pcs) == 0 {
-		return fmt.Errorf("no coverage data available")
+		return "", nil, nil, fmt.Errorf("no coverage data available")
 	}
 	for i, pc := range pcs {
 		pcs[i] = PreviousInstructionPC(rg.arch, pc)
 	}
 	covered, _, err := rg.symbolize(pcs)
 	if err != nil {
-		return err
+		return "", nil, nil, err
 	}
 	if len(covered) == 0 {
-		return fmt.Errorf("'%s' does not have debug info (set CONFIG_DEBUG_INFO=y)", rg.vmlinux)
+		return "", nil, nil, fmt.Errorf("'%s' does not have debug info (set CONFIG_DEBUG_INFO=y)", rg.vmlinux)
 	}
 	uncoveredPCs := rg.uncoveredPcsInFuncs(pcs)
 	uncovered, prefix, err := rg.symbolize(uncoveredPCs)
 	if err != nil {
-		return err
+		return "", nil, nil, err
 	}
-	return rg.generate(w, prefix, covered, uncovered)
+	return prefix, covered, uncovered, nil
 }
 
 func (rg *ReportGenerator) generate(w io.Writer, prefix string, covered, uncovered []symbolizer.Frame) error {
 	var d templateData
 	for f, covered := range fileSet(covered, uncovered) {
-		remain := filepath.Clean(strings.TrimPrefix(f, prefix))
-		if rg.srcDir != "" && !strings.HasPrefix(remain, rg.srcDir) {
-			f = filepath.Join(rg.srcDir, remain)
-		}
+		remain, f := rg.fileNames(prefix, f)
 		lines, err := parseFile(f)
 		if err != nil {
 			return err
@@ -107,10 +113,9 @@ func (rg *ReportGenerator) generate(w io.Writer, prefix string, covered, uncover
 				buf.Write([]byte{'\n'})
 			}
 		}
-		f = filepath.Clean(remain)
 		d.Files = append(d.Files, &templateFile{
-			ID:       hash.String([]byte(f)),
-			Name:     f,
+			ID:       hash.String([]byte(remain)),
+			Name:     remain,
 			Body:     template.HTML(buf.String()),
 			Coverage: coverage,
 		})
@@ -119,10 +124,20 @@ func (rg *ReportGenerator) generate(w io.Writer, prefix string, covered, uncover
 	return coverTemplate.Execute(w, d)
 }
 
+// fileNames returns name of the source file f relative to the common prefix
+// and the path to the file on the local machine.
+func (rg *ReportGenerator) fileNames(prefix, f string) (string, string) {
+	remain := filepath.Clean(strings.TrimPrefix(f, prefix))
+	if rg.srcDir != "" && !strings.HasPrefix(remain, rg.srcDir) {
+		f = filepath.Join(rg.srcDir, remain)
+	}
+	return remain, f
+}
+
 func (rg *ReportGenerator) readSymbols() error {
 	symbols, err := symbolizer.ReadSymbols(rg.vmlinux)
 	if err != nil {
-		return fmt.Errorf("failed to run nm on %v: %v", rg.vmlinux, err)
+		return fmt.Errorf("failed to read symbols from %v: %v", rg.vmlinux, err)
 	}
 	for name, ss := range symbols {
 		for _, s := range ss {
@@ -141,38 +156,31 @@ func (rg *ReportGenerator) readSymbols() error {
 
 // readPCs collects list of PCs of __sanitizer_cov_trace_pc calls in the kernel.
 func (rg *ReportGenerator) readPCs() error {
-	cmd := osutil.Command("objdump", "-d", "--no-show-raw-insn", rg.vmlinux)
-	stdout, err := cmd.StdoutPipe()
+	f, err := elf.Open(rg.vmlinux)
 	if err != nil {
 		return err
 	}
-	defer stdout.Close()
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to run objdump on %v: %v", rg.vmlinux, err)
-	}
-	defer cmd.Wait()
-	s := bufio.NewScanner(stdout)
-	callInsnS, traceFuncS := archCallInsn(rg.arch)
-	callInsn, traceFunc := []byte(callInsnS), []byte(traceFuncS)
-	for s.Scan() {
-		ln := s.Bytes()
-		if pos := bytes.Index(ln, callInsn); pos == -1 {
-			continue
-		} else if !bytes.Contains(ln[pos:], traceFunc) {
-			continue
+	defer f.Close()
+	var traceStart, traceEnd uint64
+	for _, s := range rg.symbols {
+		if s.name == "__sanitizer_cov_trace_pc" || s.name == ".__sanitizer_cov_trace_pc" {
+			traceStart, traceEnd = s.start, s.end
+			break
 		}
-		colon := bytes.IndexByte(ln, ':')
-		if colon == -1 {
+	}
+	if traceStart == 0 {
+		// The kernel is not built with coverage, there are no coverage points.
+		return nil
+	}
+	for _, sec := range f.Sections {
+		if sec.Type != elf.SHT_PROGBITS || sec.Flags&elf.SHF_EXECINSTR == 0 {
 			continue
 		}
-		pc, err := strconv.ParseUint(string(ln[:colon]), 16, 64)
+		data, err := sec.Data()
 		if err != nil {
-			continue
+			return fmt.Errorf("failed to read section %v in %v: %v", sec.Name, rg.vmlinux, err)
 		}
-		rg.coverPCs = append(rg.coverPCs, pc)
-	}
-	if err := s.Err(); err != nil {
-		return fmt.Errorf("failed to run objdump output: %v", err)
+		rg.coverPCs = append(rg.coverPCs, findCalls(rg.arch, f.ByteOrder, data, sec.Addr, traceStart, traceEnd)...)
 	}
 	sort.Slice(rg.coverPCs, func(i, j int) bool {
 		return rg.coverPCs[i] < rg.coverPCs[j]
@@ -180,6 +188,82 @@ func (rg *ReportGenerator) readPCs() error {
 	return nil
 }
 
+// findCalls returns PCs of call instructions in code located at addr that call a function in [start, end).
+// The code is not disassembled, instead we look for encodings of direct call instructions at all
+// possible instruction offsets. A false positive requires a random byte sequence to match the exact
+// call target, which is very unlikely.
+func findCalls(arch string, order binary.ByteOrder, code []byte, addr, start, end uint64) []uint64 {
+	var pcs []uint64
+	match := func(pc, target uint64) {
+		if target >= start && target < end {
+			pcs = append(pcs, pc)
+		}
+	}
+	switch arch {
+	case "amd64", "386":
+		// call rel32: e8 xx xx xx xx
+		for i := 0; i+5 <= len(code); i++ {
+			if code[i] != 0xe8 {
+				continue
+			}
+			pc := addr + uint64(i)
+			off := int32(order.Uint32(code[i+1:]))
+			match(pc, pc+5+uint64(int64(off)))
+		}
+	case "arm64":
+		// bl imm26
+		for i := 0; i+4 <= len(code); i += 4 {
+			insn := order.Uint32(code[i:])
+			if insn&0xfc000000 != 0x94000000 {
+				continue
+			}
+			pc := addr + uint64(i)
+			off := int64(int32(insn<<6)>>6) * 4
+			match(pc, pc+uint64(off))
+		}
+	case "arm":
+		// ARM mode bl imm24 (always executed).
+		for i := 0; i+4 <= len(code); i += 4 {
+			insn := order.Uint32(code[i:])
+			if insn&0xff000000 != 0xeb000000 {
+				continue
+			}
+			pc := addr + uint64(i)
+			off := int64(int32(insn<<8)>>8) * 4
+			match(pc, uint64(uint32(pc+8+uint64(off))))
+		}
+		// Thumb-2 bl: 11110 S imm10, 11 J1 1 J2 imm11.
+		for i := 0; i+4 <= len(code); i += 2 {
+			hi, lo := uint32(order.Uint16(code[i:])), uint32(order.Uint16(code[i+2:]))
+			if hi&0xf800 != 0xf000 || lo&0xd000 != 0xd000 {
+				continue
+			}
+			s := (hi >> 10) & 1
+			i1 := ^((lo >> 13) ^ s) & 1
+			i2 := ^((lo >> 11) ^ s) & 1
+			imm := s<<24 | i1<<23 | i2<<22 | (hi&0x3ff)<<12 | (lo&0x7ff)<<1
+			pc := addr + uint64(i)
+			off := int64(int32(imm<<7) >> 7)
+			// Thumb functions have the low bit set in the symbol table.
+			match(pc, uint64(uint32(pc+4+uint64(off)))|1)
+		}
+	case "ppc64le":
+		// bl: opcode 18 with AA=0 and LK=1.
+		for i := 0; i+4 <= len(code); i += 4 {
+			insn := order.Uint32(code[i:])
+			if insn&0xfc000003 != 0x48000001 {
+				continue
+			}
+			pc := addr + uint64(i)
+			off := int64(int32(insn<<6)>>6) &^ 3
+			match(pc, pc+uint64(off))
+		}
+	default:
+		panic("unknown arch")
+	}
+	return pcs
+}
+
 // uncoveredPcsInFuncs returns uncovered PCs with __sanitizer_cov_trace_pc calls in functions containing pcs.
 func (rg *ReportGenerator) uncoveredPcsInFuncs(pcs []uint64) []uint64 {
 	handledFuncs := make(map[uint64]bool)
@@ -225,10 +309,16 @@ func (rg *ReportGenerator) symbolize(pcs []uint64) ([]symbolizer.Frame, string,
 		return nil, "", err
 	}
 
-	prefix := ""
 	for i := range frames {
-		frame := &frames[i]
-		frame.PC--
+		frames[i].PC--
+	}
+	return frames, filePrefix(frames), nil
+}
+
+// filePrefix returns the longest common prefix of file names in frames.
+func filePrefix(frames []symbolizer.Frame) string {
+	prefix := ""
+	for _, frame := range frames {
 		if prefix == "" {
 			prefix = frame.File
 		} else {
@@ -240,9 +330,8 @@ func (rg *ReportGenerator) symbolize(pcs []uint64) ([]symbolizer.Frame, string,
 			}
 			prefix = prefix[:i]
 		}
-
 	}
-	return frames, prefix, nil
+	return prefix
 }
 
 func parseFile(fn string) ([][]byte, error) {
@@ -320,29 +409,6 @@ func PreviousInstructionPC(arch string, pc uint64) uint64 {
 	}
 }
 
-func archCallInsn(arch string) (string, string) {
-	const callName = " <__sanitizer_cov_trace_pc>"
-	switch arch {
-	case "amd64":
-		// ffffffff8100206a:       callq  ffffffff815cc1d0 <__sanitizer_cov_trace_pc>
-		return "\tcallq ", callName
-	case "386":
-		// c1000102:       call   c10001f0 <__sanitizer_cov_trace_pc>
-		return "\tcall ", callName
-	case "arm64":
-		// ffff0000080d9cc0:       bl      ffff00000820f478 <__sanitizer_cov_trace_pc>
-		return "\tbl\t", callName
-	case "arm":
-		// 8010252c:       bl      801c3280 <__sanitizer_cov_trace_pc>
-		return "\tbl\t", callName
-	case "ppc64le":
-		// c00000000006d904:       bl      c000000000350780 <.__sanitizer_cov_trace_pc>
-		return "\tbl ", " <.__sanitizer_cov_trace_pc>"
-	default:
-		panic("unknown arch")
-	}
-}
-
 type templateData struct {
 	Files []*templateFile
 }
@@ -0,0 +1,183 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+func TestFindCalls(t *testing.T) {
+	tests := []struct {
+		arch  string
+		code  []byte
+		addr  uint64
+		start uint64
+		end   uint64
+		want  []uint64
+	}{
+		{
+			arch: "amd64",
+			// call 0x1100; nop; call 0x1200; call 0x1000 (call to self).
+			code:  []byte{0xe8, 0xfb, 0x00, 0x00, 0x00, 0x90, 0xe8, 0xf5, 0x01, 0x00, 0x00, 0xe8, 0xf0, 0xff, 0xff, 0xff},
+			addr:  0x1000,
+			start: 0x1100,
+			end:   0x1110,
+			want:  []uint64{0x1000},
+		},
+		{
+			arch: "amd64",
+			// Call backwards.
+			code:  []byte{0x90, 0xe8, 0xfa, 0xfe, 0xff, 0xff},
+			addr:  0x1000,
+			start: 0xf00,
+			end:   0xf10,
+			want:  []uint64{0x1001},
+		},
+		{
+			arch: "arm64",
+			// bl +0x100; bl -4; nop.
+			code:  []byte{0x40, 0x00, 0x00, 0x94, 0xff, 0xff, 0xff, 0x97, 0x1f, 0x20, 0x03, 0xd5},
+			addr:  0x1000,
+			start: 0x1100,
+			end:   0x1110,
+			want:  []uint64{0x1000},
+		},
+		{
+			arch:  "arm64",
+			code:  []byte{0x40, 0x00, 0x00, 0x94, 0xff, 0xff, 0xff, 0x97},
+			addr:  0x1000,
+			start: 0x1000,
+			end:   0x1004,
+			want:  []uint64{0x1004},
+		},
+		{
+			arch: "arm",
+			// ARM mode bl +0x1000 (relative to pc+8).
+			code:  []byte{0xfe, 0x03, 0x00, 0xeb},
+			addr:  0x1000,
+			start: 0x2000,
+			end:   0x2010,
+			want:  []uint64{0x1000},
+		},
+		{
+			arch: "arm",
+			// Thumb bl +0x1000 (relative to pc+4), Thumb function address has the low bit set.
+			code:  []byte{0x00, 0xbf, 0x01, 0xf0, 0x00, 0xf8},
+			addr:  0x1000,
+			start: 0x2007,
+			end:   0x2017,
+			want:  []uint64{0x1002},
+		},
+		{
+			arch: "ppc64le",
+			// bl +0x40; bl -8.
+			code:  []byte{0x41, 0x00, 0x00, 0x48, 0xf9, 0xff, 0xff, 0x4b},
+			addr:  0x1000,
+			start: 0xffc,
+			end:   0x1044,
+			want:  []uint64{0x1000, 0x1004},
+		},
+	}
+	for i, test := range tests {
+		got := findCalls(test.arch, binary.LittleEndian, test.code, test.addr, test.start, test.end)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("test #%v/%v: got %#v, want %#v", i, test.arch, got, test.want)
+		}
+	}
+}
+
+const kcovProg = `
+void __sanitizer_cov_trace_pc(void) {}
+volatile int x;
+__attribute__((noinline)) int f(int a) { if (a > 3) x = 1; else if (a < 0) x = 2; return a * 2; }
+__attribute__((noinline)) int g(int a) { for (int i = 0; i < a; i++) if (i % 3) x += f(i); return x; }
+int main(int argc, char **argv) { return g(argc) + f(argc); }
+`
+
+// TestReadPCs checks that coverage points match calls found by objdump in a real binary.
+func TestReadPCs(t *testing.T) {
+	for _, tool := range []string{"gcc", "objdump"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%v is not available", tool)
+		}
+	}
+	dir, err := ioutil.TempDir("", "syz-cover-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src, bin := dir+"/kcov.c", dir+"/kcov"
+	if err := osutil.WriteFile(src, []byte(kcovProg)); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := osutil.RunCmd(time.Minute, "", "gcc", "-O2", "-g", "-fsanitize-coverage=trace-pc",
+		"-o", bin, src); err != nil {
+		t.Skipf("failed to build test program: %v\n%s", err, out)
+	}
+	rg, err := MakeReportGenerator(bin, "", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := osutil.RunCmd(time.Minute, "", "objdump", "-d", "--no-show-raw-insn", bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []uint64
+	for s := bufio.NewScanner(bytes.NewReader(out)); s.Scan(); {
+		ln := s.Text()
+		if !strings.Contains(ln, "call") || !strings.HasSuffix(ln, " <__sanitizer_cov_trace_pc>") {
+			continue
+		}
+		pc, err := strconv.ParseUint(strings.TrimSpace(ln[:strings.IndexByte(ln, ':')]), 16, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, pc)
+	}
+	if len(want) == 0 {
+		t.Fatalf("no coverage points found by objdump")
+	}
+	if !reflect.DeepEqual(rg.coverPCs, want) {
+		t.Fatalf("got coverage points %#v\nwant %#v", rg.coverPCs, want)
+	}
+	// Summary must include functions without coverage.
+	var mainPC uint64
+	for _, s := range rg.symbols {
+		for _, pc := range want {
+			if s.name == "main" && pc >= s.start && pc < s.end {
+				mainPC = pc
+			}
+		}
+	}
+	if mainPC == 0 {
+		t.Fatalf("no coverage points in main")
+	}
+	files, err := rg.Summary([]uint64{mainPC + 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs := make(map[string]int)
+	for _, file := range files {
+		for _, fn := range file.Functions {
+			funcs[fn.Name] += fn.CoveredLines
+		}
+	}
+	for _, fn := range []string{"f", "g"} {
+		if covered, ok := funcs[fn]; !ok || covered != 0 {
+			t.Errorf("function %v: present %v, covered lines %v", fn, ok, covered)
+		}
+	}
+}
@@ -4,6 +4,9 @@
 package host
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/google/syzkaller/pkg/log"
 	"github.com/google/syzkaller/prog"
 )
@@ -42,6 +45,34 @@ func DetectSupportedSyscalls(target *prog.Target, sandbox string) (
 	return supported, unsupported, nil
 }
 
+// CheckSyscalls splits enabled syscalls into supported and disabled ones
+// given syscalls unsupported by the host (as returned by DetectSupportedSyscalls).
+// For disabled syscalls it returns reasons, for transitively disabled syscalls
+// the reason contains the whole dependency chain (see prog.Target.ExplainDisabledCalls).
+func CheckSyscalls(target *prog.Target, enabled map[*prog.Syscall]bool, unsupported map[*prog.Syscall]string) (
+	map[*prog.Syscall]bool, map[*prog.Syscall]string) {
+	calls := make(map[*prog.Syscall]bool)
+	disabled := make(map[*prog.Syscall]string)
+	reasons := make(map[*prog.Syscall]string)
+	for _, c := range target.Syscalls {
+		if reason, ok := unsupported[c]; ok {
+			reasons[c] = reason
+			if enabled[c] {
+				disabled[c] = reason
+			}
+		} else if enabled[c] {
+			calls[c] = true
+		} else {
+			reasons[c] = "not enabled"
+		}
+	}
+	supported, _ := target.TransitivelyEnabledCalls(calls)
+	for c, reason := range target.ExplainDisabledCalls(calls, supported, reasons) {
+		disabled[c] = reason
+	}
+	return supported, disabled
+}
+
 var testFallback = false
 
 const (
@@ -65,6 +96,62 @@ type Feature struct {
 
 type Features [numFeatures]Feature
 
+// FeatureIDs are short feature names used in configs (see Features.Override).
+var FeatureIDs = [numFeatures]string{
+	FeatureCoverage:                   "coverage",
+	FeatureComparisons:                "comparisons",
+	FeatureSandboxSetuid:              "sandbox_setuid",
+	FeatureSandboxNamespace:           "sandbox_namespace",
+	FeatureSandboxAndroidUntrustedApp: "sandbox_android_untrusted_app",
+	FeatureFaultInjection:             "fault_injection",
+	FeatureLeakChecking:               "leak_checking",
+	FeatureNetworkInjection:           "net_injection",
+	FeatureNetworkDevices:             "net_devices",
+}
+
+// ValidateOverrides checks that overrides refer only to known features.
+func ValidateOverrides(overrides map[string]bool) error {
+	for id := range overrides {
+		if featureByID(id) == -1 {
+			return fmt.Errorf("unknown feature %q, known features: %v",
+				id, strings.Join(FeatureIDs[:], ", "))
+		}
+	}
+	return nil
+}
+
+// Override applies user-requested feature overrides (keyed by FeatureIDs) to the detected features:
+// false forcibly disables the feature, true requires the feature to be supported
+// (an error is returned otherwise). Features not mentioned in overrides are left intact.
+func (features *Features) Override(overrides map[string]bool) error {
+	if err := ValidateOverrides(overrides); err != nil {
+		return err
+	}
+	for id, enable := range overrides {
+		feat := &features[featureByID(id)]
+		if !enable {
+			if feat.Enabled {
+				feat.Enabled = false
+				feat.Reason = "disabled by config"
+			}
+			continue
+		}
+		if !feat.Enabled {
+			return fmt.Errorf("%v is required by config, but is not supported (%v)", feat.Name, feat.Reason)
+		}
+	}
+	return nil
+}
+
+func featureByID(id string) int {
+	for i, fid := range FeatureIDs {
+		if fid == id {
+			return i
+		}
+	}
+	return -1
+}
+
 var checkFeature [numFeatures]func() string
 var setupFeature [numFeatures]func() error
 var callbFeature [numFeatures]func(leakFrames [][]byte)
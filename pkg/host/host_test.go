@@ -60,3 +60,57 @@ func TestCheck(t *testing.T) {
 		t.Logf("%-24v: %v", feat.Name, feat.Reason)
 	}
 }
+
+func TestFeatureOverride(t *testing.T) {
+	features := &Features{
+		FeatureCoverage:     {Name: "code coverage", Enabled: true, Reason: "enabled"},
+		FeatureLeakChecking: {Name: "leak checking", Enabled: true, Reason: "enabled"},
+		FeatureComparisons:  {Name: "comparison tracing", Reason: "unsupported"},
+	}
+	if err := features.Override(map[string]bool{"leak_checking": false, "coverage": true}); err != nil {
+		t.Fatal(err)
+	}
+	if !features[FeatureCoverage].Enabled {
+		t.Errorf("coverage is disabled")
+	}
+	if feat := features[FeatureLeakChecking]; feat.Enabled || feat.Reason != "disabled by config" {
+		t.Errorf("leak checking is not disabled: %+v", feat)
+	}
+	if err := features.Override(map[string]bool{"comparisons": true}); err == nil {
+		t.Errorf("required unsupported feature did not fail")
+	}
+	if err := features.Override(map[string]bool{"foo": false}); err == nil {
+		t.Errorf("unknown feature did not fail")
+	}
+}
+
+func TestCheckSyscalls(t *testing.T) {
+	target, err := prog.GetTarget("test", "64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled := make(map[*prog.Syscall]bool)
+	for _, c := range target.Syscalls {
+		enabled[c] = true
+	}
+	unsupported := map[*prog.Syscall]string{
+		target.SyscallMap["test$res2"]: "ENOENT",
+	}
+	delete(enabled, target.SyscallMap["mutate5"])
+	supported, disabled := CheckSyscalls(target, enabled, unsupported)
+	if supported[target.SyscallMap["test$res2"]] || disabled[target.SyscallMap["test$res2"]] != "ENOENT" {
+		t.Errorf("test$res2 is not disabled")
+	}
+	if _, ok := disabled[target.SyscallMap["mutate5"]]; ok {
+		t.Errorf("not enabled mutate5 is reported as disabled")
+	}
+	if reason := disabled[target.SyscallMap["fallback$1"]]; reason != "" {
+		t.Errorf("fallback$1 is disabled: %v", reason)
+	}
+	delete(enabled, target.SyscallMap["fallback$0"])
+	_, disabled = CheckSyscalls(target, enabled, unsupported)
+	want := "needs fd, which can only be created by fallback$0 (not enabled), mutate5 (not enabled), test$res2 (ENOENT)"
+	if reason := disabled[target.SyscallMap["fallback$1"]]; reason != want {
+		t.Errorf("wrong reason for fallback$1:\n%v\nwant:\n%v", reason, want)
+	}
+}
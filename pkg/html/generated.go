@@ -200,6 +200,7 @@ function textSort(v) { return v.toLowerCase(); }
 function numSort(v) { return -parseInt(v); }
 function floatSort(v) { return -parseFloat(v); }
 function reproSort(v) { return v == "C" ? 0 : v == "syz" ? 1 : 2; }
+function severitySort(v) { return v == "critical" ? 0 : v == "severe" ? 1 : v == "high" ? 2 : v == "medium" ? 3 : v == "low" ? 4 : 5; }
 function patchedSort(v) { return v == "" ? -1 : parseInt(v); }
 
 function timeSort(v) {
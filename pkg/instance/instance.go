@@ -76,17 +76,68 @@ func (env *Env) BuildSyzkaller(repo, commit string) error {
 	return nil
 }
 
-func (env *Env) BuildKernel(compilerBin, userspaceDir, cmdlineFile, sysctlFile string, kernelConfig []byte) error {
+func (env *Env) BuildKernel(compilerBin, userspaceDir, cmdlineFile, sysctlFile string, kernelConfig []byte,
+	buildCmd *build.CommandConfig) error {
 	cfg := env.cfg
 	imageDir := filepath.Join(cfg.Workdir, "image")
 	if err := build.Image(cfg.TargetOS, cfg.TargetVMArch, cfg.Type,
 		cfg.KernelSrc, imageDir, compilerBin, userspaceDir,
-		cmdlineFile, sysctlFile, kernelConfig); err != nil {
+		cmdlineFile, sysctlFile, kernelConfig, buildCmd); err != nil {
 		return err
 	}
 	return SetConfigImage(cfg, imageDir)
 }
 
+// BuildKernelCached is like BuildKernel, but reuses a previously built image from cache
+// if the kernel was already built with the same parameters, and stores the image in cache otherwise.
+// commit and patch must describe the kernel sources. On cache miss the kernel dir is cleaned before the build.
+// Returns true if the image was taken from cache. If cache is nil, it's equivalent to BuildKernel.
+func (env *Env) BuildKernelCached(cache *build.Cache, commit string, patch []byte, compilerBin, userspaceDir,
+	cmdlineFile, sysctlFile string, kernelConfig []byte, buildCmd *build.CommandConfig) (bool, error) {
+	if cache == nil {
+		return false, env.BuildKernel(compilerBin, userspaceDir, cmdlineFile, sysctlFile, kernelConfig, buildCmd)
+	}
+	cfg := env.cfg
+	compilerID, err := build.CompilerIdentity(compilerBin)
+	if err != nil {
+		return false, err
+	}
+	key := &build.CacheKey{
+		TargetOS:   cfg.TargetOS,
+		TargetArch: cfg.TargetVMArch,
+		VMType:     cfg.Type,
+		Commit:     commit,
+		Patch:      patch,
+		CompilerID: compilerID,
+		Config:     kernelConfig,
+		Userspace:  userspaceDir,
+		Cmdline:    cmdlineFile,
+		Sysctl:     sysctlFile,
+		Command:    buildCmd,
+	}
+	imageDir := filepath.Join(cfg.Workdir, "image")
+	if err := os.RemoveAll(imageDir); err != nil {
+		return false, err
+	}
+	ok, err := cache.Get(key, imageDir)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return true, SetConfigImage(cfg, imageDir)
+	}
+	if err := build.Clean(cfg.TargetOS, cfg.TargetVMArch, cfg.Type, cfg.KernelSrc, buildCmd); err != nil {
+		return false, fmt.Errorf("kernel clean failed: %v", err)
+	}
+	if err := env.BuildKernel(compilerBin, userspaceDir, cmdlineFile, sysctlFile, kernelConfig, buildCmd); err != nil {
+		return false, err
+	}
+	if err := cache.Put(key, imageDir); err != nil {
+		log.Logf(0, "failed to store kernel build in cache: %v", err)
+	}
+	return false, nil
+}
+
 func SetConfigImage(cfg *mgrconfig.Config, imageDir string) error {
 	cfg.KernelObj = filepath.Join(imageDir, "obj")
 	cfg.Image = filepath.Join(imageDir, "image")
@@ -320,18 +371,21 @@ func (inst *inst) testRepro() error {
 	if !opts.Fault {
 		opts.FaultCall = -1
 	}
+	target, err := prog.GetTarget(cfg.TargetOS, cfg.TargetArch)
+	if err != nil {
+		return err
+	}
+	// Multi-program reproducers are serialized as a log with several programs
+	// that need to be executed concurrently.
+	parallel := len(target.ParseLog(inst.reproSyz)) > 1
 	cmdSyz := ExecprogCmd(execprogBin, executorBin, cfg.TargetOS, cfg.TargetArch, opts.Sandbox,
-		true, true, true, cfg.Procs, opts.FaultCall, opts.FaultNth, vmProgFile)
+		true, true, true, parallel, cfg.Procs, opts.FaultCall, opts.FaultNth, vmProgFile)
 	if err := inst.testProgram(cmdSyz, 7*time.Minute); err != nil {
 		return err
 	}
 	if len(inst.reproC) == 0 {
 		return nil
 	}
-	target, err := prog.GetTarget(cfg.TargetOS, cfg.TargetArch)
-	if err != nil {
-		return err
-	}
 	bin, err := csource.Build(target, inst.reproC)
 	if err != nil {
 		return err
@@ -380,7 +434,9 @@ func FuzzerCmd(fuzzer, executor, name, OS, arch, fwdAddr, sandbox string, procs,
 		procs, verbosity, cover, debug, test, runtestArg)
 }
 
-func ExecprogCmd(execprog, executor, OS, arch, sandbox string, repeat, threaded, collide bool,
+// ExecprogCmd returns command line for syz-execprog. If parallel is set, each program
+// in progFile is executed in a separate proc concurrently with the others (procs is ignored).
+func ExecprogCmd(execprog, executor, OS, arch, sandbox string, repeat, threaded, collide, parallel bool,
 	procs, faultCall, faultNth int, progFile string) string {
 	repeatCount := 1
 	if repeat {
@@ -390,12 +446,17 @@ func ExecprogCmd(execprog, executor, OS, arch, sandbox string, repeat, threaded,
 	if OS == "akaros" {
 		osArg = " -os=" + OS
 	}
+	parallelArg := ""
+	if parallel {
+		// Don't pass the flag unless needed because old execprog does not have it.
+		parallelArg = " -parallel"
+	}
 	return fmt.Sprintf("%v -executor=%v -arch=%v%v -sandbox=%v"+
 		" -procs=%v -repeat=%v -threaded=%v -collide=%v -cover=0"+
-		" -fault_call=%v -fault_nth=%v %v",
+		" -fault_call=%v -fault_nth=%v%v %v",
 		execprog, executor, arch, osArg, sandbox,
 		procs, repeatCount, threaded, collide,
-		faultCall, faultNth, progFile)
+		faultCall, faultNth, parallelArg, progFile)
 }
 
 var MakeBin = func() string {
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,6 +17,7 @@ import (
 	"unsafe"
 
 	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/pkg/signal"
 	"github.com/google/syzkaller/prog"
 )
 
@@ -32,11 +34,15 @@ const (
 	FlagEnableTun                                       // initialize and use tun in executor
 	FlagEnableNetDev                                    // setup a bunch of various network devices for testing
 	FlagEnableFault                                     // enable fault injection support
+	FlagRemote                                          // executor runs on another machine, shmem is emulated over pipes
 	// Executor does not know about these:
 	FlagUseShmem      // use shared memory instead of pipes for communication
 	FlagUseForkServer // use extended protocol with handshake
 )
 
+// Config.SignalMode is passed to executor in the upper half of env flags.
+const signalModeShift = 32
+
 // Per-exec flags for ExecOpts.Flags:
 type ExecFlags uint64
 
@@ -71,8 +77,18 @@ type Config struct {
 	// Flags are configuation flags, defined above.
 	Flags EnvFlags
 
+	// SignalMode is granularity of feedback signal (used if FlagSignal is set).
+	SignalMode signal.Mode
+
 	// Timeout is the execution timeout for a single program.
 	Timeout time.Duration
+
+	// ExecutorConn is a unix socket connected to control pipes of a remote executor
+	// (e.g. qemu virtio-serial port), used only with FlagRemote.
+	// If set, Executor is a command that starts executor on the remote machine
+	// with stdin/stdout connected to the other end of the socket and exits.
+	// Unlike ssh, such connection survives VM snapshot restore, which is required by Env.EnableSnapshots.
+	ExecutorConn string
 }
 
 type CallFlags uint32
@@ -91,6 +107,12 @@ type CallInfo struct {
 	// if dedup == false, then cov effectively contains a trace, otherwise duplicates are removed
 	Comps prog.CompMap // per-call comparison operands
 	Errno int          // call errno (0 if the call was successful)
+	// Time is wall time spent in the call. For unfinished calls it is the time
+	// from the call start till the end of program execution.
+	Time time.Duration
+	// CPUTime is CPU time consumed by the call (0 if not supported by the OS
+	// or if the call has not finished).
+	CPUTime time.Duration
 }
 
 type ProgInfo struct {
@@ -110,8 +132,14 @@ type Env struct {
 	pid       int
 	config    *Config
 
-	StatExecs    uint64
-	StatRestarts uint64
+	snapshotter   Snapshotter
+	snapshotEvery int
+	snapshotExecs int
+
+	StatExecs       uint64
+	StatRestarts    uint64
+	StatRestores    uint64
+	StatRestoreTime uint64 // in nanoseconds
 }
 
 const (
@@ -154,9 +182,13 @@ func FlagsToSandbox(flags EnvFlags) string {
 }
 
 func MakeEnv(config *Config, pid int) (*Env, error) {
+	if config.Flags&FlagRemote != 0 &&
+		config.Flags&(FlagUseShmem|FlagUseForkServer) != FlagUseShmem|FlagUseForkServer {
+		return nil, fmt.Errorf("remote executor requires shmem and fork server")
+	}
 	var inf, outf *os.File
 	var inmem, outmem []byte
-	if config.Flags&FlagUseShmem != 0 {
+	if config.Flags&FlagUseShmem != 0 && config.Flags&FlagRemote == 0 {
 		var err error
 		inf, inmem, err = osutil.CreateMemMappedFile(prog.ExecBufferSize)
 		if err != nil {
@@ -238,6 +270,51 @@ func (env *Env) Close() error {
 	}
 }
 
+// EnableSnapshots makes env return the machine into a clean state with s
+// every n executed programs (before the program that follows them).
+// The snapshot is taken right away after executor is started and has finished handshake and sandbox setup,
+// so after restore the same executor is ready to execute the next program.
+// Executor connection must survive restore, so this requires Config.ExecutorConn.
+// Snapshots also replace executor restarts: after a hang or an executor failure
+// the machine is restored before the next program.
+func (env *Env) EnableSnapshots(s Snapshotter, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("bad snapshot period %v", n)
+	}
+	if env.config.ExecutorConn == "" {
+		return fmt.Errorf("snapshots require executor connection that survives restore")
+	}
+	if env.cmd == nil {
+		atomic.AddUint64(&env.StatRestarts, 1)
+		cmd, err := makeCommand(env.pid, env.bin, env.config, env.inFile, env.outFile, env.out)
+		if err != nil {
+			return err
+		}
+		env.cmd = cmd
+	}
+	if err := s.Snapshot(); err != nil {
+		return err
+	}
+	env.snapshotter = s
+	env.snapshotEvery = n
+	env.snapshotExecs = 0
+	return nil
+}
+
+func (env *Env) restoreSnapshot() error {
+	start := time.Now()
+	if err := env.snapshotter.Restore(); err != nil {
+		return fmt.Errorf("failed to restore snapshot: %v", err)
+	}
+	if err := env.cmd.reset(); err != nil {
+		return err
+	}
+	atomic.AddUint64(&env.StatRestores, 1)
+	atomic.AddUint64(&env.StatRestoreTime, uint64(time.Since(start)))
+	env.snapshotExecs = 0
+	return nil
+}
+
 var rateLimit = time.NewTicker(1 * time.Second)
 
 // Exec starts executor binary to execute program p and returns information about the execution:
@@ -254,7 +331,7 @@ func (env *Env) Exec(opts *ExecOpts, p *prog.Prog) (output []byte, info *ProgInf
 		return
 	}
 	var progData []byte
-	if env.config.Flags&FlagUseShmem == 0 {
+	if env.config.Flags&FlagUseShmem == 0 || env.config.Flags&FlagRemote != 0 {
 		progData = env.in[:progSize]
 	}
 	// Zero out the first two words (ncmd and nsig), so that we don't have garbage there
@@ -263,6 +340,14 @@ func (env *Env) Exec(opts *ExecOpts, p *prog.Prog) (output []byte, info *ProgInf
 		env.out[i] = 0
 	}
 
+	if env.snapshotter != nil {
+		if env.snapshotExecs >= env.snapshotEvery {
+			if err0 = env.restoreSnapshot(); err0 != nil {
+				return
+			}
+		}
+		env.snapshotExecs++
+	}
 	atomic.AddUint64(&env.StatExecs, 1)
 	if env.cmd == nil {
 		if p.Target.OS == "akaros" {
@@ -278,6 +363,14 @@ func (env *Env) Exec(opts *ExecOpts, p *prog.Prog) (output []byte, info *ProgInf
 	}
 	var restart bool
 	output, failed, hanged, restart, err0 = env.cmd.exec(opts, progData)
+	if env.snapshotter != nil && (err0 != nil || hanged || restart) {
+		// Executor in the snapshot is ready to continue, so restore instead of restarting.
+		env.snapshotExecs = env.snapshotEvery
+		if err0 != nil {
+			return
+		}
+		restart = false
+	}
 	if err0 != nil {
 		env.cmd.close()
 		env.cmd = nil
@@ -343,6 +436,8 @@ func (env *Env) parseOutput(p *prog.Prog) (*ProgInfo, error) {
 		}
 		inf.Errno = int(reply.errno)
 		inf.Flags = CallFlags(reply.flags)
+		inf.Time = time.Duration(reply.wallTime) * time.Microsecond
+		inf.CPUTime = time.Duration(reply.cpuTime) * time.Microsecond
 		if inf.Signal, ok = readUint32Array(&out, reply.signalSize); !ok {
 			return nil, fmt.Errorf("call %v/%v/%v: signal overflow: %v/%v",
 				i, reply.index, reply.num, reply.signalSize, len(out))
@@ -442,9 +537,13 @@ type command struct {
 	dir      string
 	readDone chan []byte
 	exited   chan struct{}
-	inrp     *os.File
-	outwp    *os.File
+	inrp     io.ReadCloser
+	outwp    io.WriteCloser
 	outmem   []byte
+	// conn is set if we talk to executor over Config.ExecutorConn, cmd is nil in this case.
+	conn net.Conn
+	// stale is set if remote executor could have sent something we did not read.
+	stale bool
 }
 
 const (
@@ -486,6 +585,8 @@ type callReply struct {
 	num        uint32 // syscall number (for cross-checking)
 	errno      uint32
 	flags      uint32 // see CallFlags
+	wallTime   uint32 // in microseconds
+	cpuTime    uint32 // in microseconds
 	signalSize uint32
 	coverSize  uint32
 	compsSize  uint32
@@ -519,6 +620,15 @@ func makeCommand(pid int, bin []string, config *Config, inFile, outFile *os.File
 		}
 	}
 
+	if config.ExecutorConn != "" {
+		if err := c.startRemote(bin); err != nil {
+			return nil, err
+		}
+		tmp := c
+		c = nil // disable defer above
+		return tmp, nil
+	}
+
 	// Output capture pipe.
 	rp, wp, err := os.Pipe()
 	if err != nil {
@@ -604,6 +714,61 @@ func makeCommand(pid int, bin []string, config *Config, inFile, outFile *os.File
 	return tmp, nil
 }
 
+// startRemote connects to executor control pipes over Config.ExecutorConn
+// and starts executor with bin. Executor output is not captured in this mode
+// (it's expected to go to the console of the remote machine).
+func (c *command) startRemote(bin []string) error {
+	conn, err := net.DialTimeout("unix", c.config.ExecutorConn, time.Minute)
+	if err != nil {
+		return fmt.Errorf("failed to connect to executor: %v", err)
+	}
+	c.conn = conn
+	c.inrp = conn
+	c.outwp = conn
+	c.readDone = make(chan []byte)
+	close(c.readDone)
+	c.exited = make(chan struct{})
+	if _, err := osutil.RunCmd(time.Minute, c.dir, bin[0], bin[1:]...); err != nil {
+		return fmt.Errorf("failed to start executor: %v", err)
+	}
+	if c.config.Flags&FlagUseForkServer != 0 {
+		if err := c.handshake(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reset prepares connection to remote executor for use after the remote machine
+// was restored from a snapshot: it discards anything executor sent before the restore.
+func (c *command) reset() error {
+	if c.stale {
+		// Anything executor managed to send before the restore is already in the socket buffer.
+		c.conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+		buf := make([]byte, 4<<10)
+		for {
+			if _, err := c.conn.Read(buf); err != nil {
+				if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+					return fmt.Errorf("executor %v: connection failed: %v", c.pid, err)
+				}
+				break
+			}
+		}
+		c.stale = false
+	}
+	return c.conn.SetDeadline(time.Time{})
+}
+
+// kill kills executor process, or, for remote executor, aborts all pending communication with it.
+func (c *command) kill() {
+	if c.conn != nil {
+		c.stale = true
+		c.conn.SetDeadline(time.Now())
+		return
+	}
+	c.cmd.Process.Kill()
+}
+
 func (c *command) close() {
 	if c.cmd != nil {
 		c.cmd.Process.Kill()
@@ -618,11 +783,16 @@ func (c *command) close() {
 	}
 }
 
+// envFlags returns env flags as executor expects them.
+func (c *command) envFlags() uint64 {
+	return uint64(c.config.Flags) | uint64(c.config.SignalMode)<<signalModeShift
+}
+
 // handshake sends handshakeReq and waits for handshakeReply.
 func (c *command) handshake() error {
 	req := &handshakeReq{
 		magic: inMagic,
-		flags: uint64(c.config.Flags),
+		flags: c.envFlags(),
 		pid:   uint64(c.pid),
 	}
 	reqData := (*[unsafe.Sizeof(*req)]byte)(unsafe.Pointer(req))[:]
@@ -659,11 +829,11 @@ func (c *command) handshake() error {
 }
 
 func (c *command) handshakeError(err error) error {
-	c.cmd.Process.Kill()
+	c.kill()
 	output := <-c.readDone
 	err = fmt.Errorf("executor %v: %v\n%s", c.pid, err, output)
 	c.wait()
-	if c.cmd.ProcessState != nil {
+	if c.cmd != nil && c.cmd.ProcessState != nil {
 		// Magic values returned by executor.
 		if osutil.ProcessExitStatus(c.cmd.ProcessState) == statusFail {
 			err = ExecutorFailure(err.Error())
@@ -673,7 +843,12 @@ func (c *command) handshakeError(err error) error {
 }
 
 func (c *command) wait() error {
-	err := c.cmd.Wait()
+	var err error
+	if c.cmd != nil {
+		err = c.cmd.Wait()
+	} else {
+		err = fmt.Errorf("remote executor does not respond")
+	}
 	select {
 	case <-c.exited:
 		// c.exited closed by an earlier call to wait.
@@ -687,7 +862,7 @@ func (c *command) exec(opts *ExecOpts, progData []byte) (output []byte, failed,
 	restart bool, err0 error) {
 	req := &executeReq{
 		magic:     inMagic,
-		envFlags:  uint64(c.config.Flags),
+		envFlags:  c.envFlags(),
 		execFlags: uint64(opts.Flags),
 		pid:       uint64(c.pid),
 		faultCall: uint64(opts.FaultCall),
@@ -715,7 +890,7 @@ func (c *command) exec(opts *ExecOpts, progData []byte) (output []byte, failed,
 		t := time.NewTimer(c.timeout)
 		select {
 		case <-t.C:
-			c.cmd.Process.Kill()
+			c.kill()
 			hang <- true
 		case <-done:
 			t.Stop()
@@ -724,6 +899,7 @@ func (c *command) exec(opts *ExecOpts, progData []byte) (output []byte, failed,
 	}()
 	restart = c.config.Flags&FlagUseForkServer == 0
 	exitStatus := -1
+	remote := c.config.Flags&FlagRemote != 0
 	completedCalls := (*uint32)(unsafe.Pointer(&c.outmem[0]))
 	outmem := c.outmem[4:]
 	for {
@@ -738,6 +914,9 @@ func (c *command) exec(opts *ExecOpts, progData []byte) (output []byte, failed,
 		}
 		if reply.done != 0 {
 			exitStatus = int(reply.status)
+			if exitStatus == 0 && remote && !c.readRemoteOutput() {
+				exitStatus = -1
+			}
 			break
 		}
 		callReply := &callReply{}
@@ -760,7 +939,7 @@ func (c *command) exec(opts *ExecOpts, progData []byte) (output []byte, failed,
 		<-hang
 		return
 	}
-	c.cmd.Process.Kill()
+	c.kill()
 	output = <-c.readDone
 	if err := c.wait(); <-hang {
 		hanged = true
@@ -768,7 +947,7 @@ func (c *command) exec(opts *ExecOpts, progData []byte) (output []byte, failed,
 		output = append(output, '\n')
 		return
 	}
-	if exitStatus == -1 {
+	if exitStatus == -1 && c.cmd != nil {
 		exitStatus = osutil.ProcessExitStatus(c.cmd.ProcessState)
 		if exitStatus == 0 {
 			exitStatus = statusRetry // fuchsia always returns wrong exit status 0
@@ -795,6 +974,21 @@ func (c *command) exec(opts *ExecOpts, progData []byte) (output []byte, failed,
 	return
 }
 
+// readRemoteOutput reads output of the program sent by remote executor after the reply
+// (size in words followed by the contents of executor output region) into outmem.
+func (c *command) readRemoteOutput() bool {
+	var sizeData [4]byte
+	if _, err := io.ReadFull(c.inrp, sizeData[:]); err != nil {
+		return false
+	}
+	size := int(*(*uint32)(unsafe.Pointer(&sizeData[0]))) * 4
+	if size < 4 || size > len(c.outmem) {
+		return false
+	}
+	_, err := io.ReadFull(c.inrp, c.outmem[:size])
+	return err == nil
+}
+
 func sanitizeTimeout(config *Config) time.Duration {
 	const (
 		executorTimeout = 5 * time.Second
@@ -5,10 +5,14 @@ package ipc_test
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -18,11 +22,12 @@ import (
 	"github.com/google/syzkaller/pkg/osutil"
 	"github.com/google/syzkaller/prog"
 	_ "github.com/google/syzkaller/sys"
+	"github.com/google/syzkaller/sys/targets"
 )
 
 const timeout = 10 * time.Second
 
-func buildExecutor(t *testing.T, target *prog.Target) string {
+func buildExecutor(t testing.TB, target *prog.Target) string {
 	src := filepath.FromSlash("../../executor/executor.cc")
 	bin, err := csource.BuildFile(target, src)
 	if err != nil {
@@ -175,3 +180,265 @@ func TestParallel(t *testing.T) {
 		}
 	}
 }
+
+func TestCallTime(t *testing.T) {
+	target, _, _, configFlags := initTest(t)
+	if target.OS != "linux" {
+		t.Skipf("test program is linux-specific")
+	}
+	bin := buildExecutor(t, target)
+	defer os.Remove(bin)
+	cfg := &Config{
+		Executor: bin,
+		Flags:    configFlags,
+		Timeout:  timeout,
+	}
+	env, err := MakeEnv(cfg, 0)
+	if err != nil {
+		t.Fatalf("failed to create env: %v", err)
+	}
+	defer env.Close()
+	// Sleep for 10ms, so that wall time is noticeable while CPU time is not.
+	p, err := target.Deserialize([]byte(`
+mmap(&(0x7f0000000000/0x1000)=nil, 0x1000, 0x3, 0x32, 0xffffffffffffffff, 0x0)
+nanosleep(&(0x7f0000000000)={0x0, 0x989680}, 0x0)
+`), prog.Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, info, failed, hanged, err := env.Exec(&ExecOpts{}, p)
+	if err != nil {
+		t.Fatalf("failed to run executor: %v", err)
+	}
+	if hanged || failed {
+		t.Fatalf("program failed (hanged=%v):\n%s", hanged, output)
+	}
+	if len(info.Calls) != len(p.Calls) {
+		t.Fatalf("executed %v calls, want %v", len(info.Calls), len(p.Calls))
+	}
+	for i, inf := range info.Calls {
+		if inf.CPUTime > inf.Time {
+			t.Errorf("call #%v: CPU time %v is larger than wall time %v", i, inf.CPUTime, inf.Time)
+		}
+	}
+	if sleep := info.Calls[1]; sleep.Errno != 0 || sleep.Time < 10*time.Millisecond {
+		t.Fatalf("nanosleep: errno %v, wall time %v, want at least 10ms", sleep.Errno, sleep.Time)
+	}
+}
+
+func TestExecRemote(t *testing.T) {
+	target, _, _, configFlags := initTest(t)
+	if configFlags&(FlagUseShmem|FlagUseForkServer) != FlagUseShmem|FlagUseForkServer {
+		t.Skipf("remote mode is not supported on %v/%v", target.OS, target.Arch)
+	}
+	bin := buildExecutor(t, target)
+	defer os.Remove(bin)
+	cfg := &Config{
+		Executor: bin,
+		Flags:    configFlags | FlagRemote,
+		Timeout:  timeout,
+	}
+	env, err := MakeEnv(cfg, 0)
+	if err != nil {
+		t.Fatalf("failed to create env: %v", err)
+	}
+	defer env.Close()
+	for i := 0; i < 5; i++ {
+		output, info, failed, hanged, err := env.Exec(&ExecOpts{}, target.GenerateSimpleProg())
+		if err != nil {
+			t.Fatalf("failed to run executor: %v", err)
+		}
+		if hanged {
+			t.Fatalf("program hanged:\n%s", output)
+		}
+		if failed {
+			t.Fatalf("program failed:\n%s", output)
+		}
+		if len(info.Calls) == 0 || info.Calls[0].Flags&CallExecuted == 0 {
+			t.Fatalf("prog %v: no calls executed:\n%s", i, output)
+		}
+		if info.Calls[0].Errno != 0 {
+			t.Fatalf("prog %v: simple call failed: %v\n%s", i, info.Calls[0].Errno, output)
+		}
+	}
+}
+
+// remoteExecutor emulates a remote machine with executor control pipes connected to a unix socket
+// (this is what qemu virtio-serial port looks like from host).
+// It returns config that starts executor bin on the "remote machine" and the number of started executors.
+func remoteExecutor(t testing.TB, bin string, flags EnvFlags) (*Config, *int32, func()) {
+	dir, err := ioutil.TempDir("", "syz-remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("unix", filepath.Join(dir, "executor.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	// On a real machine Executor is an ssh command that starts executor in background,
+	// here executor is started on connect and the command does nothing.
+	starter := filepath.Join(dir, "starter")
+	if err := osutil.WriteExecFile(starter, []byte("#!/bin/sh\n")); err != nil {
+		ln.Close()
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	started := new(int32)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f, err := conn.(*net.UnixConn).File()
+			conn.Close()
+			if err != nil {
+				continue
+			}
+			cmd := exec.Command(bin)
+			cmd.Stdin = f
+			cmd.Stdout = f
+			if err := cmd.Start(); err == nil {
+				atomic.AddInt32(started, 1)
+				go cmd.Wait()
+			}
+			f.Close()
+		}
+	}()
+	cfg := &Config{
+		Executor:     starter,
+		Flags:        flags | FlagRemote,
+		Timeout:      timeout,
+		ExecutorConn: ln.Addr().String(),
+	}
+	return cfg, started, func() {
+		ln.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+type testSnapshotter struct {
+	started   *int32
+	snapshots int
+	restores  int
+	// number of started executors at the time of snapshot
+	snapshotStarted int32
+}
+
+func (s *testSnapshotter) Snapshot() error {
+	s.snapshots++
+	s.snapshotStarted = atomic.LoadInt32(s.started)
+	return nil
+}
+
+func (s *testSnapshotter) Restore() error {
+	s.restores++
+	return nil
+}
+
+func TestExecSnapshot(t *testing.T) {
+	target, _, _, configFlags := initTest(t)
+	if configFlags&(FlagUseShmem|FlagUseForkServer) != FlagUseShmem|FlagUseForkServer {
+		t.Skipf("remote mode is not supported on %v/%v", target.OS, target.Arch)
+	}
+	bin := buildExecutor(t, target)
+	defer os.Remove(bin)
+	cfg, started, cleanup := remoteExecutor(t, bin, configFlags)
+	defer cleanup()
+	env, err := MakeEnv(cfg, 0)
+	if err != nil {
+		t.Fatalf("failed to create env: %v", err)
+	}
+	defer env.Close()
+	s := &testSnapshotter{started: started}
+	if err := env.EnableSnapshots(s, 2); err != nil {
+		t.Fatal(err)
+	}
+	if s.snapshots != 1 || s.snapshotStarted != 1 {
+		t.Fatalf("snapshot is not taken after executor start: snapshots=%v started=%v",
+			s.snapshots, s.snapshotStarted)
+	}
+	const progs = 6
+	for i := 0; i < progs; i++ {
+		output, info, failed, hanged, err := env.Exec(&ExecOpts{}, target.GenerateSimpleProg())
+		if err != nil {
+			t.Fatalf("failed to run executor: %v", err)
+		}
+		if hanged || failed {
+			t.Fatalf("program failed (hanged=%v):\n%s", hanged, output)
+		}
+		if len(info.Calls) == 0 || info.Calls[0].Flags&CallExecuted == 0 {
+			t.Fatalf("prog %v: no calls executed:\n%s", i, output)
+		}
+	}
+	if s.restores != progs/2-1 {
+		t.Fatalf("got %v restores, want %v", s.restores, progs/2-1)
+	}
+	if n := atomic.LoadInt32(started); n != 1 {
+		t.Fatalf("executor was started %v times", n)
+	}
+}
+
+// BenchmarkExecShmem and BenchmarkExecRemote compare the overhead of shmem emulation in remote mode.
+// BenchmarkExecSnapshot measures snapshot mode overhead on top of that:
+// it talks to executor over a socket and restores a (fake) snapshot before every program,
+// the cost of the actual VM restore needs to be added to the result.
+func BenchmarkExecShmem(b *testing.B) {
+	benchmarkExec(b, 0, false)
+}
+
+func BenchmarkExecRemote(b *testing.B) {
+	benchmarkExec(b, FlagRemote, false)
+}
+
+func BenchmarkExecSnapshot(b *testing.B) {
+	benchmarkExec(b, FlagRemote, true)
+}
+
+func benchmarkExec(b *testing.B, flags EnvFlags, snapshot bool) {
+	target, err := prog.GetTarget(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		b.Fatal(err)
+	}
+	sysTarget := targets.Get(target.OS, target.Arch)
+	cfg := &Config{
+		Timeout: timeout,
+		Flags:   flags,
+	}
+	if sysTarget.ExecutorUsesShmem {
+		cfg.Flags |= FlagUseShmem
+	}
+	if sysTarget.ExecutorUsesForkServer {
+		cfg.Flags |= FlagUseForkServer
+	}
+	if flags&FlagRemote != 0 && cfg.Flags&FlagUseShmem == 0 {
+		b.Skipf("remote mode is not supported on %v/%v", target.OS, target.Arch)
+	}
+	cfg.Executor = buildExecutor(b, target)
+	defer os.Remove(cfg.Executor)
+	var started *int32
+	if snapshot {
+		var cleanup func()
+		cfg, started, cleanup = remoteExecutor(b, cfg.Executor, cfg.Flags)
+		defer cleanup()
+	}
+	env, err := MakeEnv(cfg, 0)
+	if err != nil {
+		b.Fatalf("failed to create env: %v", err)
+	}
+	defer env.Close()
+	if snapshot {
+		if err := env.EnableSnapshots(&testSnapshotter{started: started}, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+	p := target.GenerateSimpleProg()
+	opts := &ExecOpts{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, _, err := env.Exec(opts, p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
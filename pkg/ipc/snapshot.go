@@ -0,0 +1,91 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package ipc
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Snapshotter saves and restores state of the machine where executor runs.
+// Env uses it to return the machine into a clean state between programs (see Env.EnableSnapshots).
+type Snapshotter interface {
+	// Snapshot saves current state of the machine.
+	Snapshot() error
+	// Restore returns the machine into the state saved by the last Snapshot call.
+	Restore() error
+}
+
+// QemuSnapshotter implements Snapshotter with savevm/loadvm commands
+// issued via qemu human monitor exposed on a unix socket
+// (qemu -monitor unix:path,server,nowait).
+type QemuSnapshotter struct {
+	monitor string
+	name    string
+}
+
+func NewQemuSnapshotter(monitor string) *QemuSnapshotter {
+	return &QemuSnapshotter{
+		monitor: monitor,
+		name:    "syzkaller",
+	}
+}
+
+func (s *QemuSnapshotter) Snapshot() error {
+	return s.command("savevm " + s.name)
+}
+
+func (s *QemuSnapshotter) Restore() error {
+	return s.command("loadvm " + s.name)
+}
+
+const qemuMonitorPrompt = "(qemu) "
+
+func (s *QemuSnapshotter) command(cmd string) error {
+	conn, err := net.DialTimeout("unix", s.monitor, time.Minute)
+	if err != nil {
+		return fmt.Errorf("failed to connect to qemu monitor: %v", err)
+	}
+	defer conn.Close()
+	// savevm/loadvm of a VM with lots of memory can take a while.
+	conn.SetDeadline(time.Now().Add(5 * time.Minute))
+	if _, err := readUntilPrompt(conn); err != nil {
+		return fmt.Errorf("failed to read qemu monitor banner: %v", err)
+	}
+	if _, err := conn.Write([]byte(cmd + "\n")); err != nil {
+		return fmt.Errorf("failed to write qemu monitor command: %v", err)
+	}
+	output, err := readUntilPrompt(conn)
+	if err != nil {
+		return fmt.Errorf("failed to read qemu monitor reply for %q: %v", cmd, err)
+	}
+	// Monitor does not have error codes, errors are reported as free-form text
+	// (e.g. "Error: Device 'hda' is writable but does not support snapshots").
+	// Monitor also echoes the command, so look only at the lines that follow it.
+	if pos := bytes.IndexByte(output, '\n'); pos != -1 {
+		output = output[pos+1:]
+	} else {
+		output = nil
+	}
+	if reply := strings.TrimSpace(string(output)); strings.Contains(strings.ToLower(reply), "error") {
+		return fmt.Errorf("qemu monitor command %q failed: %v", cmd, reply)
+	}
+	return nil
+}
+
+func readUntilPrompt(conn net.Conn) ([]byte, error) {
+	var output []byte
+	buf := make([]byte, 1<<10)
+	for !bytes.HasSuffix(output, []byte(qemuMonitorPrompt)) {
+		n, err := conn.Read(buf)
+		output = append(output, buf[:n]...)
+		if err != nil {
+			return output, err
+		}
+	}
+	return output[:len(output)-len(qemuMonitorPrompt)], nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package ipc
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeMonitor emulates qemu human monitor: it prints banner and prompt,
+// echoes commands and replies with the given output for known commands.
+func fakeMonitor(t *testing.T, replies map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "syz-monitor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "monitor")
+	ln, err := net.Listen("unix", file)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(conn, "QEMU 2.11.1 monitor - type 'help' for more information\r\n%v", qemuMonitorPrompt)
+			cmd, err := bufio.NewReader(conn).ReadString('\n')
+			if err == nil {
+				cmd = strings.TrimSpace(cmd)
+				fmt.Fprintf(conn, "%v\r\n%v%v", cmd, replies[cmd], qemuMonitorPrompt)
+			}
+			conn.Close()
+		}
+	}()
+	return file, func() {
+		ln.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestQemuSnapshotter(t *testing.T) {
+	monitor, cleanup := fakeMonitor(t, map[string]string{
+		"loadvm syzkaller": "Error: Snapshot 'syzkaller' does not exist in one or more devices\r\n",
+	})
+	defer cleanup()
+	s := NewQemuSnapshotter(monitor)
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	err := s.Restore()
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("restore did not fail with the expected error: %v", err)
+	}
+}
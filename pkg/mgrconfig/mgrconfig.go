@@ -7,11 +7,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/google/syzkaller/pkg/config"
+	"github.com/google/syzkaller/pkg/host"
 	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/pkg/signal"
 	"github.com/google/syzkaller/prog"
 	_ "github.com/google/syzkaller/sys" // most mgrconfig users want targets too
 	"github.com/google/syzkaller/sys/targets"
@@ -67,17 +72,43 @@ type Config struct {
 
 	// Use KCOV coverage (default: true).
 	Cover bool `json:"cover"`
+	// Granularity of feedback signal (default: "edge"):
+	// "edge": edges between subsequent basic blocks
+	// "pc": individual basic blocks
+	// "ngram": sequences of 3 subsequent basic blocks
+	// "call_context": edges distinguished by the syscall that executed them
+	// Corpus collected in one mode can't be reused in a different mode.
+	SignalMode string `json:"signal_mode"`
+	// Restrict feedback signal to the specified parts of the kernel (optional, requires cover).
+	// Signal that comes from code outside of the filter is ignored by fuzzers.
+	CoverFilter *CoverFilter `json:"cover_filter"`
 	// Reproduce, localize and minimize crashers (default: true).
 	Reproduce bool `json:"reproduce"`
+	// Number of times the final reproducer is re-run (both syz and C variants) to measure
+	// its reliability: reproduction rate and mean time to crash (default: 0, disabled).
+	// Each run occupies a repro VM for up to the reproducer duration, so every successful
+	// reproduction costs up to twice that many additional runs.
+	ReproReliabilityRuns int `json:"repro_reliability_runs"`
+	// Number of failed attempts to reproduce a crash title after which it's not reproduced
+	// anymore (default: 3). Repros requested by dashboard are not limited.
+	ReproAttempts int `json:"repro_attempts"`
+	// Max fraction of VMs used for crash reproduction (default: 0.5),
+	// at least one repro can run regardless of the fraction.
+	ReproVMFraction float64 `json:"repro_vm_fraction"`
 
 	EnabledSyscalls  []string `json:"enable_syscalls"`
 	DisabledSyscalls []string `json:"disable_syscalls"`
+	// Overrides for detected machine features (see host.FeatureIDs for names, e.g. "leak_checking"):
+	// false disables the feature, true requires the feature to be supported by the machine.
+	Features map[string]bool `json:"features"`
 	// Don't save reports matching these regexps, but reboot VM after them,
 	// matched against whole report output.
 	Suppressions []string `json:"suppressions"`
 	// Completely ignore reports matching these regexps (don't save nor reboot),
 	// must match the first line of crash message.
 	Ignores []string `json:"ignores"`
+	// Rules that rewrite and classify crash titles, applied in order after report parsing.
+	CrashRules []CrashRule `json:"crash_rules"`
 
 	// VM type (qemu, gce, android, isolated, etc).
 	Type string `json:"type"`
@@ -95,6 +126,49 @@ type Config struct {
 	SyzExecutorBin string `json:"-"`
 }
 
+// CrashRule rewrites title and/or attaches classification to crashes with matching titles.
+// All matching rules are applied in order, so a rule sees the title produced by the previous rules.
+type CrashRule struct {
+	// Regexp matched against crash title (required).
+	Match string `json:"match"`
+	// Replacement title, can reference regexp submatches as $1 or ${name} (optional).
+	Title string `json:"title"`
+	// Labels attached to the crash (e.g. "harmless"), optional.
+	Labels []string `json:"labels"`
+	// Severity of the crash: low, medium, high, severe or critical (optional).
+	Severity string `json:"severity"`
+	// Additional emails that should be CCed on the crash (optional).
+	Maintainers []string `json:"maintainers"`
+}
+
+// CoverFilter selects kernel code that gives feedback signal.
+// Code is selected if it matches any of the files, functions or pcs entries.
+type CoverFilter struct {
+	// Glob patterns matched against source file names relative to kernel_src
+	// and their parent directories (e.g. "net/sctp" or "net/ipv4/tcp*.c").
+	Files []string `json:"files"`
+	// Glob patterns matched against function names (e.g. "sctp_*").
+	Functions []string `json:"functions"`
+	// Ranges of kernel PCs in the form "0xffffffff81000000-0xffffffff81001000" (end is exclusive).
+	PCs []string `json:"pcs"`
+	// Fraction of signal from code outside of the filter that is still used, in [0, 1)
+	// (default: 0, such signal is dropped). Allows to down-weight rather than ignore other code.
+	Weight float64 `json:"weight"`
+}
+
+// CrashSeverities lists allowed values of CrashRule.Severity in increasing order.
+var CrashSeverities = []string{"low", "medium", "high", "severe", "critical"}
+
+// CrashSeverity returns index of the severity in CrashSeverities, or -1 if it's unknown.
+func CrashSeverity(severity string) int {
+	for i, s := range CrashSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
 func LoadData(data []byte) (*Config, error) {
 	cfg, err := LoadPartialData(data)
 	if err != nil {
@@ -135,12 +209,16 @@ func LoadPartialFile(filename string) (*Config, error) {
 
 func defaultValues() *Config {
 	return &Config{
-		SSHUser:   "root",
-		Cover:     true,
-		Reproduce: true,
-		Sandbox:   "none",
-		RPC:       ":0",
-		Procs:     1,
+		SSHUser:    "root",
+		Cover:      true,
+		SignalMode: signal.ModeEdge.String(),
+		Reproduce:  true,
+		Sandbox:    "none",
+		RPC:        ":0",
+		Procs:      1,
+
+		ReproAttempts:   3,
+		ReproVMFraction: 0.5,
 	}
 }
 
@@ -184,6 +262,27 @@ func Complete(cfg *Config) error {
 	if err := checkSSHParams(cfg); err != nil {
 		return err
 	}
+	if err := checkFeatureOverrides(cfg); err != nil {
+		return err
+	}
+	if err := checkCrashRules(cfg.CrashRules); err != nil {
+		return err
+	}
+	if _, err := signal.ParseMode(cfg.SignalMode); err != nil {
+		return fmt.Errorf("bad config param signal_mode: %v", err)
+	}
+	if err := checkCoverFilter(cfg); err != nil {
+		return err
+	}
+	if cfg.ReproReliabilityRuns < 0 {
+		return fmt.Errorf("bad config param repro_reliability_runs: %v", cfg.ReproReliabilityRuns)
+	}
+	if cfg.ReproAttempts <= 0 {
+		return fmt.Errorf("bad config param repro_attempts: %v", cfg.ReproAttempts)
+	}
+	if cfg.ReproVMFraction < 0 || cfg.ReproVMFraction > 1 {
+		return fmt.Errorf("bad config param repro_vm_fraction: %v, want [0, 1]", cfg.ReproVMFraction)
+	}
 
 	cfg.KernelObj = osutil.Abs(cfg.KernelObj)
 	if cfg.KernelSrc == "" {
@@ -202,6 +301,97 @@ func Complete(cfg *Config) error {
 	return nil
 }
 
+func checkCrashRules(rules []CrashRule) error {
+	for i, rule := range rules {
+		if rule.Match == "" {
+			return fmt.Errorf("crash_rules[%v]: match is empty", i)
+		}
+		if _, err := regexp.Compile(rule.Match); err != nil {
+			return fmt.Errorf("crash_rules[%v]: bad match regexp: %v", i, err)
+		}
+		if rule.Title == "" && len(rule.Labels) == 0 && rule.Severity == "" && len(rule.Maintainers) == 0 {
+			return fmt.Errorf("crash_rules[%v]: rule does not do anything", i)
+		}
+		if rule.Severity != "" && CrashSeverity(rule.Severity) < 0 {
+			return fmt.Errorf("crash_rules[%v]: bad severity %q, want one of %v",
+				i, rule.Severity, strings.Join(CrashSeverities, "/"))
+		}
+	}
+	return nil
+}
+
+// checkFeatureOverrides checks that features don't disable features required by other config params,
+// otherwise every machine check fails.
+func checkFeatureOverrides(cfg *Config) error {
+	if err := host.ValidateOverrides(cfg.Features); err != nil {
+		return fmt.Errorf("bad config param features: %v", err)
+	}
+	required := make(map[string]string) // feature -> config param that requires it
+	if cfg.Cover {
+		required["coverage"] = `"cover": true`
+	}
+	switch cfg.Sandbox {
+	case "setuid", "namespace", "android_untrusted_app":
+		required["sandbox_"+cfg.Sandbox] = fmt.Sprintf(`"sandbox": %q`, cfg.Sandbox)
+	}
+	for feature, param := range required {
+		if enable, ok := cfg.Features[feature]; ok && !enable {
+			return fmt.Errorf("bad config param features: %v is disabled, but %v requires it", feature, param)
+		}
+	}
+	return nil
+}
+
+func checkCoverFilter(cfg *Config) error {
+	f := cfg.CoverFilter
+	if f == nil {
+		return nil
+	}
+	if !cfg.Cover {
+		return fmt.Errorf("cover_filter requires cover")
+	}
+	if len(f.Files) == 0 && len(f.Functions) == 0 && len(f.PCs) == 0 {
+		return fmt.Errorf("cover_filter is empty")
+	}
+	if (len(f.Files) != 0 || len(f.Functions) != 0) && cfg.KernelObj == "" {
+		return fmt.Errorf("cover_filter files/functions require kernel_obj")
+	}
+	if f.Weight < 0 || f.Weight >= 1 {
+		return fmt.Errorf("bad cover_filter weight: %v, want [0, 1)", f.Weight)
+	}
+	for _, pattern := range append(append([]string{}, f.Files...), f.Functions...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("cover_filter: bad pattern %q: %v", pattern, err)
+		}
+	}
+	for _, pcs := range f.PCs {
+		if _, _, err := ParsePCRange(pcs); err != nil {
+			return fmt.Errorf("cover_filter: %v", err)
+		}
+	}
+	return nil
+}
+
+// ParsePCRange parses PC range in the form "0xffffffff81000000-0xffffffff81001000".
+func ParsePCRange(s string) (uint64, uint64, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("bad pc range %q, want start-end", s)
+	}
+	start, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 0, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad pc range %q: %v", s, err)
+	}
+	end, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 0, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad pc range %q: %v", s, err)
+	}
+	if start >= end {
+		return 0, 0, fmt.Errorf("bad pc range %q: start is not less than end", s)
+	}
+	return start, end, nil
+}
+
 func checkSSHParams(cfg *Config) error {
 	if cfg.SSHUser == "" {
 		return fmt.Errorf("bad config syzkaller param: ssh user is empty")
@@ -62,3 +62,53 @@ func TestMatchSyscall(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePCRange(t *testing.T) {
+	tests := []struct {
+		input string
+		start uint64
+		end   uint64
+		err   bool
+	}{
+		{"0xffffffff81000000-0xffffffff81001000", 0xffffffff81000000, 0xffffffff81001000, false},
+		{"0x10 - 0x20", 0x10, 0x20, false},
+		{"0x20-0x10", 0, 0, true},
+		{"0x10", 0, 0, true},
+		{"foo-0x10", 0, 0, true},
+	}
+	for i, test := range tests {
+		start, end, err := ParsePCRange(test.input)
+		if test.err != (err != nil) {
+			t.Errorf("#%v: input=%q want err=%v got %v", i, test.input, test.err, err)
+			continue
+		}
+		if start != test.start || end != test.end {
+			t.Errorf("#%v: input=%q want 0x%x-0x%x got 0x%x-0x%x",
+				i, test.input, test.start, test.end, start, end)
+		}
+	}
+}
+
+func TestCheckFeatureOverrides(t *testing.T) {
+	tests := []struct {
+		cover    bool
+		sandbox  string
+		features map[string]bool
+		err      bool
+	}{
+		{true, "none", nil, false},
+		{true, "none", map[string]bool{"coverage": true, "leak_checking": false}, false},
+		{true, "none", map[string]bool{"coverage": false}, true},
+		{false, "none", map[string]bool{"coverage": false}, false},
+		{false, "setuid", map[string]bool{"sandbox_setuid": false}, true},
+		{false, "none", map[string]bool{"sandbox_setuid": false}, false},
+		{false, "none", map[string]bool{"foo": true}, true},
+	}
+	for i, test := range tests {
+		cfg := &Config{Cover: test.cover, Sandbox: test.sandbox, Features: test.features}
+		err := checkFeatureOverrides(cfg)
+		if test.err != (err != nil) {
+			t.Errorf("#%v: want err=%v got %v", i, test.err, err)
+		}
+	}
+}
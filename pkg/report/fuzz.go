@@ -5,10 +5,13 @@ package report
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/google/syzkaller/pkg/mgrconfig"
 )
 
+// Fuzz runs all reporters over data and checks invariants that must hold for any input.
+// The report testdata can be used as the initial corpus (see TestFuzzCorpus).
 func Fuzz(data []byte) int {
 	res := 0
 	for _, reporter := range fuzzReporters {
@@ -22,10 +25,23 @@ func Fuzz(data []byte) int {
 			continue
 		}
 		res = 1
-		reporter.Symbolize(rep)
 		if rep.Title == "" {
 			panic(fmt.Sprintf("%v: Title is empty", typ))
 		}
+		if strings.ContainsAny(rep.Title, "\r\n") {
+			panic(fmt.Sprintf("%v: Title contains new line: %q", typ, rep.Title))
+		}
+		// Titles must be stable: parsing the same output again,
+		// or parsing from StartPos must produce the same title.
+		if rep1 := reporter.Parse(data); rep1 == nil || rep1.Title != rep.Title {
+			panic(fmt.Sprintf("%v: Parse is not deterministic", typ))
+		}
+		if rep.StartPos != 0 {
+			if rep1 := reporter.Parse(data[rep.StartPos:]); rep1 == nil || rep1.Title != rep.Title {
+				panic(fmt.Sprintf("%v: different title when parsing from StartPos=%v", typ, rep.StartPos))
+			}
+		}
+		reporter.Symbolize(rep)
 		if len(rep.Report) == 0 {
 			panic(fmt.Sprintf("%v: len(Report) == 0", typ))
 		}
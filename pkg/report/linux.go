@@ -160,6 +160,7 @@ func (ctx *linux) Parse(output []byte) *Report {
 	if !rep.Corrupted {
 		rep.Corrupted, rep.CorruptedReason = ctx.isCorrupted(title, report, format)
 	}
+	rep.Details = parseLinuxDetails(report)
 	return rep
 }
 
@@ -0,0 +1,189 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	linuxKasanTitleRe = regexp.MustCompile(`BUG: KASAN: ([a-z\-]+(?: or [a-z\-]+)?) (?:in|on) [^ ]+(?:.* at addr ([0-9a-f]+))?`)
+	linuxKmsanTitleRe = regexp.MustCompile(`BUG: KMSAN: ([a-z\-]+)`)
+	linuxUbsanTitleRe = regexp.MustCompile(`UBSAN: (.+?) in `)
+	linuxLockdepRe    = regexp.MustCompile(`(?:WARNING|INFO): (possible circular locking dependency|` +
+		`possible recursive locking|inconsistent lock state|possible irq lock inversion dependency|` +
+		`bad unlock balance|held lock freed|lock held when returning to user space|suspicious RCU usage)`)
+
+	// KASAN access line, older kernels print address in the title line instead.
+	linuxAccessRe = regexp.MustCompile(`(Read|Write) of size ([0-9]+)(?: at addr ([0-9a-f]+))? by task ([^ ]+)/([0-9]+)`)
+	linuxTaskRe   = regexp.MustCompile(`CPU: [0-9]+ PID: ([0-9]+) Comm: ([^ ]+)`)
+	// Lockdep task line: "syz-executor5/5807 is trying to acquire lock:".
+	linuxLockTaskRe = regexp.MustCompile(`([^ ]+)/([0-9]+) is trying to (?:acquire|release) lock`)
+	// Lockdep dependency chain entry: "-> #1 (&xt[i].mutex){+.+.}:".
+	linuxLockChainRe = regexp.MustCompile(`-> #[0-9]+ \((.+)\)\{[^}]*\}`)
+	// Lock description that follows "trying to acquire lock:"/"already holding lock:".
+	linuxLockRe = regexp.MustCompile(`^ *(?:#[0-9]+: *)?\((.+)\)\{[^}]*\}`)
+
+	linuxCallTraceRe  = regexp.MustCompile(`Call Trace:`)
+	linuxAllocStackRe = regexp.MustCompile(`^(?:Allocated by task [0-9]+:|Allocated:|Uninit was created at:|local variable created at:)`)
+	linuxFreeStackRe  = regexp.MustCompile(`^(?:Freed by task [0-9]+:|Freed:)`)
+)
+
+// linuxUbsanTypes classify old-style "UBSAN: Undefined behaviour in file:line" reports
+// by the line that follows the title.
+var linuxUbsanTypes = []struct {
+	re  *regexp.Regexp
+	typ string
+}{
+	{regexp.MustCompile(`shift exponent|left shift of`), "shift-out-of-bounds"},
+	{regexp.MustCompile(`index .* is out of range`), "array-index-out-of-bounds"},
+	{regexp.MustCompile(`signed integer overflow`), "signed-integer-overflow"},
+	{regexp.MustCompile(`negation of`), "signed-integer-overflow"},
+	{regexp.MustCompile(`division by zero|division of .* by -1`), "divide-by-zero"},
+	{regexp.MustCompile(`load of value .* is not a valid value`), "invalid-load"},
+	{regexp.MustCompile(`null pointer`), "null-ptr-deref"},
+	{regexp.MustCompile(`misaligned address`), "misaligned-access"},
+	{regexp.MustCompile(`object size mismatch|insufficient space`), "object-size-mismatch"},
+}
+
+var linuxLockdepTypes = map[string]string{
+	"possible circular locking dependency":   "circular-locking",
+	"possible recursive locking":             "recursive-locking",
+	"inconsistent lock state":                "inconsistent-lock-state",
+	"possible irq lock inversion dependency": "irq-lock-inversion",
+	"bad unlock balance":                     "bad-unlock-balance",
+	"held lock freed":                        "held-lock-freed",
+	"lock held when returning to user space": "lock-held-on-return",
+	"suspicious RCU usage":                   "suspicious-rcu-usage",
+}
+
+// parseLinuxDetails extracts structured information from KASAN/KMSAN/UBSAN/lockdep reports.
+// Returns nil for other report types.
+func parseLinuxDetails(report []byte) *Details {
+	d := new(Details)
+	if match := linuxKasanTitleRe.FindSubmatch(report); match != nil {
+		d.Tool, d.BugType = "KASAN", string(match[1])
+		if len(match[2]) != 0 {
+			d.Address, _ = strconv.ParseUint(string(match[2]), 16, 64)
+		}
+	} else if match := linuxKmsanTitleRe.FindSubmatch(report); match != nil {
+		d.Tool, d.BugType = "KMSAN", string(match[1])
+	} else if match := linuxUbsanTitleRe.FindSubmatchIndex(report); match != nil {
+		d.Tool, d.BugType = "UBSAN", string(report[match[2]:match[3]])
+		if d.BugType == "Undefined behaviour" {
+			d.BugType = classifyLinuxUbsan(report[match[1]:])
+		}
+	} else if match := linuxLockdepRe.FindSubmatch(report); match != nil {
+		d.Tool, d.BugType = "LOCKDEP", linuxLockdepTypes[string(match[1])]
+	} else {
+		return nil
+	}
+	var stack *[]string
+	var lockLine, taskFromCPU bool
+	s := bufio.NewScanner(bytes.NewReader(report))
+	for s.Scan() {
+		ln := bytes.Trim(s.Bytes(), "\r")
+		if stack != nil {
+			if match := matchesAnySubmatch(ln, linuxStackParams.frameRes); match != nil {
+				*stack = append(*stack, string(match[1]))
+				continue
+			}
+			// Skip unreliable frames ("? foo+0x1/0x2"), context markers ("<IRQ>")
+			// and inlined frames added by symbolization.
+			trimmed := bytes.TrimSpace(ln)
+			if bytes.HasPrefix(trimmed, []byte("?")) || bytes.HasPrefix(trimmed, []byte("<")) ||
+				bytes.HasSuffix(trimmed, []byte("[inline]")) {
+				continue
+			}
+			stack = nil
+		}
+		if lockLine {
+			lockLine = false
+			if match := linuxLockRe.FindSubmatch(ln); match != nil {
+				d.LockChain = appendUnique(d.LockChain, string(match[1]))
+				continue
+			}
+		}
+		switch {
+		case linuxCallTraceRe.Match(ln):
+			if len(d.CallTrace) == 0 {
+				stack = &d.CallTrace
+			}
+		case linuxAllocStackRe.Match(bytes.TrimSpace(ln)):
+			if len(d.AllocStack) == 0 {
+				stack = &d.AllocStack
+			}
+		case linuxFreeStackRe.Match(bytes.TrimSpace(ln)):
+			if len(d.FreeStack) == 0 {
+				stack = &d.FreeStack
+			}
+		case bytes.Contains(ln, []byte("is trying to acquire lock:")),
+			bytes.Contains(ln, []byte("already holding lock:")):
+			lockLine = true
+		}
+		if match := linuxAccessRe.FindSubmatch(ln); match != nil && d.Access == "" {
+			d.Access = strings.ToLower(string(match[1]))
+			d.AccessSize, _ = strconv.Atoi(string(match[2]))
+			if len(match[3]) != 0 {
+				d.Address, _ = strconv.ParseUint(string(match[3]), 16, 64)
+			}
+			d.Task = string(match[4])
+			d.PID, _ = strconv.Atoi(string(match[5]))
+			taskFromCPU = false
+		}
+		if match := linuxLockTaskRe.FindSubmatch(ln); match != nil && (d.Task == "" || taskFromCPU) {
+			d.Task = string(match[1])
+			d.PID, _ = strconv.Atoi(string(match[2]))
+			taskFromCPU = false
+		}
+		// The CPU line is less precise (comm is truncated), so it's used only as a fallback.
+		if match := linuxTaskRe.FindSubmatch(ln); match != nil && d.Task == "" {
+			d.Task = string(match[2])
+			d.PID, _ = strconv.Atoi(string(match[1]))
+			taskFromCPU = true
+		}
+		if match := linuxLockChainRe.FindSubmatch(ln); match != nil {
+			d.LockChain = appendUnique(d.LockChain, string(match[1]))
+		}
+	}
+	return d
+}
+
+func classifyLinuxUbsan(rest []byte) string {
+	// The bug description is on the line following the title.
+	if pos := bytes.IndexByte(rest, '\n'); pos != -1 {
+		rest = rest[pos+1:]
+		if pos := bytes.IndexByte(rest, '\n'); pos != -1 {
+			rest = rest[:pos]
+		}
+		for _, typ := range linuxUbsanTypes {
+			if typ.re.Match(rest) {
+				return typ.typ
+			}
+		}
+	}
+	return "undefined-behaviour"
+}
+
+func matchesAnySubmatch(line []byte, res []*regexp.Regexp) [][]byte {
+	for _, re := range res {
+		if match := re.FindSubmatch(line); match != nil {
+			return match
+		}
+	}
+	return nil
+}
+
+func appendUnique(list []string, str string) []string {
+	for _, s := range list {
+		if s == str {
+			return list
+		}
+	}
+	return append(list, str)
+}
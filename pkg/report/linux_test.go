@@ -4,7 +4,11 @@
 package report
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/google/syzkaller/pkg/mgrconfig"
@@ -227,3 +231,153 @@ func TestLinuxSymbolizeLine(t *testing.T) {
 		})
 	}
 }
+
+func TestLinuxStackSignature(t *testing.T) {
+	reporter, err := NewReporter(&mgrconfig.Config{
+		TargetOS:   "linux",
+		TargetArch: "amd64",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const log = `
+[   25.480347] ==================================================================
+[   25.487740] BUG: KASAN: use-after-free in skb_put.isra.3+0x1c/0x90
+[   25.494088] Read of size 4 at addr ffff8801c8a9b8f0 by task syz-executor0/3364
+[   25.501431] 
+[   25.503043] CPU: 0 PID: 3364 Comm: syz-executor0 Not tainted 4.16.0-rc1+ #1
+[   25.510228] Call Trace:
+[   25.512801]  __dump_stack lib/dump_stack.c:17 [inline]
+[   25.512801]  dump_stack+0x194/0x24d
+[   25.516410]  ? arch_local_irq_restore+0x53/0x53
+[   25.521042]  print_address_description+0x73/0x250
+[   25.525955]  kasan_report+0x23c/0x360
+[   25.529736]  __asan_load4+0x78/0x80
+[   25.533354]  skb_put.isra.3+0x1c/0x90
+[   25.537144]  tun_build_skb+0x1b7c/0x3900
+[   25.541100]  tun_build_skb+0x12a/0x3900
+[   25.545056]  tun_chr_write_iter+0xb9/0x160
+[   25.549271]  do_iter_write+0x154/0x540
+[   25.553144]  vfs_writev+0x18a/0x340
+[   25.556754]  do_writev+0xfc/0x2a0
+[   25.560191]  SyS_writev+0x27/0x30
+[   25.563627]  do_syscall_64+0x281/0x940
+[   25.567500]  entry_SYSCALL_64_after_hwframe+0x42/0xb7
+[   25.572664] 
+[   25.574270] Allocated by task 3364:
+[   25.577883]  save_stack+0x43/0xd0
+[   25.581321]  kasan_kmalloc+0xad/0xe0
+[   25.585019] ==================================================================
+`
+	rep := reporter.Parse([]byte(log))
+	if rep == nil {
+		t.Fatalf("no crash")
+	}
+	want := []string{"skb_put", "tun_build_skb", "tun_chr_write_iter", "do_iter_write",
+		"vfs_writev", "do_writev", "SyS_writev", "do_syscall_64", "entry_SYSCALL_64_after_hwframe"}
+	if fmt.Sprint(rep.Stack) != fmt.Sprint(want) {
+		t.Fatalf("wrong stack signature:\n%q\nwant:\n%q", rep.Stack, want)
+	}
+}
+
+func TestLinuxDetails(t *testing.T) {
+	reporter, err := NewReporter(&mgrconfig.Config{
+		TargetOS:   "linux",
+		TargetArch: "amd64",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		file string
+		want *Details
+	}{
+		{
+			file: "105",
+			want: &Details{
+				Tool:       "KASAN",
+				BugType:    "slab-out-of-bounds",
+				Access:     "read",
+				AccessSize: 840,
+				Address:    0xffff88000969e798,
+				Task:       "ip6_fragment-oo",
+				PID:        3789,
+			},
+		},
+		{
+			file: "108",
+			want: &Details{
+				Tool:    "KMSAN",
+				BugType: "uninit-value",
+				Task:    "syz-executor0",
+				PID:     12442,
+			},
+		},
+		{
+			file: "40",
+			want: &Details{
+				Tool:    "UBSAN",
+				BugType: "shift-out-of-bounds",
+				Task:    "usb",
+				PID:     3624,
+			},
+		},
+		{
+			file: "185",
+			want: &Details{
+				Tool:      "LOCKDEP",
+				BugType:   "circular-locking",
+				Task:      "syz-executor5",
+				PID:       5807,
+				LockChain: []string{"sk_lock-AF_INET", "rtnl_mutex", "&xt[i].mutex"},
+			},
+		},
+		{
+			file: "100",
+			want: nil,
+		},
+	}
+	readLog := func(file string) []byte {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", "linux", "report", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Strip test headers and the expected report (see testParseFile).
+		data = data[bytes.Index(data, []byte("\n\n"))+2:]
+		if pos := bytes.Index(data, []byte("\nREPORT:\n")); pos != -1 {
+			data = data[:pos]
+		}
+		return data
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			rep := reporter.Parse(readLog(test.file))
+			if rep == nil {
+				t.Fatalf("no crash")
+			}
+			got := rep.Details
+			if got == nil || test.want == nil {
+				if got != test.want {
+					t.Fatalf("got details %+v, want %+v", got, test.want)
+				}
+				return
+			}
+			// Stacks are checked separately.
+			got1 := *got
+			got1.CallTrace, got1.AllocStack, got1.FreeStack = nil, nil, nil
+			if !reflect.DeepEqual(&got1, test.want) {
+				t.Fatalf("got details:\n%+v\nwant:\n%+v", &got1, test.want)
+			}
+		})
+	}
+	d := reporter.Parse(readLog("105")).Details
+	if len(d.CallTrace) < 6 || d.CallTrace[5] != "ip6_fragment" {
+		t.Errorf("bad call trace: %q", d.CallTrace)
+	}
+	if len(d.AllocStack) == 0 || d.AllocStack[len(d.AllocStack)-2] != "SyS_sendmsg" {
+		t.Errorf("bad alloc stack: %q", d.AllocStack)
+	}
+	if len(d.FreeStack) == 0 || d.FreeStack[3] != "kfree" {
+		t.Errorf("bad free stack: %q", d.FreeStack)
+	}
+}
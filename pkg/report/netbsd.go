@@ -4,6 +4,7 @@
 package report
 
 import (
+	"bytes"
 	"regexp"
 
 	"github.com/google/syzkaller/sys/targets"
@@ -26,13 +27,85 @@ func ctorNetbsd(target *targets.Target, kernelSrc, kernelObj string,
 }
 
 func (ctx *netbsd) ContainsCrash(output []byte) bool {
-	return false
+	return containsCrash(output, netbsdOopses, ctx.ignores)
 }
 
 func (ctx *netbsd) Parse(output []byte) *Report {
-	return nil
+	stripped := bytes.Replace(output, []byte{'\r', '\n'}, []byte{'\n'}, -1)
+	stripped = bytes.Replace(stripped, []byte{'\n', '\r'}, []byte{'\n'}, -1)
+	for len(stripped) != 0 && stripped[0] == '\r' {
+		stripped = stripped[1:]
+	}
+	rep := simpleLineParser(stripped, netbsdOopses, netbsdStackParams, ctx.ignores)
+	if rep == nil {
+		return nil
+	}
+	rep.Output = output
+	return rep
 }
 
 func (ctx *netbsd) Symbolize(rep *Report) error {
 	return nil
 }
+
+var netbsdTracebackRe = compile(`cpu[0-9]+: Begin traceback`)
+
+var netbsdStackParams = &stackParams{
+	stackStartRes: []*regexp.Regexp{
+		netbsdTracebackRe,
+	},
+	frameRes: []*regexp.Regexp{
+		compile(`^(?:\[ *[0-9.]+\] )?([A-Za-z0-9_]+)\(\) at netbsd:`),
+	},
+	skipPatterns: []string{
+		"^vpanic$",
+		"^panic$",
+		"printf$",
+		"^startlwp$",
+		"^trap$",
+		"^alltraps$",
+		"^calltrap$",
+	},
+}
+
+var netbsdOopses = []*oops{
+	{
+		[]byte("fault in supervisor mode"),
+		[]oopsFormat{
+			{
+				title: compile("fatal (page|protection|integer divide) fault in supervisor mode"),
+				fmt:   "%[1]v fault in %[2]v",
+				stack: &stackFmt{
+					parts: []*regexp.Regexp{
+						netbsdTracebackRe,
+						parseStackTrace,
+					},
+					parts2: []*regexp.Regexp{
+						compile(`Stopped in .* at[ ]+netbsd:([A-Za-z0-9_]+)`),
+					},
+				},
+			},
+		},
+		[]*regexp.Regexp{},
+	},
+	{
+		[]byte("panic: "),
+		[]oopsFormat{
+			{
+				title: compile(`panic: kernel diagnostic assertion (".*?") failed: file "(?:.*/)?([^"]+)"`),
+				fmt:   "assert %[1]v failed in %[2]v",
+			},
+		},
+		[]*regexp.Regexp{},
+	},
+	{
+		[]byte("UBSan: Undefined Behavior in "),
+		[]oopsFormat{
+			{
+				title: compile(`UBSan: Undefined Behavior in (?:.*/)?([^/:]+):[0-9]+:[0-9]+`),
+				fmt:   "UBSan: Undefined Behavior in %[1]v",
+			},
+		},
+		[]*regexp.Regexp{},
+	},
+}
@@ -11,8 +11,10 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/pkg/report/stack"
 	"github.com/google/syzkaller/sys/targets"
 )
 
@@ -46,12 +48,54 @@ type Report struct {
 	CorruptedReason string
 	// Maintainers is list of maintainer emails (filled in by Symbolize).
 	Maintainers []string
+	// Stack is normalized stack signature of the first stack trace in the report:
+	// names of the top functions with uninteresting frames (e.g. kasan/lockdep internals) removed.
+	// Reports with different titles, but similar stacks are possibly duplicates (see package stack).
+	Stack []string
+	// Details contains structured information parsed from the report,
+	// nil if the report format is not recognized (see Details for supported formats).
+	Details *Details
+	// Labels are attached by user-defined crash rules (see mgrconfig.CrashRule).
+	Labels []string
+	// Severity is one of mgrconfig.CrashSeverities (low, medium, high, severe, critical).
+	// It's derived from the report (see severityRules), but can be overridden by crash rules.
+	Severity string
+	// ruleMaintainers are additional maintainers from crash rules, added to Maintainers by Symbolize.
+	ruleMaintainers []string
 	// guiltyFile is the source file that we think is to blame for the crash  (filled in by Symbolize).
 	guiltyFile string
 	// reportPrefixLen is length of additional prefix lines that we added before actual crash report.
 	reportPrefixLen int
 }
 
+// Details is structured information extracted from sanitizer/lockdep reports.
+// Currently filled only for linux KASAN, KMSAN, UBSAN and lockdep reports.
+// Fields that are not present in a particular report are left empty.
+type Details struct {
+	// Tool is the bug detector that produced the report (KASAN, KMSAN, UBSAN, LOCKDEP).
+	Tool string
+	// BugType is the bug class within the tool
+	// (e.g. use-after-free, uninit-value, shift-out-of-bounds, circular-locking).
+	BugType string
+	// Access is the bad access type (read or write).
+	Access string
+	// AccessSize is the bad access size in bytes.
+	AccessSize int
+	// Address is the bad access address.
+	Address uint64
+	// Task is the name of the task that triggered the bug and PID is its pid.
+	Task string
+	PID  int
+	// CallTrace contains function names of the main stack trace (top frame first).
+	CallTrace []string
+	// AllocStack/FreeStack are stacks where the accessed object was allocated/freed
+	// (for KMSAN AllocStack is where the uninit value was created).
+	AllocStack []string
+	FreeStack  []string
+	// LockChain contains names of the locks involved in a lockdep report.
+	LockChain []string
+}
+
 // NewReporter creates reporter for the specified OS/Type.
 func NewReporter(cfg *mgrconfig.Config) (Reporter, error) {
 	typ := cfg.TargetOS
@@ -78,7 +122,22 @@ func NewReporter(cfg *mgrconfig.Config) (Reporter, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &reporterWrapper{rep, supps, typ}, nil
+	rules, err := compileCrashRules(cfg.CrashRules)
+	if err != nil {
+		return nil, err
+	}
+	params := stackSignatureParams[typ]
+	if params == nil {
+		params = genericStackParams
+	}
+	return &reporterWrapper{
+		Reporter:     rep,
+		suppressions: supps,
+		typ:          typ,
+		stackParams:  params,
+		stackSkipRe:  compileSkipPatterns(params.skipPatterns),
+		rules:        rules,
+	}, nil
 }
 
 const UnexpectedKernelReboot = "unexpected kernel reboot"
@@ -112,6 +171,9 @@ type reporterWrapper struct {
 	Reporter
 	suppressions []*regexp.Regexp
 	typ          string
+	stackParams  *stackParams
+	stackSkipRe  *regexp.Regexp // compiled stackParams.skipPatterns
+	rules        []*crashRule
 }
 
 func (wrap *reporterWrapper) Parse(output []byte) *Report {
@@ -121,9 +183,108 @@ func (wrap *reporterWrapper) Parse(output []byte) *Report {
 	}
 	rep.Title = sanitizeTitle(replaceTable(dynamicTitleReplacement, rep.Title))
 	rep.Suppressed = matchesAny(rep.Output, wrap.suppressions)
+	rep.Stack = extractStackSignature(wrap.stackParams, wrap.stackSkipRe, rep.Report)
+	rep.Severity = classifySeverity(rep)
+	applyCrashRules(wrap.rules, rep)
 	return rep
 }
 
+func (wrap *reporterWrapper) Symbolize(rep *Report) error {
+	err := wrap.Reporter.Symbolize(rep)
+	for _, email := range rep.ruleMaintainers {
+		rep.Maintainers = appendUnique(rep.Maintainers, email)
+	}
+	return err
+}
+
+// severityRules classify crashes by exploitability, first matching rule wins.
+// Roughly: memory corruption writes > use-after-free reads > other bad reads/wild accesses >
+// WARNINGs/BUGs > hangs/leaks.
+var severityRules = []struct {
+	re       *regexp.Regexp
+	severity string
+}{
+	{
+		regexp.MustCompile(`KASAN: .* Write|double-free|invalid-free|slab corruption|stack[- ]protector`),
+		"critical",
+	},
+	{
+		regexp.MustCompile(`use-after-free`),
+		"severe",
+	},
+	{
+		regexp.MustCompile(`KASAN:|KMSAN:|out-of-bounds|general protection fault|` +
+			`unable to handle kernel|Fatal trap|page fault|null-ptr-deref|wild-memory-access`),
+		"high",
+	},
+	{
+		regexp.MustCompile(`task hung|blocked for more than|detected stall|lost connection|` +
+			`no output|memory leak|unexpected kernel reboot|timed out`),
+		"low",
+	},
+}
+
+// classifySeverity returns severity of the crash based on title and parsed details.
+func classifySeverity(rep *Report) string {
+	if d := rep.Details; d != nil && d.Tool == "KASAN" && d.Access == "write" {
+		return "critical"
+	}
+	for _, rule := range severityRules {
+		if rule.re.MatchString(rep.Title) {
+			return rule.severity
+		}
+	}
+	return "medium"
+}
+
+type crashRule struct {
+	match       *regexp.Regexp
+	title       string
+	labels      []string
+	severity    string
+	maintainers []string
+}
+
+func compileCrashRules(rules []mgrconfig.CrashRule) ([]*crashRule, error) {
+	var compiled []*crashRule
+	for i, rule := range rules {
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile crash rule %v %q: %v", i, rule.Match, err)
+		}
+		compiled = append(compiled, &crashRule{
+			match:       re,
+			title:       rule.Title,
+			labels:      rule.Labels,
+			severity:    rule.Severity,
+			maintainers: rule.Maintainers,
+		})
+	}
+	return compiled, nil
+}
+
+func applyCrashRules(rules []*crashRule, rep *Report) {
+	for _, rule := range rules {
+		match := rule.match.FindStringSubmatchIndex(rep.Title)
+		if match == nil {
+			continue
+		}
+		if rule.title != "" {
+			title := rule.match.ExpandString(nil, rule.title, rep.Title, match)
+			if title := sanitizeTitle(string(title)); title != "" {
+				rep.Title = title
+			}
+		}
+		for _, label := range rule.labels {
+			rep.Labels = appendUnique(rep.Labels, label)
+		}
+		if rule.severity != "" {
+			rep.Severity = rule.severity
+		}
+		rep.ruleMaintainers = append(rep.ruleMaintainers, rule.maintainers...)
+	}
+}
+
 func IsSuppressed(reporter Reporter, output []byte) bool {
 	return matchesAny(output, reporter.(*reporterWrapper).suppressions)
 }
@@ -364,13 +525,97 @@ type stackParams struct {
 	corruptedLines []*regexp.Regexp
 }
 
-func extractStackFrame(params *stackParams, stack *stackFmt, output []byte) (string, string) {
+// stackSignatureParams are used to extract Report.Stack for the given OS/type.
+var stackSignatureParams = map[string]*stackParams{
+	"linux":   linuxStackParams,
+	"akaros":  akarosStackParams,
+	"fuchsia": zirconStackParams,
+	"netbsd":  netbsdStackParams,
+}
+
+// genericStackParams are used to extract Report.Stack for types without own stack params.
+// Most kernels print frames as func+0xoff/0xsize or func+0xoff.
+var genericStackParams = &stackParams{
+	frameRes: []*regexp.Regexp{
+		compile(`(?:^|[ <])([a-zA-Z_][a-zA-Z0-9_]*)\+0x[0-9a-f]+`),
+	},
+}
+
+func extractStackSignature(params *stackParams, skipRe *regexp.Regexp, report []byte) []string {
+	// If the OS has stack start markers, frames are extracted only from the first stack trace.
+	inStack := len(params.stackStartRes) == 0
+	var frames []string
+	s := bufio.NewScanner(bytes.NewReader(report))
+	for s.Scan() && len(frames) < stack.MaxFrames {
+		ln := bytes.Trim(s.Bytes(), "\r")
+		if matchesAny(ln, params.stackStartRes) {
+			if len(frames) != 0 {
+				break
+			}
+			inStack = true
+			continue
+		}
+		if !inStack || matchesAny(ln, params.corruptedLines) {
+			continue
+		}
+		for _, re := range params.frameRes {
+			match := re.FindSubmatch(ln)
+			if match == nil {
+				continue
+			}
+			frame := string(match[1])
+			if skipRe != nil && skipRe.MatchString(frame) {
+				break
+			}
+			// Recursion and duplicate frames (e.g. in fuchsia inline frames) don't add any info.
+			if len(frames) == 0 || frames[len(frames)-1] != frame {
+				frames = append(frames, frame)
+			}
+			break
+		}
+	}
+	return frames
+}
+
+// compileSkipPatterns returns a single regexp matching any of the patterns, or nil if there are none.
+func compileSkipPatterns(patterns []string) *regexp.Regexp {
+	if len(patterns) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(strings.Join(patterns, "|"))
+}
+
+// stackSkipCache holds compiled skip regexps for (params, stack) pairs.
+// Both are static tables, so every pair is compiled once per process rather than per report.
+var stackSkipCache struct {
+	sync.Mutex
+	entries map[stackSkipKey]*regexp.Regexp
+}
+
+type stackSkipKey struct {
+	params *stackParams
+	stack  *stackFmt
+}
+
+func stackSkipRe(params *stackParams, stack *stackFmt) *regexp.Regexp {
+	key := stackSkipKey{params, stack}
+	stackSkipCache.Lock()
+	defer stackSkipCache.Unlock()
+	if re, ok := stackSkipCache.entries[key]; ok {
+		return re
+	}
+	if stackSkipCache.entries == nil {
+		stackSkipCache.entries = make(map[stackSkipKey]*regexp.Regexp)
+	}
 	skip := append([]string{}, params.skipPatterns...)
 	skip = append(skip, stack.skip...)
-	var skipRe *regexp.Regexp
-	if len(skip) != 0 {
-		skipRe = regexp.MustCompile(strings.Join(skip, "|"))
-	}
+	re := compileSkipPatterns(skip)
+	stackSkipCache.entries[key] = re
+	return re
+}
+
+func extractStackFrame(params *stackParams, stack *stackFmt, output []byte) (string, string) {
+	skipRe := stackSkipRe(params, stack)
 	extractor := stack.extractor
 	if extractor == nil {
 		extractor = func(frames []string) (string, string) {
@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
@@ -327,6 +328,37 @@ func TestReplace(t *testing.T) {
 	}
 }
 
+// TestParseCorpus checks that every reporter has a parse test corpus in testdata/<os>/report.
+func TestParseCorpus(t *testing.T) {
+	testFilenameRe := regexp.MustCompile("^[0-9]+$")
+	for os := range ctors {
+		if os == "windows" {
+			continue // stub reporter
+		}
+		files, _ := ioutil.ReadDir(filepath.Join("testdata", os, "report"))
+		numTests := 0
+		for _, file := range files {
+			if testFilenameRe.MatchString(file.Name()) {
+				numTests++
+			}
+		}
+		if numTests == 0 {
+			t.Errorf("no parse tests for %v in testdata/%v/report", os, os)
+		}
+	}
+}
+
+// TestFuzzCorpus checks Fuzz invariants on logs from the parse test corpus of all OSes.
+func TestFuzzCorpus(t *testing.T) {
+	forEachFile(t, "report", func(t *testing.T, reporter Reporter, fn string) {
+		data, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Fuzz(data)
+	})
+}
+
 func TestFuzz(t *testing.T) {
 	for _, data := range []string{
 		"kernel panicType 'help' for a list of commands",
@@ -341,3 +373,83 @@ func TestFuzz(t *testing.T) {
 		Fuzz([]byte(data))
 	}
 }
+
+func TestCrashRules(t *testing.T) {
+	cfg := &mgrconfig.Config{
+		TargetOS:   "linux",
+		TargetArch: "amd64",
+		CrashRules: []mgrconfig.CrashRule{
+			{
+				Match: `^WARNING in (foo|bar)_ioctl$`,
+				Title: "WARNING in ${1}_ioctl (merged)",
+			},
+			{
+				Match:       `\(merged\)`,
+				Labels:      []string{"harmless"},
+				Severity:    "low",
+				Maintainers: []string{"team@example.com"},
+			},
+			{
+				Match:  `ioctl`,
+				Labels: []string{"harmless", "ioctl"},
+			},
+		},
+	}
+	reporter, err := NewReporter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const log = `
+[   31.531419] WARNING: CPU: 0 PID: 4061 at drivers/foo/foo.c:10 foo_ioctl+0x10/0x20
+[   31.532114] Kernel panic - not syncing: panic_on_warn set ...
+`
+	rep := reporter.Parse([]byte(log))
+	if rep == nil {
+		t.Fatalf("no crash")
+	}
+	if want := "WARNING in foo_ioctl (merged)"; rep.Title != want {
+		t.Errorf("got title %q, want %q", rep.Title, want)
+	}
+	if want := []string{"harmless", "ioctl"}; !reflect.DeepEqual(rep.Labels, want) {
+		t.Errorf("got labels %q, want %q", rep.Labels, want)
+	}
+	if rep.Severity != "low" {
+		t.Errorf("got severity %q, want low", rep.Severity)
+	}
+	reporter.Symbolize(rep)
+	if want := []string{"team@example.com"}; !reflect.DeepEqual(rep.Maintainers, want) {
+		t.Errorf("got maintainers %q, want %q", rep.Maintainers, want)
+	}
+}
+
+func TestClassifySeverity(t *testing.T) {
+	tests := []struct {
+		rep      *Report
+		severity string
+	}{
+		{&Report{Title: "KASAN: use-after-free Write in foo"}, "critical"},
+		{&Report{Title: "KASAN: double-free or invalid-free in foo"}, "critical"},
+		{&Report{Title: "KASAN: use-after-free Read in foo"}, "severe"},
+		{&Report{Title: "KASAN: slab-out-of-bounds Read in foo"}, "high"},
+		{&Report{Title: "general protection fault in foo"}, "high"},
+		{&Report{Title: "KMSAN: uninit-value in foo"}, "high"},
+		{&Report{Title: "WARNING in foo"}, "medium"},
+		{&Report{Title: "possible deadlock in foo"}, "medium"},
+		{&Report{Title: "INFO: task hung in foo"}, "low"},
+		{&Report{Title: "INFO: rcu detected stall in foo"}, "low"},
+		{&Report{Title: "memory leak in foo"}, "low"},
+		{&Report{Title: "lost connection to test machine"}, "low"},
+		{
+			&Report{
+				Title:   "KASAN: use-after-free in foo",
+				Details: &Details{Tool: "KASAN", Access: "write"},
+			},
+			"critical",
+		},
+	}
+	for _, test := range tests {
+		if got := classifySeverity(test.rep); got != test.severity {
+			t.Errorf("%q: got severity %v, want %v", test.rep.Title, got, test.severity)
+		}
+	}
+}
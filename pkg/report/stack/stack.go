@@ -0,0 +1,71 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// Package stack compares stack signatures of crash reports (see report.Report.Stack).
+// It is separate from pkg/report to be usable without pulling all of report dependencies
+// (e.g. in dashboard app).
+package stack
+
+// Threshold is the similarity above which two crashes are considered possible duplicates.
+const Threshold = 0.75
+
+// MaxFrames is the max number of frames in a stack signature.
+const MaxFrames = 10
+
+// Similarity returns similarity of two stack signatures in the range [0, 1].
+// The similarity is based on the longest common subsequence of frames,
+// so that additional/missing frames (e.g. due to different inlining) affect it only slightly.
+// Top frames are more important than bottom ones (bottom frames are frequently common syscall entry code),
+// so each frame has weight inversely proportional to its depth.
+// Empty signatures are not similar to anything.
+func Similarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	weight := func(i int) float64 {
+		return 1 / float64(i+1)
+	}
+	// lcs[i][j] is the max weight of common subsequence of a[i:] and b[j:].
+	lcs := make([][]float64, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]float64, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			best := lcs[i+1][j]
+			if lcs[i][j+1] > best {
+				best = lcs[i][j+1]
+			}
+			if a[i] == b[j] {
+				if w := lcs[i+1][j+1] + (weight(i)+weight(j))/2; w > best {
+					best = w
+				}
+			}
+			lcs[i][j] = best
+		}
+	}
+	total := 0.0
+	for i := range a {
+		total += weight(i) / 2
+	}
+	for j := range b {
+		total += weight(j) / 2
+	}
+	return lcs[0][0] / total
+}
+
+// Similar says if two stack signatures are similar enough to be possible duplicates.
+func Similar(a, b []string) bool {
+	return Similarity(a, b) >= Threshold
+}
+
+// KeyFrames returns frames of sig such that any signature similar to sig contains at least one of them.
+// This allows to look up only candidates for similarity by frames rather than compare with all signatures.
+// Holds for Threshold and signatures of up to MaxFrames frames: if neither of the top 2 frames match,
+// similarity is at most 0.734.
+func KeyFrames(sig []string) []string {
+	if len(sig) > 2 {
+		return sig[:2]
+	}
+	return sig
+}
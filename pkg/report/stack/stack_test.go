@@ -0,0 +1,78 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package stack
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b    []string
+		similar bool
+	}{
+		{
+			a:       []string{"foo", "bar", "baz"},
+			b:       []string{"foo", "bar", "baz"},
+			similar: true,
+		},
+		{
+			// Inlined frame is present only in one of the stacks.
+			a:       []string{"foo", "bar", "baz", "do_syscall_64"},
+			b:       []string{"foo", "baz", "do_syscall_64"},
+			similar: true,
+		},
+		{
+			// Guilty frame differs.
+			a:       []string{"foo", "bar", "baz", "do_syscall_64"},
+			b:       []string{"qux", "bar", "baz", "do_syscall_64"},
+			similar: false,
+		},
+		{
+			// Only the common syscall entry is the same.
+			a:       []string{"foo", "bar", "do_syscall_64"},
+			b:       []string{"qux", "quux", "do_syscall_64"},
+			similar: false,
+		},
+		{
+			a:       nil,
+			b:       nil,
+			similar: false,
+		},
+	}
+	for i, test := range tests {
+		sim := Similarity(test.a, test.b)
+		if sim < 0 || sim > 1 {
+			t.Errorf("#%v: similarity %v is out of range", i, sim)
+		}
+		if sim1 := Similarity(test.b, test.a); sim != sim1 {
+			t.Errorf("#%v: similarity is not symmetric: %v vs %v", i, sim, sim1)
+		}
+		if similar := Similar(test.a, test.b); similar != test.similar {
+			t.Errorf("#%v: similar=%v (%v), want %v", i, similar, sim, test.similar)
+		}
+	}
+}
+
+func TestKeyFrames(t *testing.T) {
+	// The best case for a signature that does not contain key frames of a is
+	// when it consists of the remaining frames of a moved to the top.
+	for n := 1; n <= MaxFrames; n++ {
+		var a []string
+		for i := 0; i < n; i++ {
+			a = append(a, fmt.Sprintf("frame%v", i))
+		}
+		key := KeyFrames(a)
+		rest := a[len(key):]
+		for k := 1; k <= len(rest); k++ {
+			for _, b := range [][]string{rest[:k], rest[len(rest)-k:]} {
+				if Similar(a, b) {
+					t.Errorf("%q is similar to %q (%v), but contains none of key frames %q",
+						b, a, Similarity(a, b), key)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package repro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/google/syzkaller/pkg/csource"
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/prog"
+)
+
+// Reproduction phases in the order of execution.
+const (
+	phaseExtractProg = iota
+	phaseMinimizeProg
+	phaseExtractC
+	phaseSimplifyProg
+	phaseSimplifyC
+)
+
+// checkpoint is the persistent state of reproduction that allows to resume it after a restart.
+type checkpoint struct {
+	// Crash log that is being reproduced, entries below refer to programs in this log.
+	Log   []byte
+	Phase int
+	// Progress of phaseExtractProg.
+	Timeout      int     // index of the current timeout in extractTimeouts
+	SingleDone   bool    // single programs were already tested with the current timeout
+	ParallelDone bool    // concurrent execution was already tested with the current timeout
+	Guilty       [][]int // current guilty chunks of bisection (indices of entries)
+	// Current result (phaseMinimizeProg and later).
+	Prog     []byte
+	Parallel [][]byte
+	Duration time.Duration
+	Opts     csource.Options
+	CRepro   bool
+	Simplify int // index of the next simplification to try in the current phase
+	Stats    *Stats
+}
+
+func loadCheckpoint(file string) (*checkpoint, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cp := new(checkpoint)
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("failed to parse repro checkpoint %v: %v", file, err)
+	}
+	if len(cp.Log) == 0 || cp.Stats == nil {
+		return nil, fmt.Errorf("corrupted repro checkpoint %v", file)
+	}
+	return cp, nil
+}
+
+// CheckpointLog returns crash log of the reproduction saved in the checkpoint file,
+// or nil if there is no valid checkpoint.
+func CheckpointLog(file string) []byte {
+	cp, err := loadCheckpoint(file)
+	if err != nil {
+		return nil
+	}
+	return cp.Log
+}
+
+// saveCheckpoint persists the current checkpoint state (if checkpointing is enabled).
+func (ctx *context) saveCheckpoint() {
+	if ctx.checkpointFile == "" {
+		return
+	}
+	ctx.cp.Stats = ctx.stats
+	data, err := json.Marshal(ctx.cp)
+	if err != nil {
+		ctx.reproLog(0, "failed to serialize checkpoint: %v", err)
+		return
+	}
+	tmpFile := ctx.checkpointFile + ".tmp"
+	if err := osutil.WriteFile(tmpFile, data); err != nil {
+		ctx.reproLog(0, "failed to write checkpoint: %v", err)
+		return
+	}
+	if err := os.Rename(tmpFile, ctx.checkpointFile); err != nil {
+		ctx.reproLog(0, "failed to write checkpoint: %v", err)
+	}
+}
+
+// saveResult records that res is the result of all phases before phase.
+func (ctx *context) saveResult(phase int, res *Result) {
+	ctx.cp.Phase = phase
+	ctx.cp.Guilty = nil
+	ctx.cp.Prog = res.Prog.Serialize()
+	ctx.cp.Parallel = nil
+	for _, p := range res.Parallel {
+		ctx.cp.Parallel = append(ctx.cp.Parallel, p.Serialize())
+	}
+	ctx.cp.Duration = res.Duration
+	ctx.cp.Opts = res.Opts
+	ctx.cp.CRepro = res.CRepro
+	ctx.cp.Simplify = 0
+	ctx.saveCheckpoint()
+}
+
+// saveSimplify records that the current simplification step is done and res is its result.
+func (ctx *context) saveSimplify(res *Result) {
+	ctx.cp.Simplify++
+	ctx.cp.Opts = res.Opts
+	ctx.cp.CRepro = res.CRepro
+	ctx.saveCheckpoint()
+}
+
+// checkpointResult restores the current result from the checkpoint.
+func (ctx *context) checkpointResult(target *prog.Target) (*Result, error) {
+	p, err := target.Deserialize(ctx.cp.Prog, prog.NonStrict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize checkpoint program: %v", err)
+	}
+	res := &Result{
+		Prog:     p,
+		Duration: ctx.cp.Duration,
+		Opts:     ctx.cp.Opts,
+		CRepro:   ctx.cp.CRepro,
+	}
+	for _, data := range ctx.cp.Parallel {
+		p, err := target.Deserialize(data, prog.NonStrict)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deserialize checkpoint program: %v", err)
+		}
+		res.Parallel = append(res.Parallel, p)
+	}
+	return res, nil
+}
+
+// saveBisect records current guilty chunks of bisectProgs.
+func (ctx *context) saveBisect(guilty [][]*prog.LogEntry) {
+	if ctx.entryIndex == nil {
+		return
+	}
+	ctx.cp.Guilty = nil
+	for _, chunk := range guilty {
+		var indices []int
+		for _, ent := range chunk {
+			indices = append(indices, ctx.entryIndex[ent])
+		}
+		ctx.cp.Guilty = append(ctx.cp.Guilty, indices)
+	}
+	ctx.saveCheckpoint()
+}
+
+// restoreBisect returns guilty chunks of an interrupted bisection, or nil.
+func (ctx *context) restoreBisect() [][]*prog.LogEntry {
+	if ctx.cp == nil || len(ctx.cp.Guilty) == 0 {
+		return nil
+	}
+	var guilty [][]*prog.LogEntry
+	for _, indices := range ctx.cp.Guilty {
+		var chunk []*prog.LogEntry
+		for _, idx := range indices {
+			if idx < 0 || idx >= len(ctx.entries) {
+				ctx.reproLog(0, "bad checkpoint bisection state, starting from scratch")
+				return nil
+			}
+			chunk = append(chunk, ctx.entries[idx])
+		}
+		guilty = append(guilty, chunk)
+	}
+	return guilty
+}
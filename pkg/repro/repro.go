@@ -6,8 +6,10 @@ package repro
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,7 +24,10 @@ import (
 )
 
 type Result struct {
-	Prog     *prog.Prog
+	Prog *prog.Prog
+	// Parallel contains additional programs that need to be executed concurrently with Prog
+	// (each in a separate process) to reproduce the crash. Empty for single-program reproducers.
+	Parallel []*prog.Prog
 	Duration time.Duration
 	Opts     csource.Options
 	CRepro   bool
@@ -31,13 +36,73 @@ type Result struct {
 	Report *report.Report
 }
 
+// Progs returns all programs of the reproducer (Prog followed by Parallel).
+func (res *Result) Progs() []*prog.Prog {
+	return append([]*prog.Prog{res.Prog}, res.Parallel...)
+}
+
+// Serialize returns the reproducer in the syzkaller program format.
+// Multi-program reproducers are serialized as an execution log with one program per proc,
+// such log can be executed with syz-execprog -parallel.
+func (res *Result) Serialize() []byte {
+	if len(res.Parallel) == 0 {
+		return res.Prog.Serialize()
+	}
+	return encodeEntries(parallelEntries(res.Progs()))
+}
+
+// CSources returns formatted C sources for all programs of the reproducer (in the order of Progs).
+// For multi-program reproducers all resulting binaries need to be run concurrently.
+func (res *Result) CSources() ([][]byte, error) {
+	var srcs [][]byte
+	for _, p := range res.Progs() {
+		src, err := csource.Write(p, res.Opts)
+		if err != nil {
+			return nil, err
+		}
+		if formatted, err := csource.Format(src); err == nil {
+			src = formatted
+		}
+		srcs = append(srcs, src)
+	}
+	return srcs, nil
+}
+
 type Stats struct {
 	Log              []byte
 	ExtractProgTime  time.Duration
+	ParallelTime     time.Duration
 	MinimizeProgTime time.Duration
 	SimplifyProgTime time.Duration
 	ExtractCTime     time.Duration
 	SimplifyCTime    time.Duration
+	ReliabilityTime  time.Duration
+	// Reliability of the final syz and C reproducers (zero if not measured).
+	SyzReliability Reliability
+	CReliability   Reliability
+}
+
+// Reliability is the result of re-running a reproducer several times.
+type Reliability struct {
+	Runs            int
+	Crashes         int
+	MeanTimeToCrash time.Duration // over the runs that crashed
+}
+
+// Rate returns fraction of runs that crashed.
+func (r Reliability) Rate() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	return float64(r.Crashes) / float64(r.Runs)
+}
+
+func (r Reliability) String() string {
+	if r.Runs == 0 {
+		return "not measured"
+	}
+	return fmt.Sprintf("%v/%v runs crashed (%.0f%%), mean time to crash %v",
+		r.Crashes, r.Runs, r.Rate()*100, r.MeanTimeToCrash)
 }
 
 type context struct {
@@ -48,6 +113,13 @@ type context struct {
 	bootRequests chan int
 	stats        *Stats
 	report       *report.Report
+	crashTime    time.Duration // time from start of the last crashed test run till the crash
+	progress     io.Writer
+
+	checkpointFile string
+	cp             *checkpoint
+	entries        []*prog.LogEntry // programs from the crash log that executed before the crash
+	entryIndex     map[*prog.LogEntry]int
 }
 
 type instance struct {
@@ -57,8 +129,13 @@ type instance struct {
 	executorBin string
 }
 
+// Run reproduces the crash in crashLog using VMs with vmIndexes from vmPool.
+// If checkpointFile is not empty, progress is saved to that file, and if the file
+// already contains a checkpoint, reproduction is resumed from it (crashLog is ignored then).
+// The checkpoint is removed once reproduction finishes.
+// If progress is not nil, reproduction log is written to it as reproduction goes.
 func Run(crashLog []byte, cfg *mgrconfig.Config, reporter report.Reporter, vmPool *vm.Pool,
-	vmIndexes []int) (*Result, *Stats, error) {
+	vmIndexes []int, checkpointFile string, progress io.Writer) (*Result, *Stats, error) {
 	if len(vmIndexes) == 0 {
 		return nil, nil, fmt.Errorf("no VMs provided")
 	}
@@ -66,6 +143,16 @@ func Run(crashLog []byte, cfg *mgrconfig.Config, reporter report.Reporter, vmPoo
 	if err != nil {
 		return nil, nil, err
 	}
+	cp := &checkpoint{Log: crashLog, Stats: new(Stats)}
+	resumed := false
+	if checkpointFile != "" {
+		if cp1, err := loadCheckpoint(checkpointFile); err == nil {
+			cp, resumed = cp1, true
+			crashLog = cp.Log
+		} else if !os.IsNotExist(err) {
+			log.Logf(0, "%v", err)
+		}
+	}
 	entries := target.ParseLog(crashLog)
 	if len(entries) == 0 {
 		return nil, nil, fmt.Errorf("crash log does not contain any programs")
@@ -78,14 +165,23 @@ func Run(crashLog []byte, cfg *mgrconfig.Config, reporter report.Reporter, vmPoo
 	}
 
 	ctx := &context{
-		cfg:          cfg,
-		reporter:     reporter,
-		crashTitle:   crashTitle,
-		instances:    make(chan *instance, len(vmIndexes)),
-		bootRequests: make(chan int, len(vmIndexes)),
-		stats:        new(Stats),
+		cfg:            cfg,
+		reporter:       reporter,
+		crashTitle:     crashTitle,
+		instances:      make(chan *instance, len(vmIndexes)),
+		bootRequests:   make(chan int, len(vmIndexes)),
+		stats:          cp.Stats,
+		progress:       progress,
+		checkpointFile: checkpointFile,
+		cp:             cp,
 	}
 	ctx.reproLog(0, "%v programs, %v VMs", len(entries), len(vmIndexes))
+	if resumed {
+		if progress != nil {
+			progress.Write(cp.Stats.Log)
+		}
+		ctx.reproLog(0, "resuming from checkpoint %v (phase %v)", checkpointFile, cp.Phase)
+	}
 	var wg sync.WaitGroup
 	wg.Add(len(vmIndexes))
 	for _, vmIndex := range vmIndexes {
@@ -143,28 +239,45 @@ func Run(crashLog []byte, cfg *mgrconfig.Config, reporter report.Reporter, vmPoo
 		close(ctx.instances)
 	}()
 
-	res, err := ctx.repro(entries, crashStart)
+	res, err := ctx.repro(target, entries, crashStart)
 	if err != nil {
 		return nil, nil, err
 	}
+	if res != nil && ctx.report == nil {
+		// Reproduction was resumed from a checkpoint after the last crashing run.
+		ctx.reproLog(3, "running resumed repro to obtain crash report")
+		if _, err := ctx.testResult(res); err != nil {
+			return nil, nil, err
+		}
+		if ctx.report == nil {
+			ctx.reproLog(0, "resumed repro did not crash")
+			res = nil
+		}
+	}
 	if res != nil {
 		ctx.reproLog(3, "repro crashed as (corrupted=%v):\n%s",
 			ctx.report.Corrupted, ctx.report.Report)
 		// Try to rerun the repro if the report is corrupted.
 		for attempts := 0; ctx.report.Corrupted && attempts < 3; attempts++ {
 			ctx.reproLog(3, "report is corrupted, running repro again")
-			if res.CRepro {
-				_, err = ctx.testCProg(res.Prog, res.Duration, res.Opts)
-			} else {
-				_, err = ctx.testProg(res.Prog, res.Duration, res.Opts)
-			}
-			if err != nil {
+			if _, err := ctx.testResult(res); err != nil {
 				return nil, nil, err
 			}
 		}
 		ctx.reproLog(3, "final repro crashed as (corrupted=%v):\n%s",
 			ctx.report.Corrupted, ctx.report.Report)
 		res.Report = ctx.report
+		if cfg.ReproReliabilityRuns > 0 {
+			if err := ctx.measureReliability(res, cfg.ReproReliabilityRuns); err != nil {
+				// The reproducer itself is fine, so don't lose it.
+				ctx.reproLog(0, "failed to measure reliability: %v", err)
+				ctx.stats.SyzReliability = Reliability{}
+				ctx.stats.CReliability = Reliability{}
+			}
+		}
+	}
+	if checkpointFile != "" {
+		os.Remove(checkpointFile)
 	}
 
 	close(ctx.bootRequests)
@@ -174,7 +287,7 @@ func Run(crashLog []byte, cfg *mgrconfig.Config, reporter report.Reporter, vmPoo
 	return res, ctx.stats, nil
 }
 
-func (ctx *context) repro(entries []*prog.LogEntry, crashStart int) (*Result, error) {
+func (ctx *context) repro(target *prog.Target, entries []*prog.LogEntry, crashStart int) (*Result, error) {
 	// Cut programs that were executed after crash.
 	for i, ent := range entries {
 		if ent.Start > crashStart {
@@ -182,41 +295,65 @@ func (ctx *context) repro(entries []*prog.LogEntry, crashStart int) (*Result, er
 			break
 		}
 	}
+	ctx.entries = append([]*prog.LogEntry{}, entries...)
+	ctx.entryIndex = make(map[*prog.LogEntry]int)
+	for i, ent := range entries {
+		ctx.entryIndex[ent] = i
+	}
 
 	reproStart := time.Now()
 	defer func() {
 		ctx.reproLog(3, "reproducing took %s", time.Since(reproStart))
 	}()
 
-	res, err := ctx.extractProg(entries)
-	if err != nil {
-		return nil, err
-	}
-	if res == nil {
-		return nil, nil
+	var res *Result
+	var err error
+	if ctx.cp.Phase == phaseExtractProg {
+		res, err = ctx.extractProg(entries)
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			return nil, nil
+		}
+		ctx.saveResult(phaseMinimizeProg, res)
+	} else {
+		res, err = ctx.checkpointResult(target)
+		if err != nil {
+			return nil, err
+		}
 	}
 	defer func() {
 		if res != nil {
 			res.Opts.Repro = false
 		}
 	}()
-	res, err = ctx.minimizeProg(res)
-	if err != nil {
-		return nil, err
+	if ctx.cp.Phase == phaseMinimizeProg {
+		res, err = ctx.minimizeProg(res)
+		if err != nil {
+			return nil, err
+		}
+		ctx.saveResult(phaseExtractC, res)
 	}
 
 	// Try extracting C repro without simplifying options first.
-	res, err = ctx.extractC(res)
-	if err != nil {
-		return nil, err
+	if ctx.cp.Phase == phaseExtractC {
+		res, err = ctx.extractC(res)
+		if err != nil {
+			return nil, err
+		}
+		ctx.saveResult(phaseSimplifyProg, res)
 	}
 
 	// Simplify options and try extracting C repro.
-	if !res.CRepro {
-		res, err = ctx.simplifyProg(res)
-		if err != nil {
-			return nil, err
+	if ctx.cp.Phase == phaseSimplifyProg {
+		if !res.CRepro {
+			res, err = ctx.simplifyProg(res)
+			if err != nil {
+				return nil, err
+			}
 		}
+		ctx.saveResult(phaseSimplifyC, res)
 	}
 
 	// Simplify C related options.
@@ -234,7 +371,7 @@ func (ctx *context) extractProg(entries []*prog.LogEntry) (*Result, error) {
 	ctx.reproLog(2, "extracting reproducer from %v programs", len(entries))
 	start := time.Now()
 	defer func() {
-		ctx.stats.ExtractProgTime = time.Since(start)
+		ctx.stats.ExtractProgTime += time.Since(start)
 	}()
 
 	// Extract last program on every proc.
@@ -252,43 +389,65 @@ func (ctx *context) extractProg(entries []*prog.LogEntry) (*Result, error) {
 		lastEntries = append(lastEntries, entries[indices[i]])
 	}
 
-	// The shortest duration is 10 seconds to detect simple crashes (i.e. no races and no hangs).
-	// The longest duration is 5 minutes to catch races and hangs. Note that this value must be larger
-	// than hang/no output detection duration in vm.MonitorExecution, which is currently set to 3 mins.
-	timeouts := []time.Duration{10 * time.Second, 1 * time.Minute, 5 * time.Minute}
-
-	for _, timeout := range timeouts {
-		// Execute each program separately to detect simple crashes caused by a single program.
-		// Programs are executed in reverse order, usually the last program is the guilty one.
-		res, err := ctx.extractProgSingle(reverseEntries(lastEntries), timeout)
-		if err != nil {
-			return nil, err
-		}
-		if res != nil {
-			ctx.reproLog(3, "found reproducer with %d syscalls", len(res.Prog.Calls))
-			return res, nil
+	for ; ctx.cp.Timeout < len(extractTimeouts); ctx.cp.Timeout++ {
+		timeout := extractTimeouts[ctx.cp.Timeout]
+		if !ctx.cp.SingleDone {
+			// Execute each program separately to detect simple crashes caused by a single program.
+			// Programs are executed in reverse order, usually the last program is the guilty one.
+			res, err := ctx.extractProgSingle(reverseEntries(lastEntries), timeout)
+			if err != nil {
+				return nil, err
+			}
+			if res != nil {
+				ctx.reproLog(3, "found reproducer with %d syscalls", len(res.Prog.Calls))
+				return res, nil
+			}
+			ctx.cp.SingleDone = true
+			ctx.saveCheckpoint()
 		}
 
-		// Don't try bisecting if there's only one entry.
-		if len(entries) == 1 {
-			continue
+		// Races frequently require several programs running concurrently in different procs.
+		if len(lastEntries) > 1 && !ctx.cp.ParallelDone {
+			res, err := ctx.extractProgParallel(reverseEntries(lastEntries), timeout)
+			if err != nil {
+				return nil, err
+			}
+			if res != nil {
+				ctx.reproLog(3, "found reproducer with %d programs", len(res.Progs()))
+				return res, nil
+			}
+			ctx.cp.ParallelDone = true
+			ctx.cp.Guilty = nil
+			ctx.saveCheckpoint()
 		}
 
-		// Execute all programs and bisect the log to find multiple guilty programs.
-		res, err = ctx.extractProgBisect(reverseEntries(entries), timeout)
-		if err != nil {
-			return nil, err
-		}
-		if res != nil {
-			ctx.reproLog(3, "found reproducer with %d syscalls", len(res.Prog.Calls))
-			return res, nil
+		// Don't try bisecting if there's only one entry.
+		if len(entries) != 1 {
+			// Execute all programs and bisect the log to find multiple guilty programs.
+			res, err := ctx.extractProgBisect(reverseEntries(entries), timeout)
+			if err != nil {
+				return nil, err
+			}
+			if res != nil {
+				ctx.reproLog(3, "found reproducer with %d syscalls", len(res.Prog.Calls))
+				return res, nil
+			}
 		}
+		ctx.cp.SingleDone = false
+		ctx.cp.ParallelDone = false
+		ctx.cp.Guilty = nil
+		ctx.saveCheckpoint()
 	}
 
 	ctx.reproLog(0, "failed to extract reproducer")
 	return nil, nil
 }
 
+// The shortest duration is 10 seconds to detect simple crashes (i.e. no races and no hangs).
+// The longest duration is 5 minutes to catch races and hangs. Note that this value must be larger
+// than hang/no output detection duration in vm.MonitorExecution, which is currently set to 3 mins.
+var extractTimeouts = []time.Duration{10 * time.Second, 1 * time.Minute, 5 * time.Minute}
+
 func (ctx *context) extractProgSingle(entries []*prog.LogEntry, duration time.Duration) (*Result, error) {
 	ctx.reproLog(3, "single: executing %d programs separately with timeout %s", len(entries), duration)
 
@@ -319,6 +478,46 @@ func (ctx *context) extractProgSingle(entries []*prog.LogEntry, duration time.Du
 	return nil, nil
 }
 
+// extractProgParallel tries to reproduce the crash by executing the last programs
+// of different procs concurrently, and then bisects them to find the guilty group.
+// Fault injection is not used in this mode.
+func (ctx *context) extractProgParallel(entries []*prog.LogEntry, duration time.Duration) (*Result, error) {
+	ctx.reproLog(3, "parallel: executing %d programs concurrently with timeout %s", len(entries), duration)
+	start := time.Now()
+	defer func() {
+		ctx.stats.ParallelTime += time.Since(start)
+	}()
+
+	opts := csource.DefaultOpts(ctx.cfg)
+	opts.Fault = false
+	opts.FaultCall = -1
+	opts.FaultNth = 0
+	entries, err := ctx.bisectProgs(entries, func(entries []*prog.LogEntry) (bool, error) {
+		var progs []*prog.Prog
+		for _, ent := range entries {
+			progs = append(progs, ent.P)
+		}
+		return ctx.testParallel(progs, duration, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		ctx.reproLog(3, "parallel: failed to extract reproducer")
+		return nil, nil
+	}
+	res := &Result{
+		Prog:     entries[0].P,
+		Duration: duration * 3 / 2,
+		Opts:     opts,
+	}
+	for _, ent := range entries[1:] {
+		res.Parallel = append(res.Parallel, ent.P)
+	}
+	ctx.reproLog(3, "parallel: successfully extracted reproducer with %d programs", len(entries))
+	return res, nil
+}
+
 func (ctx *context) extractProgBisect(entries []*prog.LogEntry, baseDuration time.Duration) (*Result, error) {
 	ctx.reproLog(3, "bisect: bisecting %d programs with base timeout %s", len(entries), baseDuration)
 
@@ -403,9 +602,12 @@ func (ctx *context) minimizeProg(res *Result) (*Result, error) {
 	ctx.reproLog(2, "minimizing guilty program")
 	start := time.Now()
 	defer func() {
-		ctx.stats.MinimizeProgTime = time.Since(start)
+		ctx.stats.MinimizeProgTime += time.Since(start)
 	}()
 
+	if len(res.Parallel) != 0 {
+		return ctx.minimizeParallel(res)
+	}
 	call := -1
 	if res.Opts.Fault {
 		call = res.Opts.FaultCall
@@ -423,20 +625,42 @@ func (ctx *context) minimizeProg(res *Result) (*Result, error) {
 	return res, nil
 }
 
+// minimizeParallel minimizes programs of a multi-program reproducer one by one,
+// while executing the rest of the programs concurrently as is.
+func (ctx *context) minimizeParallel(res *Result) (*Result, error) {
+	progs := res.Progs()
+	for i := range progs {
+		progs[i], _ = prog.Minimize(progs[i], -1, true,
+			func(p1 *prog.Prog, callIndex int) bool {
+				progs1 := append([]*prog.Prog{}, progs...)
+				progs1[i] = p1
+				crashed, err := ctx.testParallel(progs1, res.Duration, res.Opts)
+				if err != nil {
+					ctx.reproLog(0, "minimization failed with %v", err)
+					return false
+				}
+				return crashed
+			})
+	}
+	res.Prog, res.Parallel = progs[0], progs[1:]
+	return res, nil
+}
+
 // Simplify repro options (threaded, collide, sandbox, etc).
 func (ctx *context) simplifyProg(res *Result) (*Result, error) {
 	ctx.reproLog(2, "simplifying guilty program")
 	start := time.Now()
 	defer func() {
-		ctx.stats.SimplifyProgTime = time.Since(start)
+		ctx.stats.SimplifyProgTime += time.Since(start)
 	}()
 
-	for _, simplify := range progSimplifies {
+	for ; ctx.cp.Simplify < len(progSimplifies); ctx.saveSimplify(res) {
+		simplify := progSimplifies[ctx.cp.Simplify]
 		opts := res.Opts
 		if !simplify(&opts) {
 			continue
 		}
-		crashed, err := ctx.testProg(res.Prog, res.Duration, opts)
+		crashed, err := ctx.testSyz(res, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -462,10 +686,10 @@ func (ctx *context) extractC(res *Result) (*Result, error) {
 	ctx.reproLog(2, "extracting C reproducer")
 	start := time.Now()
 	defer func() {
-		ctx.stats.ExtractCTime = time.Since(start)
+		ctx.stats.ExtractCTime += time.Since(start)
 	}()
 
-	crashed, err := ctx.testCProg(res.Prog, res.Duration, res.Opts)
+	crashed, err := ctx.testC(res, res.Opts)
 	if err != nil {
 		return nil, err
 	}
@@ -478,13 +702,14 @@ func (ctx *context) simplifyC(res *Result) (*Result, error) {
 	ctx.reproLog(2, "simplifying C reproducer")
 	start := time.Now()
 	defer func() {
-		ctx.stats.SimplifyCTime = time.Since(start)
+		ctx.stats.SimplifyCTime += time.Since(start)
 	}()
 
-	for _, simplify := range cSimplifies {
+	for ; ctx.cp.Simplify < len(cSimplifies); ctx.saveSimplify(res) {
+		simplify := cSimplifies[ctx.cp.Simplify]
 		opts := res.Opts
 		if simplify(&opts) {
-			crashed, err := ctx.testCProg(res.Prog, res.Duration, opts)
+			crashed, err := ctx.testC(res, opts)
 			if err != nil {
 				return nil, err
 			}
@@ -496,6 +721,77 @@ func (ctx *context) simplifyC(res *Result) (*Result, error) {
 	return res, nil
 }
 
+// testResult runs the reproducer in res (C reproducer if it's available).
+func (ctx *context) testResult(res *Result) (crashed bool, err error) {
+	if res.CRepro {
+		return ctx.testC(res, res.Opts)
+	}
+	return ctx.testSyz(res, res.Opts)
+}
+
+// testSyz runs programs of the reproducer res with opts using syz-execprog.
+func (ctx *context) testSyz(res *Result, opts csource.Options) (crashed bool, err error) {
+	if len(res.Parallel) != 0 {
+		return ctx.testParallel(res.Progs(), res.Duration, opts)
+	}
+	return ctx.testProg(res.Prog, res.Duration, opts)
+}
+
+// testC runs C reproducer for res generated with opts.
+func (ctx *context) testC(res *Result, opts csource.Options) (crashed bool, err error) {
+	if len(res.Parallel) != 0 {
+		return ctx.testParallelC(res.Progs(), res.Duration, opts)
+	}
+	return ctx.testCProg(res.Prog, res.Duration, opts)
+}
+
+// measureReliability re-runs the final reproducer runs times (both syz and C variants)
+// and records the reproduction rate and mean time to crash in stats.
+func (ctx *context) measureReliability(res *Result, runs int) error {
+	ctx.reproLog(2, "measuring reliability of the reproducer with %v runs", runs)
+	start := time.Now()
+	defer func() {
+		ctx.stats.ReliabilityTime += time.Since(start)
+	}()
+
+	measure := func(test func(*Result, csource.Options) (bool, error)) (Reliability, error) {
+		var rel Reliability
+		var total time.Duration
+		for i := 0; i < runs; i++ {
+			crashed, err := test(res, res.Opts)
+			if err != nil {
+				return rel, err
+			}
+			rel.Runs++
+			if crashed {
+				rel.Crashes++
+				total += ctx.crashTime
+			}
+		}
+		if rel.Crashes != 0 {
+			rel.MeanTimeToCrash = total / time.Duration(rel.Crashes)
+		}
+		return rel, nil
+	}
+	// Don't let the reliability runs override report of the reproducer.
+	rep := ctx.report
+	defer func() {
+		ctx.report = rep
+	}()
+	var err error
+	if ctx.stats.SyzReliability, err = measure(ctx.testSyz); err != nil {
+		return err
+	}
+	ctx.reproLog(2, "syz reproducer reliability: %v", ctx.stats.SyzReliability)
+	if res.CRepro {
+		if ctx.stats.CReliability, err = measure(ctx.testC); err != nil {
+			return err
+		}
+		ctx.reproLog(2, "C reproducer reliability: %v", ctx.stats.CReliability)
+	}
+	return nil
+}
+
 func (ctx *context) testProg(p *prog.Prog, duration time.Duration, opts csource.Options) (crashed bool, err error) {
 	entry := prog.LogEntry{P: p}
 	if opts.Fault {
@@ -508,6 +804,20 @@ func (ctx *context) testProg(p *prog.Prog, duration time.Duration, opts csource.
 
 func (ctx *context) testProgs(entries []*prog.LogEntry, duration time.Duration, opts csource.Options) (
 	crashed bool, err error) {
+	return ctx.testEntries(entries, duration, opts, false)
+}
+
+// testParallel executes progs concurrently, each program in a separate proc.
+func (ctx *context) testParallel(progs []*prog.Prog, duration time.Duration, opts csource.Options) (
+	crashed bool, err error) {
+	if len(progs) == 1 {
+		return ctx.testProg(progs[0], duration, opts)
+	}
+	return ctx.testEntries(parallelEntries(progs), duration, opts, true)
+}
+
+func (ctx *context) testEntries(entries []*prog.LogEntry, duration time.Duration, opts csource.Options,
+	parallel bool) (crashed bool, err error) {
 	inst := <-ctx.instances
 	if inst == nil {
 		return false, fmt.Errorf("all VMs failed to boot")
@@ -534,6 +844,9 @@ func (ctx *context) testProgs(entries []*prog.LogEntry, duration time.Duration,
 	program := entries[0].P.String()
 	if len(entries) > 1 {
 		program = "["
+		if parallel {
+			program = "parallel ["
+		}
 		for i, entry := range entries {
 			program += fmt.Sprintf("%v", len(entry.P.Calls))
 			if i != len(entries)-1 {
@@ -545,7 +858,7 @@ func (ctx *context) testProgs(entries []*prog.LogEntry, duration time.Duration,
 
 	command := instancePkg.ExecprogCmd(inst.execprogBin, inst.executorBin,
 		ctx.cfg.TargetOS, ctx.cfg.TargetArch, opts.Sandbox, opts.Repeat,
-		opts.Threaded, opts.Collide, opts.Procs, -1, -1, vmProgFile)
+		opts.Threaded, opts.Collide, parallel, opts.Procs, -1, -1, vmProgFile)
 	ctx.reproLog(2, "testing program (duration=%v, %+v): %s", duration, opts, program)
 	return ctx.testImpl(inst.Instance, command, duration)
 }
@@ -561,28 +874,63 @@ func (ctx *context) testCProg(p *prog.Prog, duration time.Duration, opts csource
 	}
 	defer os.Remove(bin)
 	ctx.reproLog(2, "testing compiled C program (duration=%v, %+v): %s", duration, opts, p)
-	crashed, err = ctx.testBin(bin, duration)
+	crashed, err = ctx.testBin(duration, bin)
 	if err != nil {
 		return false, err
 	}
 	return crashed, nil
 }
 
-func (ctx *context) testBin(bin string, duration time.Duration) (crashed bool, err error) {
+// testParallelC builds a C program for each of progs and runs all binaries concurrently.
+func (ctx *context) testParallelC(progs []*prog.Prog, duration time.Duration, opts csource.Options) (
+	crashed bool, err error) {
+	var bins []string
+	defer func() {
+		for _, bin := range bins {
+			os.Remove(bin)
+		}
+	}()
+	for _, p := range progs {
+		src, err := csource.Write(p, opts)
+		if err != nil {
+			return false, err
+		}
+		bin, err := csource.Build(p.Target, src)
+		if err != nil {
+			return false, err
+		}
+		bins = append(bins, bin)
+	}
+	ctx.reproLog(2, "testing %v compiled C programs concurrently (duration=%v, %+v)",
+		len(bins), duration, opts)
+	return ctx.testBin(duration, bins...)
+}
+
+// testBin copies bins to a VM and runs them concurrently.
+func (ctx *context) testBin(duration time.Duration, bins ...string) (crashed bool, err error) {
 	inst := <-ctx.instances
 	if inst == nil {
 		return false, fmt.Errorf("all VMs failed to boot")
 	}
 	defer ctx.returnInstance(inst)
 
-	bin, err = inst.Copy(bin)
-	if err != nil {
-		return false, fmt.Errorf("failed to copy to VM: %v", err)
+	var vmBins []string
+	for _, bin := range bins {
+		vmBin, err := inst.Copy(bin)
+		if err != nil {
+			return false, fmt.Errorf("failed to copy to VM: %v", err)
+		}
+		vmBins = append(vmBins, vmBin)
+	}
+	command := vmBins[0]
+	if len(vmBins) > 1 {
+		command = strings.Join(vmBins, " & ") + " & wait"
 	}
-	return ctx.testImpl(inst.Instance, bin, duration)
+	return ctx.testImpl(inst.Instance, command, duration)
 }
 
 func (ctx *context) testImpl(inst *vm.Instance, command string, duration time.Duration) (crashed bool, err error) {
+	start := time.Now()
 	outc, errc, err := inst.Run(duration, nil, command)
 	if err != nil {
 		return false, fmt.Errorf("failed to run command in VM: %v", err)
@@ -598,6 +946,7 @@ func (ctx *context) testImpl(inst *vm.Instance, command string, duration time.Du
 		return false, nil
 	}
 	ctx.report = rep
+	ctx.crashTime = time.Since(start)
 	ctx.reproLog(2, "program crashed: %v", rep.Title)
 	return true, nil
 }
@@ -610,25 +959,34 @@ func (ctx *context) returnInstance(inst *instance) {
 func (ctx *context) reproLog(level int, format string, args ...interface{}) {
 	prefix := fmt.Sprintf("reproducing crash '%v': ", ctx.crashTitle)
 	log.Logf(level, prefix+format, args...)
-	ctx.stats.Log = append(ctx.stats.Log, []byte(fmt.Sprintf(format, args...)+"\n")...)
+	line := []byte(fmt.Sprintf(format, args...) + "\n")
+	ctx.stats.Log = append(ctx.stats.Log, line...)
+	if ctx.progress != nil {
+		ctx.progress.Write(line)
+	}
 }
 
 func (ctx *context) bisectProgs(progs []*prog.LogEntry, pred func([]*prog.LogEntry) (bool, error)) (
 	[]*prog.LogEntry, error) {
 	ctx.reproLog(3, "bisect: bisecting %d programs", len(progs))
 
-	ctx.reproLog(3, "bisect: executing all %d programs", len(progs))
-	crashed, err := pred(progs)
-	if err != nil {
-		return nil, err
-	}
-	if !crashed {
-		ctx.reproLog(3, "bisect: didn't crash")
-		return nil, nil
+	guilty := ctx.restoreBisect()
+	if guilty != nil {
+		ctx.reproLog(3, "bisect: resuming from checkpoint")
+	} else {
+		ctx.reproLog(3, "bisect: executing all %d programs", len(progs))
+		crashed, err := pred(progs)
+		if err != nil {
+			return nil, err
+		}
+		if !crashed {
+			ctx.reproLog(3, "bisect: didn't crash")
+			return nil, nil
+		}
+		guilty = [][]*prog.LogEntry{progs}
 	}
-
-	guilty := [][]*prog.LogEntry{progs}
 again:
+	ctx.saveBisect(guilty)
 	ctx.reproLog(3, "bisect: guilty chunks: %v", chunksToStr(guilty))
 	for i, chunk := range guilty {
 		if len(chunk) == 1 {
@@ -724,10 +1082,20 @@ func chunksToStr(chunks [][]*prog.LogEntry) string {
 	return log
 }
 
+// reverseEntries returns entries in reverse order (entries itself is not modified).
 func reverseEntries(entries []*prog.LogEntry) []*prog.LogEntry {
-	last := len(entries) - 1
-	for i := 0; i < len(entries)/2; i++ {
-		entries[i], entries[last-i] = entries[last-i], entries[i]
+	res := make([]*prog.LogEntry, len(entries))
+	for i, ent := range entries {
+		res[len(entries)-1-i] = ent
+	}
+	return res
+}
+
+// parallelEntries returns log entries for progs executed in separate procs.
+func parallelEntries(progs []*prog.Prog) []*prog.LogEntry {
+	var entries []*prog.LogEntry
+	for i, p := range progs {
+		entries = append(entries, &prog.LogEntry{P: p, Proc: i})
 	}
 	return entries
 }
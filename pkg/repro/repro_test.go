@@ -4,13 +4,17 @@
 package repro
 
 import (
+	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/google/syzkaller/pkg/csource"
 	"github.com/google/syzkaller/prog"
+	_ "github.com/google/syzkaller/sys"
 )
 
 func initTest(t *testing.T) (*rand.Rand, int) {
@@ -101,3 +105,112 @@ func TestSimplifies(t *testing.T) {
 	}
 	check(opts, 0)
 }
+
+func TestBisectResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syz-repro-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	checkpointFile := filepath.Join(dir, "repro.checkpoint")
+
+	var entries []*prog.LogEntry
+	for i := 0; i < 100; i++ {
+		ent := &prog.LogEntry{Proc: i}
+		entries = append(entries, ent)
+	}
+	guilty := map[int]bool{13: true, 42: true, 77: true}
+	newContext := func(cp *checkpoint) *context {
+		ctx := &context{
+			stats:          cp.Stats,
+			checkpointFile: checkpointFile,
+			cp:             cp,
+			entries:        entries,
+			entryIndex:     make(map[*prog.LogEntry]int),
+		}
+		for i, ent := range entries {
+			ctx.entryIndex[ent] = i
+		}
+		return ctx
+	}
+	runs := 0
+	pred := func(limit int) func([]*prog.LogEntry) (bool, error) {
+		return func(progs []*prog.LogEntry) (bool, error) {
+			if runs++; runs > limit {
+				return false, fmt.Errorf("interrupted")
+			}
+			n := 0
+			for _, ent := range progs {
+				if guilty[ent.Proc] {
+					n++
+				}
+			}
+			return n == len(guilty), nil
+		}
+	}
+	ctx := newContext(&checkpoint{Log: []byte("log"), Stats: new(Stats)})
+	if _, err := ctx.bisectProgs(entries, pred(5)); err == nil {
+		t.Fatalf("bisection was not interrupted")
+	}
+	cp, err := loadCheckpoint(checkpointFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Guilty) == 0 {
+		t.Fatalf("checkpoint does not contain bisection state")
+	}
+	runs = 0
+	res, err := newContext(cp).bisectProgs(entries, pred(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != len(guilty) {
+		t.Fatalf("wrong number of guilty programs: got %v, want %v", len(res), len(guilty))
+	}
+	for _, ent := range res {
+		if !guilty[ent.Proc] {
+			t.Fatalf("wrong program is guilty: %v", ent.Proc)
+		}
+	}
+}
+
+func TestSerializeParallel(t *testing.T) {
+	target, err := prog.GetTarget("test", "64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, _ := initTest(t)
+	res := &Result{
+		Prog:     target.Generate(rs, 5, nil),
+		Parallel: []*prog.Prog{target.Generate(rs, 5, nil), target.Generate(rs, 5, nil)},
+	}
+	entries := target.ParseLog(res.Serialize())
+	if len(entries) != len(res.Progs()) {
+		t.Fatalf("got %v programs, want %v", len(entries), len(res.Progs()))
+	}
+	for i, ent := range entries {
+		if ent.Proc != i {
+			t.Errorf("program %v has proc %v", i, ent.Proc)
+		}
+		if want, got := res.Progs()[i].Serialize(), ent.P.Serialize(); string(want) != string(got) {
+			t.Errorf("program %v differs:\n%s\nwant:\n%s", i, got, want)
+		}
+	}
+	res.Parallel = nil
+	if len(target.ParseLog(res.Serialize())) != 1 {
+		t.Fatalf("single program reproducer serialized as several programs")
+	}
+}
+
+func TestReliability(t *testing.T) {
+	if rate := (Reliability{}).Rate(); rate != 0 {
+		t.Fatalf("rate of no runs is %v", rate)
+	}
+	rel := Reliability{Runs: 4, Crashes: 3, MeanTimeToCrash: 10 * time.Second}
+	if rate := rel.Rate(); rate != 0.75 {
+		t.Fatalf("bad rate %v, want 0.75", rate)
+	}
+	if want := "3/4 runs crashed (75%), mean time to crash 10s"; rel.String() != want {
+		t.Fatalf("bad string %q, want %q", rel.String(), want)
+	}
+}
@@ -6,6 +6,8 @@
 package rpctype
 
 import (
+	"time"
+
 	"github.com/google/syzkaller/pkg/host"
 	"github.com/google/syzkaller/pkg/ipc"
 	"github.com/google/syzkaller/pkg/signal"
@@ -29,12 +31,16 @@ type ConnectArgs struct {
 }
 
 type ConnectRes struct {
-	EnabledCalls     []int
-	GitRevision      string
-	TargetRevision   string
-	AllSandboxes     bool
-	CheckResult      *CheckArgs
-	MemoryLeakFrames [][]byte
+	EnabledCalls      []int
+	GitRevision       string
+	TargetRevision    string
+	AllSandboxes      bool
+	FeatureOverrides  map[string]bool  // see mgrconfig.Config.Features
+	CoverFilter       []signal.PCRange // signal is restricted to these PCs if not empty
+	CoverFilterWeight float64          // fraction of signal outside of CoverFilter that is kept
+	SignalMode        signal.Mode
+	CheckResult       *CheckArgs
+	MemoryLeakFrames  [][]byte
 }
 
 type CheckArgs struct {
@@ -42,7 +48,8 @@ type CheckArgs struct {
 	Error         string
 	EnabledCalls  map[string][]int
 	DisabledCalls map[string][]SyscallReason
-	Features      *host.Features
+	// Final features with FeatureOverrides applied.
+	Features *host.Features
 }
 
 type SyscallReason struct {
@@ -60,6 +67,27 @@ type PollArgs struct {
 	NeedCandidates bool
 	MaxSignal      signal.Serial
 	Stats          map[string]uint64
+	SlowCalls      map[string]*SlowCallStat
+}
+
+// SlowCallStat aggregates executions of a single syscall that took longer
+// than the fuzzer slow call threshold.
+type SlowCallStat struct {
+	Count     uint64        // number of slow executions
+	Blocked   uint64        // number of slow executions that did not finish at all
+	TotalTime time.Duration // sum of wall times of slow executions
+	MaxTime   time.Duration // max wall time of a single execution
+	CPUTime   time.Duration // sum of CPU times of slow executions
+}
+
+func (stat *SlowCallStat) Merge(other *SlowCallStat) {
+	stat.Count += other.Count
+	stat.Blocked += other.Blocked
+	stat.TotalTime += other.TotalTime
+	stat.CPUTime += other.CPUTime
+	if stat.MaxTime < other.MaxTime {
+		stat.MaxTime = other.MaxTime
+	}
 }
 
 type PollRes struct {
@@ -0,0 +1,92 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package signal
+
+import (
+	"math"
+	"sort"
+)
+
+// PCRange is a half-open range [Start, End) of truncated kernel PCs (as reported in coverage).
+type PCRange struct {
+	Start uint32
+	End   uint32
+}
+
+// Filter restricts signal to code within a set of PC ranges.
+type Filter struct {
+	ranges    []PCRange // sorted and non-overlapping
+	threshold uint32    // signal outside of ranges is kept if its hash is below threshold
+}
+
+// NewFilter creates a filter for the ranges. weight is the fraction (in [0, 1)) of signal
+// outside of the ranges that is still kept (signal is selected by hash, so the same signal
+// is always either kept or dropped). 0 drops all such signal.
+func NewFilter(ranges []PCRange, weight float64) *Filter {
+	sorted := append([]PCRange{}, ranges...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+	f := &Filter{
+		threshold: uint32(weight * math.MaxUint32),
+	}
+	for _, r := range sorted {
+		if r.Start >= r.End {
+			continue
+		}
+		if n := len(f.ranges); n != 0 && r.Start <= f.ranges[n-1].End {
+			if r.End > f.ranges[n-1].End {
+				f.ranges[n-1].End = r.End
+			}
+			continue
+		}
+		f.ranges = append(f.ranges, r)
+	}
+	return f
+}
+
+func (f *Filter) Empty() bool {
+	return len(f.ranges) == 0
+}
+
+func (f *Filter) Contains(pc uint32) bool {
+	idx := sort.Search(len(f.ranges), func(i int) bool {
+		return pc < f.ranges[i].End
+	})
+	return idx != len(f.ranges) && pc >= f.ranges[idx].Start
+}
+
+// FilterRaw returns elements of raw signal that originate from PCs within the filter.
+// trace is the coverage trace that the signal was computed from in the given mode
+// (as collected without FlagDedupCover, i.e. in execution order), call is the syscall ID.
+// Signal that does not originate from trace (e.g. fallback signal) is considered to be outside of the filter.
+// The result reuses the raw slice.
+func (f *Filter) FilterRaw(mode Mode, raw, trace []uint32, call int) []uint32 {
+	if len(raw) == 0 {
+		return raw
+	}
+	allowed := make(map[uint32]bool)
+	mode.Trace(trace, call, func(pc, elem uint32) {
+		if f.Contains(pc) {
+			allowed[elem] = true
+		}
+	})
+	res := raw[:0]
+	for _, e := range raw {
+		if allowed[e] || hash(e) < f.threshold {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// hash is the same hash function that executor uses to compute signal.
+func hash(a uint32) uint32 {
+	a = (a ^ 61) ^ (a >> 16)
+	a = a + (a << 3)
+	a = a ^ (a >> 4)
+	a = a * 0x27d4eb2d
+	a = a ^ (a >> 15)
+	return a
+}
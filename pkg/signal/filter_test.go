@@ -0,0 +1,81 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package signal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterContains(t *testing.T) {
+	f := NewFilter([]PCRange{{0x300, 0x400}, {0x100, 0x200}, {0x150, 0x250}, {0x500, 0x500}}, 0)
+	tests := []struct {
+		pc     uint32
+		result bool
+	}{
+		{0x0ff, false},
+		{0x100, true},
+		{0x1ff, true},
+		{0x24f, true},
+		{0x250, false},
+		{0x300, true},
+		{0x3ff, true},
+		{0x400, false},
+		{0x500, false},
+	}
+	for _, test := range tests {
+		if res := f.Contains(test.pc); res != test.result {
+			t.Errorf("pc=0x%x: want %v, got %v", test.pc, test.result, res)
+		}
+	}
+	if NewFilter(nil, 0).Contains(0) {
+		t.Errorf("empty filter contains pc")
+	}
+}
+
+func TestFilterRaw(t *testing.T) {
+	trace := []uint32{0x1000, 0x2000, 0x1004, 0x3000}
+	var raw []uint32
+	prev := uint32(0)
+	for _, pc := range trace {
+		raw = append(raw, pc^prev)
+		prev = hash(pc)
+	}
+	f := NewFilter([]PCRange{{0x1000, 0x1fff}}, 0)
+	got := f.FilterRaw(ModeEdge, append([]uint32{}, raw...), trace, 0)
+	want := []uint32{raw[0], raw[2]}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %x, got %x", want, got)
+	}
+}
+
+func TestFilterRawWeight(t *testing.T) {
+	var trace []uint32
+	for pc := uint32(0x1000); pc < 0x2000; pc += 4 {
+		trace = append(trace, pc)
+	}
+	f := NewFilter([]PCRange{{0x1000, 0x1100}}, 0.25)
+	raw := append([]uint32{}, trace...)
+	got := f.FilterRaw(ModePC, raw, trace, 0)
+	inside, outside := 0, 0
+	for _, pc := range got {
+		if f.Contains(pc) {
+			inside++
+		} else {
+			outside++
+		}
+	}
+	if inside != 0x100/4 {
+		t.Fatalf("kept %v signal inside of the filter, want %v", inside, 0x100/4)
+	}
+	// Total outside signal is 960 elements, roughly a quarter of them must be kept.
+	if outside < 160 || outside > 320 {
+		t.Fatalf("kept %v signal outside of the filter, want ~240", outside)
+	}
+	// The same signal is kept every time.
+	got1 := f.FilterRaw(ModePC, append([]uint32{}, trace...), trace, 0)
+	if !reflect.DeepEqual(got, got1) {
+		t.Fatalf("filtering is not deterministic")
+	}
+}
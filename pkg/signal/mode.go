@@ -0,0 +1,67 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package signal
+
+import (
+	"fmt"
+)
+
+// Mode determines granularity of feedback signal that executor computes from coverage trace.
+// Signal elements produced in different modes are not comparable.
+type Mode uint8
+
+const (
+	ModeEdge        Mode = iota // edges between subsequent PCs (default)
+	ModePC                      // individual PCs
+	ModeNGram                   // sequences of 3 subsequent PCs
+	ModeCallContext             // edges distinguished by the syscall that executed them
+	modeCount
+)
+
+var modeNames = [modeCount]string{
+	ModeEdge:        "edge",
+	ModePC:          "pc",
+	ModeNGram:       "ngram",
+	ModeCallContext: "call_context",
+}
+
+func (m Mode) String() string {
+	if m >= modeCount {
+		return fmt.Sprintf("mode%v", uint8(m))
+	}
+	return modeNames[m]
+}
+
+func ParseMode(name string) (Mode, error) {
+	for m, n := range modeNames {
+		if n == name {
+			return Mode(m), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown signal mode %q, want one of %v", name, modeNames)
+}
+
+// Trace calls fn for every PC in coverage trace with the signal element it produces.
+// call is the ID of the syscall that produced the trace.
+// Must be in sync with write_coverage_signal in executor.
+func (m Mode) Trace(trace []uint32, call int, fn func(pc, elem uint32)) {
+	var prev, prev2 uint32
+	for _, pc := range trace {
+		elem := pc
+		switch m {
+		case ModeEdge:
+			elem ^= prev
+		case ModePC:
+		case ModeNGram:
+			elem ^= prev ^ (prev2 >> 1)
+		case ModeCallContext:
+			elem ^= prev ^ hash(uint32(call)+1)
+		default:
+			panic(fmt.Sprintf("unknown signal mode %v", m))
+		}
+		prev2 = prev
+		prev = hash(pc)
+		fn(pc, elem)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package signal
+
+import (
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	for m := Mode(0); m < modeCount; m++ {
+		m1, err := ParseMode(m.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m1 != m {
+			t.Fatalf("mode %v parsed as %v", m, m1)
+		}
+	}
+	if _, err := ParseMode("foo"); err == nil {
+		t.Fatalf("parsed unknown mode")
+	}
+}
+
+func TestModeTrace(t *testing.T) {
+	trace := []uint32{0x1000, 0x2000, 0x1000, 0x2000, 0x3000}
+	elems := func(m Mode, call int) map[uint32]bool {
+		res := make(map[uint32]bool)
+		m.Trace(trace, call, func(pc, elem uint32) {
+			res[elem] = true
+		})
+		return res
+	}
+	// Number of distinct signal elements in the trace for every mode.
+	want := map[Mode]int{
+		ModePC:          3,
+		ModeEdge:        4,
+		ModeNGram:       5,
+		ModeCallContext: 4,
+	}
+	for m, n := range want {
+		if got := len(elems(m, 0)); got != n {
+			t.Errorf("mode %v: want %v elements, got %v", m, n, got)
+		}
+	}
+	ctx0, ctx1 := elems(ModeCallContext, 0), elems(ModeCallContext, 1)
+	for e := range ctx0 {
+		if ctx1[e] {
+			t.Errorf("call_context signal does not depend on call: 0x%x", e)
+		}
+	}
+}
@@ -15,7 +15,9 @@ type (
 
 type Signal map[elemType]prioType
 
+// Serial is serialized form of Signal tagged with the mode the signal was collected in.
 type Serial struct {
+	Mode  Mode
 	Elems []elemType
 	Prios []prioType
 }
@@ -66,11 +68,12 @@ func FromRaw(raw []uint32, prio uint8) Signal {
 	return s
 }
 
-func (s Signal) Serialize() Serial {
+func (s Signal) Serialize(mode Mode) Serial {
 	if s.Empty() {
-		return Serial{}
+		return Serial{Mode: mode}
 	}
 	res := Serial{
+		Mode:  mode,
 		Elems: make([]elemType, len(s)),
 		Prios: make([]prioType, len(s)),
 	}
@@ -83,6 +86,11 @@ func (s Signal) Serialize() Serial {
 	return res
 }
 
+// Compatible returns true if ser can be mixed with signal collected in mode.
+func (ser Serial) Compatible(mode Mode) bool {
+	return len(ser.Elems) == 0 || ser.Mode == mode
+}
+
 func (ser Serial) Deserialize() Signal {
 	if len(ser.Elems) != len(ser.Prios) {
 		panic("corrupted Serial")
@@ -0,0 +1,424 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package symbolizer
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DWARF symbolizes PCs of a single binary using its DWARF debug info in-process.
+// Line tables and the tree of inlined functions are parsed once, lookups are binary searches,
+// so it's much faster than addr2line for large numbers of PCs (e.g. coverage of vmlinux).
+// DWARF is immutable after construction and is safe for concurrent use.
+type DWARF struct {
+	files []string
+	lines []lineEntry // sorted by pc
+	funcs []funcRange // sorted by start, non-overlapping
+}
+
+type lineEntry struct {
+	pc   uint64
+	file uint32
+	line uint32 // 0 denotes end of a sequence
+}
+
+type funcRange struct {
+	start uint64
+	end   uint64
+	fn    *dwarfFunc
+}
+
+type dwarfFunc struct {
+	name     string
+	ranges   [][2]uint64
+	callFile uint32 // file/line where this function was inlined (for inlined functions)
+	callLine uint32
+	inlined  []*dwarfFunc
+}
+
+// NewDWARF parses DWARF debug info of the ELF binary bin.
+func NewDWARF(bin string) (*DWARF, error) {
+	f, err := elf.Open(bin)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := f.DWARF()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DWARF from %v: %v", bin, err)
+	}
+	b := &dwarfBuilder{
+		d:       new(DWARF),
+		data:    data,
+		fileIdx: make(map[string]uint32),
+		names:   make(map[dwarf.Offset]string),
+		origins: make(map[dwarf.Offset]dwarf.Offset),
+		pending: make(map[*dwarfFunc]dwarf.Offset),
+	}
+	if err := b.build(); err != nil {
+		return nil, fmt.Errorf("failed to parse DWARF in %v: %v", bin, err)
+	}
+	// addr2line prints names of non-inlined functions from the symbol table
+	// (e.g. mangled names of static C++ functions that have no DW_AT_linkage_name).
+	if syms, err := f.Symbols(); err == nil {
+		b.useSymbolNames(syms)
+	}
+	b.d.funcs = flattenRanges(b.d.funcs)
+	return b.d, nil
+}
+
+type dwarfBuilder struct {
+	d       *DWARF
+	data    *dwarf.Data
+	fileIdx map[string]uint32
+	// names/origins allow to resolve names of functions that refer to
+	// an abstract instance (DW_AT_abstract_origin) or a declaration (DW_AT_specification).
+	names   map[dwarf.Offset]string
+	origins map[dwarf.Offset]dwarf.Offset
+	pending map[*dwarfFunc]dwarf.Offset
+	compDir string
+}
+
+func (b *dwarfBuilder) build() error {
+	var cuFiles []*dwarf.LineFile
+	// stack contains functions enclosing the current entry (nil for non-function entries).
+	var stack []*dwarfFunc
+	r := b.data.Reader()
+	for {
+		e, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			break
+		}
+		if e.Tag == 0 {
+			if len(stack) != 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		var fn *dwarfFunc
+		switch e.Tag {
+		case dwarf.TagCompileUnit:
+			stack = stack[:0]
+			b.compDir, _ = e.Val(dwarf.AttrCompDir).(string)
+			if cuFiles, err = b.readLines(e); err != nil {
+				return err
+			}
+		case dwarf.TagSubprogram, dwarf.TagInlinedSubroutine:
+			fn = b.readFunc(e, cuFiles)
+			var parent *dwarfFunc
+			for i := len(stack) - 1; i >= 0 && parent == nil; i-- {
+				parent = stack[i]
+			}
+			if e.Tag == dwarf.TagInlinedSubroutine && parent != nil {
+				parent.inlined = append(parent.inlined, fn)
+			} else {
+				for _, rng := range fn.ranges {
+					// Discarded functions have zero start address, skip them.
+					if rng[0] != 0 && rng[0] < rng[1] {
+						b.d.funcs = append(b.d.funcs, funcRange{rng[0], rng[1], fn})
+					}
+				}
+			}
+		}
+		if e.Children {
+			stack = append(stack, fn)
+		}
+	}
+	for fn, origin := range b.pending {
+		fn.name = b.resolveName(origin)
+	}
+	sort.SliceStable(b.d.lines, func(i, j int) bool {
+		l1, l2 := b.d.lines[i], b.d.lines[j]
+		if l1.pc != l2.pc {
+			return l1.pc < l2.pc
+		}
+		// End of a sequence goes before the start of the next sequence at the same pc.
+		return l1.line == 0 && l2.line != 0
+	})
+	return nil
+}
+
+func (b *dwarfBuilder) useSymbolNames(syms []elf.Symbol) {
+	names := make(map[uint64][]string)
+	for _, sym := range syms {
+		if elf.ST_TYPE(sym.Info) == elf.STT_FUNC && sym.Value != 0 {
+			names[sym.Value] = append(names[sym.Value], sym.Name)
+		}
+	}
+	for _, rng := range b.d.funcs {
+		candidates := names[rng.start]
+		if len(candidates) == 0 || rng.start != rng.fn.ranges[0][0] {
+			continue
+		}
+		// Aliases have the same address, keep the DWARF name if it is one of them.
+		found := false
+		for _, name := range candidates {
+			if name == rng.fn.name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			rng.fn.name = candidates[0]
+		}
+	}
+}
+
+// flattenRanges turns possibly nested/overlapping function ranges into sorted non-overlapping ranges,
+// every pc is attributed to the innermost (latest starting) function range that contains it.
+func flattenRanges(ranges []funcRange) []funcRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].start != ranges[j].start {
+			return ranges[i].start < ranges[j].start
+		}
+		// Outer ranges go first, so that inner ranges end up on top of the stack.
+		return ranges[i].end > ranges[j].end
+	})
+	var points []uint64
+	for _, rng := range ranges {
+		points = append(points, rng.start, rng.end)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+	var res []funcRange
+	// stack contains ranges that started before the current point.
+	// Ended ranges are popped lazily when they get to the top (only the top is used).
+	var stack []funcRange
+	next := 0
+	for i := 0; i < len(points); i++ {
+		start := points[i]
+		for i+1 < len(points) && points[i+1] == start {
+			i++
+		}
+		for len(stack) != 0 && stack[len(stack)-1].end <= start {
+			stack = stack[:len(stack)-1]
+		}
+		for ; next < len(ranges) && ranges[next].start == start; next++ {
+			stack = append(stack, ranges[next])
+		}
+		if len(stack) == 0 || i+1 == len(points) {
+			continue
+		}
+		// The top range covers the whole segment up to the next point.
+		fn, end := stack[len(stack)-1].fn, points[i+1]
+		if n := len(res); n != 0 && res[n-1].fn == fn && res[n-1].end == start {
+			res[n-1].end = end
+			continue
+		}
+		res = append(res, funcRange{start, end, fn})
+	}
+	return res
+}
+
+func (b *dwarfBuilder) readLines(cu *dwarf.Entry) ([]*dwarf.LineFile, error) {
+	lr, err := b.data.LineReader(cu)
+	if err != nil || lr == nil {
+		return nil, err
+	}
+	var le dwarf.LineEntry
+	for {
+		if err := lr.Next(&le); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		entry := lineEntry{pc: le.Address}
+		if !le.EndSequence {
+			if le.File == nil || le.Line <= 0 {
+				continue
+			}
+			entry.file = b.file(le.File.Name)
+			entry.line = uint32(le.Line)
+		}
+		b.d.lines = append(b.d.lines, entry)
+	}
+	return lr.Files(), nil
+}
+
+func (b *dwarfBuilder) readFunc(e *dwarf.Entry, cuFiles []*dwarf.LineFile) *dwarfFunc {
+	fn := new(dwarfFunc)
+	// addr2line prints linkage (mangled) names if present.
+	name, ok := e.Val(dwarf.AttrLinkageName).(string)
+	if !ok {
+		name, ok = e.Val(dwarf.AttrName).(string)
+	}
+	if ok {
+		fn.name = name
+		b.names[e.Offset] = name
+	} else if origin, ok := e.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset); ok {
+		b.origins[e.Offset] = origin
+		b.pending[fn] = origin
+	} else if spec, ok := e.Val(dwarf.AttrSpecification).(dwarf.Offset); ok {
+		b.origins[e.Offset] = spec
+		b.pending[fn] = spec
+	}
+	if ranges, err := b.data.Ranges(e); err == nil {
+		fn.ranges = ranges
+	}
+	if idx, ok := e.Val(dwarf.AttrCallFile).(int64); ok && idx >= 0 && idx < int64(len(cuFiles)) &&
+		cuFiles[idx] != nil {
+		fn.callFile = b.file(cuFiles[idx].Name)
+	}
+	if line, ok := e.Val(dwarf.AttrCallLine).(int64); ok && line > 0 {
+		fn.callLine = uint32(line)
+	}
+	return fn
+}
+
+func (b *dwarfBuilder) resolveName(off dwarf.Offset) string {
+	// Chains are short (concrete -> abstract -> declaration), the limit protects from loops.
+	for i := 0; i < 10; i++ {
+		if name, ok := b.names[off]; ok {
+			return name
+		}
+		next, ok := b.origins[off]
+		if !ok {
+			break
+		}
+		off = next
+	}
+	return ""
+}
+
+func (b *dwarfBuilder) file(name string) uint32 {
+	// Relative names are relative to the compilation directory, addr2line prints them joined.
+	if !filepath.IsAbs(name) && b.compDir != "" {
+		name = filepath.Join(b.compDir, name)
+	}
+	idx, ok := b.fileIdx[name]
+	if !ok {
+		idx = uint32(len(b.d.files))
+		b.d.files = append(b.d.files, name)
+		b.fileIdx[name] = idx
+	}
+	return idx
+}
+
+// Symbolize returns frames for pc in the same format as Symbolizer:
+// innermost inlined function first, the function that contains pc last.
+// Returns nil if there is no debug info for pc.
+func (d *DWARF) Symbolize(pc uint64) []Frame {
+	file, line, ok := d.lookupLine(pc)
+	if !ok {
+		return nil
+	}
+	fn := d.lookupFunc(pc)
+	if fn == nil {
+		return nil
+	}
+	chain := []*dwarfFunc{fn}
+	for {
+		var next *dwarfFunc
+		for _, inl := range chain[len(chain)-1].inlined {
+			if inl.contains(pc) {
+				next = inl
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		chain = append(chain, next)
+	}
+	var frames []Frame
+	for i := len(chain) - 1; i >= 0; i-- {
+		fn := chain[i]
+		// Skip frames without complete info, similarly to addr2line output parsing.
+		if fn.name != "" && line != 0 {
+			frames = append(frames, Frame{
+				PC:     pc,
+				Func:   fn.name,
+				File:   d.files[file],
+				Line:   int(line),
+				Inline: true,
+			})
+		}
+		file, line = fn.callFile, fn.callLine
+	}
+	if len(frames) != 0 {
+		frames[len(frames)-1].Inline = false
+	}
+	return frames
+}
+
+func (d *DWARF) lookupLine(pc uint64) (uint32, uint32, bool) {
+	idx := sort.Search(len(d.lines), func(i int) bool {
+		return d.lines[i].pc > pc
+	})
+	if idx == 0 {
+		return 0, 0, false
+	}
+	entry := d.lines[idx-1]
+	if entry.line == 0 {
+		return 0, 0, false
+	}
+	return entry.file, entry.line, true
+}
+
+func (d *DWARF) lookupFunc(pc uint64) *dwarfFunc {
+	idx := sort.Search(len(d.funcs), func(i int) bool {
+		return d.funcs[i].start > pc
+	})
+	if idx == 0 || pc >= d.funcs[idx-1].end {
+		return nil
+	}
+	return d.funcs[idx-1].fn
+}
+
+func (fn *dwarfFunc) contains(pc uint64) bool {
+	for _, rng := range fn.ranges {
+		if pc >= rng[0] && pc < rng[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// dwarfCache caches parsed DWARF per binary for the whole process,
+// so that e.g. every crash report and coverage report do not re-parse vmlinux.
+var dwarfCache struct {
+	sync.Mutex
+	entries map[string]*dwarfCacheEntry
+}
+
+type dwarfCacheEntry struct {
+	modTime time.Time
+	size    int64
+	dwarf   *DWARF
+	err     error
+}
+
+func cachedDWARF(bin string) (*DWARF, error) {
+	stat, err := os.Stat(bin)
+	if err != nil {
+		return nil, err
+	}
+	dwarfCache.Lock()
+	defer dwarfCache.Unlock()
+	if dwarfCache.entries == nil {
+		dwarfCache.entries = make(map[string]*dwarfCacheEntry)
+	}
+	if ent := dwarfCache.entries[bin]; ent != nil && ent.modTime.Equal(stat.ModTime()) && ent.size == stat.Size() {
+		return ent.dwarf, ent.err
+	}
+	d, err := NewDWARF(bin)
+	dwarfCache.entries[bin] = &dwarfCacheEntry{
+		modTime: stat.ModTime(),
+		size:    stat.Size(),
+		dwarf:   d,
+		err:     err,
+	}
+	return d, err
+}
@@ -0,0 +1,159 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package symbolizer
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+// testPCs builds testdata/inline.c with debug info and returns the binary and all PCs of its functions.
+func testPCs(t testing.TB) (string, []uint64) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc is not available")
+	}
+	bin, err := osutil.TempFile("syz-symbolizer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := osutil.RunCmd(time.Minute, "", "gcc", "-g", "-O2", "-o", bin,
+		filepath.Join("testdata", "inline.c")); err != nil {
+		os.Remove(bin)
+		t.Skipf("failed to build test program: %v\n%s", err, out)
+	}
+	// Note: we don't use ReadSymbols because it rounds up sizes,
+	// and padding after functions does not belong to any function in DWARF.
+	f, err := elf.Open(bin)
+	if err != nil {
+		os.Remove(bin)
+		t.Fatal(err)
+	}
+	defer f.Close()
+	symbols, err := f.Symbols()
+	if err != nil {
+		os.Remove(bin)
+		t.Fatal(err)
+	}
+	var pcs []uint64
+	for _, s := range symbols {
+		if s.Name != "main" && s.Name != "outer" && s.Name != "other" {
+			continue
+		}
+		for off := uint64(0); off < s.Size; off++ {
+			pcs = append(pcs, s.Value+off)
+		}
+	}
+	return bin, pcs
+}
+
+func TestDWARF(t *testing.T) {
+	if _, err := exec.LookPath("addr2line"); err != nil {
+		t.Skip("addr2line is not available")
+	}
+	bin, pcs := testPCs(t)
+	defer os.Remove(bin)
+	d, err := NewDWARF(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	symb := &Symbolizer{noDWARF: true}
+	defer symb.Close()
+	mismatches := 0
+	for _, pc := range pcs {
+		want, err := symb.Symbolize(bin, pc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := d.Symbolize(pc)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			mismatches++
+			t.Logf("pc 0x%x:\ngot:  %+v\nwant: %+v", pc, got, want)
+		}
+	}
+	if mismatches != 0 {
+		t.Fatalf("%v/%v pcs are symbolized differently", mismatches, len(pcs))
+	}
+}
+
+func TestFlattenRanges(t *testing.T) {
+	f1, f2, f3, f4 := &dwarfFunc{name: "f1"}, &dwarfFunc{name: "f2"}, &dwarfFunc{name: "f3"}, &dwarfFunc{name: "f4"}
+	d := &DWARF{funcs: flattenRanges([]funcRange{
+		{0x100, 0x200, f1},
+		// f2 is nested in f1, f3 is nested in f2 and starts at the same pc.
+		{0x120, 0x180, f2},
+		{0x120, 0x140, f3},
+		// f4 partially overlaps f1.
+		{0x1f0, 0x300, f4},
+		// Adjacent range of f4.
+		{0x300, 0x310, f4},
+	})}
+	for pc, want := range map[uint64]*dwarfFunc{
+		0x0ff: nil,
+		0x100: f1,
+		0x11f: f1,
+		0x120: f3,
+		0x13f: f3,
+		0x140: f2,
+		0x17f: f2,
+		0x180: f1,
+		0x1ef: f1,
+		0x1f0: f4,
+		0x2ff: f4,
+		0x300: f4,
+		0x30f: f4,
+		0x310: nil,
+	} {
+		if got := d.lookupFunc(pc); got != want {
+			t.Errorf("pc 0x%x: got %v, want %v", pc, got, want)
+		}
+	}
+	// Adjacent ranges of f4 are merged.
+	if len(d.funcs) != 5 {
+		t.Errorf("got %v ranges, want 5: %+v", len(d.funcs), d.funcs)
+	}
+}
+
+func BenchmarkSymbolizeDWARF(b *testing.B) {
+	benchmarkSymbolize(b, false)
+}
+
+func BenchmarkSymbolizeAddr2line(b *testing.B) {
+	if _, err := exec.LookPath("addr2line"); err != nil {
+		b.Skip("addr2line is not available")
+	}
+	benchmarkSymbolize(b, true)
+}
+
+func benchmarkSymbolize(b *testing.B, noDWARF bool) {
+	bin, pcs := testPCs(b)
+	defer os.Remove(bin)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Note: DWARF is parsed once per process (cached),
+		// addr2line subprocess is started per symbolizer.
+		symb := &Symbolizer{noDWARF: noDWARF}
+		if _, err := symb.SymbolizeArray(bin, pcs); err != nil {
+			b.Fatal(err)
+		}
+		symb.Close()
+	}
+}
+
+func BenchmarkParseDWARF(b *testing.B) {
+	bin, _ := testPCs(b)
+	defer os.Remove(bin)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewDWARF(bin); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -16,8 +16,12 @@ import (
 	"github.com/google/syzkaller/pkg/osutil"
 )
 
+// Symbolizer symbolizes PCs using in-process DWARF parsing (see DWARF),
+// and falls back to addr2line for binaries that we can't parse (e.g. non-ELF).
 type Symbolizer struct {
 	subprocs map[string]*subprocess
+	// noDWARF forces use of addr2line (used in tests/benchmarks).
+	noDWARF bool
 }
 
 type Frame struct {
@@ -45,6 +49,15 @@ func (s *Symbolizer) Symbolize(bin string, pc uint64) ([]Frame, error) {
 }
 
 func (s *Symbolizer) SymbolizeArray(bin string, pcs []uint64) ([]Frame, error) {
+	if !s.noDWARF {
+		if d, err := cachedDWARF(bin); err == nil {
+			var frames []Frame
+			for _, pc := range pcs {
+				frames = append(frames, d.Symbolize(pc)...)
+			}
+			return frames, nil
+		}
+	}
 	sub, err := s.getSubprocess(bin)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,38 @@
+// Copyright 2016 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package symbolizer
+
+import (
+	"debug/elf"
+)
+
+type Symbol struct {
+	Addr uint64
+	Size int
+}
+
+// ReadSymbols returns list of text symbols in the ELF binary bin.
+func ReadSymbols(bin string) (map[string][]Symbol, error) {
+	f, err := elf.Open(bin)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	syms, err := f.Symbols()
+	if err != nil {
+		return nil, err
+	}
+	symbols := make(map[string][]Symbol)
+	for _, sym := range syms {
+		if sym.Size == 0 || sym.Section >= elf.SectionIndex(len(f.Sections)) ||
+			f.Sections[sym.Section].Flags&elf.SHF_EXECINSTR == 0 {
+			continue
+		}
+		// Note: sizes reported by kernel do not match symbol table.
+		// Kernel probably subtracts address of this symbol from address of the next symbol.
+		// We could do the same, but for now we just round up size to 16.
+		symbols[sym.Name] = append(symbols[sym.Name], Symbol{sym.Value, int(sym.Size+15) / 16 * 16})
+	}
+	return symbols, nil
+}
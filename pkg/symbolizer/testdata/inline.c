@@ -0,0 +1,39 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// Test program for DWARF symbolizer: has functions with several levels of inlining.
+
+volatile int sink;
+
+static inline __attribute__((always_inline)) void inner(int x)
+{
+	sink = x * 3;
+	sink += x;
+}
+
+static inline __attribute__((always_inline)) void middle(int x)
+{
+	inner(x + 1);
+	sink ^= x;
+	inner(x + 2);
+}
+
+__attribute__((noinline)) void outer(int x)
+{
+	for (int i = 0; i < x; i++)
+		middle(i);
+	sink = 0;
+}
+
+__attribute__((noinline)) int other(int x)
+{
+	if (x > 10)
+		outer(x);
+	return sink + x;
+}
+
+int main(int argc, char** argv)
+{
+	outer(argc);
+	return other(argc);
+}
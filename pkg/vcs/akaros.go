@@ -52,7 +52,7 @@ func (ctx *akaros) ExtractFixTagsFromCommits(baseCommit, email string) ([]FixCom
 	return ctx.git.ExtractFixTagsFromCommits(baseCommit, email)
 }
 
-func (ctx *akaros) Bisect(bad, good string, trace io.Writer, pred func() (BisectResult, error)) (*Commit, error) {
+func (ctx *akaros) Bisect(bad, good string, trace io.Writer, pred func() (BisectResult, error)) ([]*Commit, error) {
 	return nil, fmt.Errorf("not implemented for akaros")
 }
 
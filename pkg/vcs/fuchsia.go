@@ -83,7 +83,7 @@ func (ctx *fuchsia) ExtractFixTagsFromCommits(baseCommit, email string) ([]FixCo
 	return ctx.zircon.ExtractFixTagsFromCommits(baseCommit, email)
 }
 
-func (ctx *fuchsia) Bisect(bad, good string, trace io.Writer, pred func() (BisectResult, error)) (*Commit, error) {
+func (ctx *fuchsia) Bisect(bad, good string, trace io.Writer, pred func() (BisectResult, error)) ([]*Commit, error) {
 	return nil, fmt.Errorf("not implemented for fuchsia")
 }
 
@@ -144,7 +144,7 @@ func (git *git) HeadCommit() (*Commit, error) {
 }
 
 func (git *git) getCommit(commit string) (*Commit, error) {
-	output, err := runSandboxed(git.dir, "git", "log", "--format=%H%n%s%n%ae%n%ad%n%b", "-n", "1", commit)
+	output, err := runSandboxed(git.dir, "git", "log", "--format=%H%n%s%n%ae%n%ad%n%P%n%b", "-n", "1", commit)
 	if err != nil {
 		return nil, err
 	}
@@ -153,7 +153,7 @@ func (git *git) getCommit(commit string) (*Commit, error) {
 
 func gitParseCommit(output []byte) (*Commit, error) {
 	lines := bytes.Split(output, []byte{'\n'})
-	if len(lines) < 4 || len(lines[0]) != 40 {
+	if len(lines) < 5 || len(lines[0]) != 40 {
 		return nil, fmt.Errorf("unexpected git log output: %q", output)
 	}
 	const dateFormat = "Mon Jan 2 15:04:05 2006 -0700"
@@ -161,9 +161,23 @@ func gitParseCommit(output []byte) (*Commit, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse date in git log output: %v\n%q", err, output)
 	}
+	com := &Commit{
+		Hash:    string(lines[0]),
+		Title:   string(lines[1]),
+		Author:  string(lines[2]),
+		CC:      extractCC(string(lines[2]), lines[5:]),
+		Date:    date,
+		Parents: strings.Fields(string(lines[4])),
+	}
+	return com, nil
+}
+
+// extractCC returns sorted list of the author and people mentioned in review/ack/test tags
+// in commit description lines.
+func extractCC(author string, lines [][]byte) []string {
 	cc := make(map[string]bool)
-	cc[strings.ToLower(string(lines[2]))] = true
-	for _, line := range lines[4:] {
+	cc[strings.ToLower(author)] = true
+	for _, line := range lines {
 		for _, re := range ccRes {
 			matches := re.FindSubmatchIndex(line)
 			if matches == nil {
@@ -182,14 +196,7 @@ func gitParseCommit(output []byte) (*Commit, error) {
 		sortedCC = append(sortedCC, addr)
 	}
 	sort.Strings(sortedCC)
-	com := &Commit{
-		Hash:   string(lines[0]),
-		Title:  string(lines[1]),
-		Author: string(lines[2]),
-		CC:     sortedCC,
-		Date:   date,
-	}
-	return com, nil
+	return sortedCC
 }
 
 func (git *git) ListRecentCommits(baseCommit string) ([]string, error) {
@@ -284,20 +291,19 @@ func splitEmail(email string) (user, domain string, err error) {
 	return
 }
 
-func (git *git) Bisect(bad, good string, trace io.Writer, pred func() (BisectResult, error)) (*Commit, error) {
+func (git *git) Bisect(bad, good string, trace io.Writer, pred func() (BisectResult, error)) ([]*Commit, error) {
 	dir := git.dir
 	runSandboxed(dir, "git", "bisect", "reset")
 	runSandboxed(dir, "git", "reset", "--hard")
-	firstBad, err := git.getCommit(bad)
-	if err != nil {
-		return nil, err
-	}
 	output, err := runSandboxed(dir, "git", "bisect", "start", bad, good)
 	if err != nil {
 		return nil, err
 	}
 	defer runSandboxed(dir, "git", "bisect", "reset")
 	fmt.Fprintf(trace, "# git bisect start %v %v\n%s", bad, good, output)
+	if hashes := gitParseBisectResult(output); len(hashes) != 0 {
+		return git.getCommits(hashes) // nothing to bisect
+	}
 	current, err := git.HeadCommit()
 	if err != nil {
 		return nil, err
@@ -312,25 +318,64 @@ func (git *git) Bisect(bad, good string, trace io.Writer, pred func() (BisectRes
 		if err != nil {
 			return nil, err
 		}
-		if res == BisectBad {
-			firstBad = current
-		}
 		output, err = runSandboxed(dir, "git", "bisect", bisectTerms[res])
+		if verr, ok := err.(*osutil.VerboseError); ok {
+			// git bisect exits with status 2 when only skipped commits are left.
+			output = verr.Output
+		}
+		fmt.Fprintf(trace, "# git bisect %v %v\n%s", bisectTerms[res], current.Hash, output)
+		if hashes := gitParseBisectResult(output); len(hashes) != 0 {
+			return git.getCommits(hashes)
+		}
 		if err != nil {
 			return nil, err
 		}
-		fmt.Fprintf(trace, "# git bisect %v %v\n%s", bisectTerms[res], current.Hash, output)
 		next, err := git.HeadCommit()
 		if err != nil {
 			return nil, err
 		}
 		if current.Hash == next.Hash {
-			return firstBad, nil
+			return nil, fmt.Errorf("git bisect did not finish and did not advance: %s", output)
 		}
 		current = next
 	}
 }
 
+// gitParseBisectResult returns the first bad commit or the candidates for the first bad commit
+// if git bisect output says that the bisection is finished.
+func gitParseBisectResult(output []byte) []string {
+	lines := strings.Split(string(output), "\n")
+	for i, line := range lines {
+		if strings.HasSuffix(line, " is the first bad commit") {
+			return []string{strings.TrimSuffix(line, " is the first bad commit")}
+		}
+		if line != "The first bad commit could be any of:" {
+			continue
+		}
+		var hashes []string
+		for _, hash := range lines[i+1:] {
+			if !gitHashRe.MatchString(hash) {
+				break
+			}
+			hashes = append(hashes, hash)
+		}
+		return hashes
+	}
+	return nil
+}
+
+func (git *git) getCommits(hashes []string) ([]*Commit, error) {
+	var commits []*Commit
+	for _, hash := range hashes {
+		com, err := git.getCommit(hash)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, com)
+	}
+	return commits, nil
+}
+
 // Note: linux-specific.
 func (git *git) PreviousReleaseTags(commit string) ([]string, error) {
 	output, err := runSandboxed(git.dir, "git", "tag", "--no-contains", commit, "--merged", commit, "v*.*")
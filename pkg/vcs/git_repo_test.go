@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 	"time"
 
@@ -86,6 +87,9 @@ func TestGitRepo(t *testing.T) {
 		if diff := cmp.Diff(com, want); diff != "" {
 			t.Fatal(diff)
 		}
+		if parent := repo2.commits["branch1"]["0"].Hash; len(com.Parents) != 1 || com.Parents[0] != parent {
+			t.Fatalf("got parents %q, want %q", com.Parents, parent)
+		}
 	}
 	{
 		com, err := repo.CheckoutBranch(repo2.dir, "branch2")
@@ -108,6 +112,70 @@ func TestGitRepo(t *testing.T) {
 	}
 }
 
+func TestGitBisect(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "syz-git-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	repo := makeTestRepo(t, filepath.Join(baseDir, "repo"))
+	repo.git("checkout", "-b", "master")
+	for i := 0; i < 10; i++ {
+		repo.commitFileChange("master", fmt.Sprint(i))
+	}
+	git := newGit(repo.dir)
+	guilty := repo.commits["master"]["6"]
+	commits, err := git.Bisect(repo.commits["master"]["9"].Hash, repo.commits["master"]["0"].Hash, ioutil.Discard,
+		testBisectPred(git, guilty, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(commits, []*Commit{guilty}); diff != "" {
+		t.Fatal(diff)
+	}
+	// Nothing to bisect.
+	commits, err = git.Bisect(guilty.Hash, repo.commits["master"]["5"].Hash, ioutil.Discard,
+		testBisectPred(git, guilty, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(commits, []*Commit{guilty}); diff != "" {
+		t.Fatal(diff)
+	}
+	// Skipped commits around the guilty one make the result ambiguous.
+	skip := []*Commit{repo.commits["master"]["5"], guilty, repo.commits["master"]["7"]}
+	commits, err = git.Bisect(repo.commits["master"]["9"].Hash, repo.commits["master"]["0"].Hash, ioutil.Discard,
+		testBisectPred(git, guilty, skip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(commits, func(i, j int) bool { return commits[i].Title < commits[j].Title })
+	want := []*Commit{repo.commits["master"]["5"], guilty, repo.commits["master"]["7"], repo.commits["master"]["8"]}
+	if diff := cmp.Diff(commits, want); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+// testBisectPred returns a bisection predicate that is bad starting from the guilty commit
+// (commits are compared by titles) and skips the skip commits.
+func testBisectPred(repo Repo, guilty *Commit, skip []*Commit) func() (BisectResult, error) {
+	return func() (BisectResult, error) {
+		current, err := repo.HeadCommit()
+		if err != nil {
+			return 0, err
+		}
+		for _, com := range skip {
+			if current.Hash == com.Hash {
+				return BisectSkip, nil
+			}
+		}
+		if current.Title >= guilty.Title {
+			return BisectBad, nil
+		}
+		return BisectGood, nil
+	}
+}
+
 func createTestRepo(t *testing.T, baseDir, name string) *testRepo {
 	repo := makeTestRepo(t, filepath.Join(baseDir, name))
 	repo.git("checkout", "-b", "master")
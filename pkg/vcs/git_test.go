@@ -18,6 +18,7 @@ func TestGitParseCommit(t *testing.T) {
 rbtree: include rcu.h
 foobar@foobar.de
 Fri May 11 16:02:14 2018 -0700
+0d5d3d2ebd0b2e8b1e4d4c26b24bd4f9e2b3cf8b
 Since commit c1adf20052d8 ("Introduce rb_replace_node_rcu()")
 rbtree_augmented.h uses RCU related data structures but does not include
 the header file.  It works as long as it gets somehow included before
@@ -44,7 +45,8 @@ Signed-off-by: Linux Master <linux@linux-foundation.org>
 				"subsystem@reviewer.com",
 				"yetanother@email.org",
 			},
-			Date: time.Date(2018, 5, 11, 16, 02, 14, 0, time.FixedZone("", -7*60*60)),
+			Date:    time.Date(2018, 5, 11, 16, 02, 14, 0, time.FixedZone("", -7*60*60)),
+			Parents: []string{"0d5d3d2ebd0b2e8b1e4d4c26b24bd4f9e2b3cf8b"},
 		},
 	}
 	for input, com := range tests {
@@ -73,6 +75,9 @@ Signed-off-by: Linux Master <linux@linux-foundation.org>
 		if !com.Date.Equal(res.Date) {
 			t.Fatalf("want date %v, got %v", com.Date, res.Date)
 		}
+		if !reflect.DeepEqual(com.Parents, res.Parents) {
+			t.Fatalf("want parents %q, got %q", com.Parents, res.Parents)
+		}
 	}
 }
 
@@ -121,6 +126,46 @@ v1.
 	}
 }
 
+func TestGitParseBisectResult(t *testing.T) {
+	tests := []struct {
+		output string
+		hashes []string
+	}{
+		{
+			output: `Bisecting: 2 revisions left to test after this (roughly 1 step)
+[be57d0103bfc9a1b9883c90b995f6dbb05651a0f] c2
+`,
+		},
+		{
+			output: `e0e21690d98a1d1d0701b46cfab858213595b39d is the first bad commit
+commit e0e21690d98a1d1d0701b46cfab858213595b39d
+Author: t <t@t>
+Date:   Sun Oct 18 18:42:34 2018 +0000
+
+    c4
+`,
+			hashes: []string{"e0e21690d98a1d1d0701b46cfab858213595b39d"},
+		},
+		{
+			output: `There are only 'skip'ped commits left to test.
+The first bad commit could be any of:
+aa3f2ad7e72d3bfd6b8882fc8c4208363b8ca847
+be57d0103bfc9a1b9883c90b995f6dbb05651a0f
+We cannot bisect more!
+`,
+			hashes: []string{
+				"aa3f2ad7e72d3bfd6b8882fc8c4208363b8ca847",
+				"be57d0103bfc9a1b9883c90b995f6dbb05651a0f",
+			},
+		},
+	}
+	for i, test := range tests {
+		if diff := cmp.Diff(gitParseBisectResult([]byte(test.output)), test.hashes); diff != "" {
+			t.Errorf("#%v: %v", i, diff)
+		}
+	}
+}
+
 func TestGitExtractFixTags(t *testing.T) {
 	commits, err := gitExtractFixTags(strings.NewReader(extractFixTagsInput), extractFixTagsEmail)
 	if err != nil {
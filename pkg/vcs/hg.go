@@ -0,0 +1,299 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package vcs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+// hg is Repo implementation for mercurial repositories.
+// Branches are mercurial named branches (or bookmarks/tags, anything that hg accepts as a revision).
+type hg struct {
+	dir string
+}
+
+func newHg(dir string) *hg {
+	return &hg{
+		dir: dir,
+	}
+}
+
+// NewHgRepo returns Repo for a mercurial repository in dir.
+func NewHgRepo(dir string) Repo {
+	return newHg(dir)
+}
+
+func (hg *hg) Poll(repo, branch string) (*Commit, error) {
+	hg.run("bisect", "--reset")
+	origin, err := hg.run("paths", "default")
+	if err != nil || strings.TrimSpace(string(origin)) != repo {
+		// The repo is here, but it has wrong origin (e.g. repo in config has changed), re-clone.
+		if err := hg.clone(repo); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := hg.run("pull"); err != nil {
+		// Something else is wrong, re-clone.
+		if err := hg.clone(repo); err != nil {
+			return nil, err
+		}
+		if _, err := hg.run("pull"); err != nil {
+			return nil, err
+		}
+	}
+	return hg.SwitchCommit(branch)
+}
+
+func (hg *hg) CheckoutBranch(repo, branch string) (*Commit, error) {
+	if err := hg.reset(); err != nil {
+		return nil, err
+	}
+	// Resolve the branch in the remote repo, because local branch heads
+	// can come from other repos that we pulled from.
+	output, err := hg.run("identify", "--debug", "--id", "-r", branch, repo)
+	if err != nil {
+		return nil, err
+	}
+	commit := strings.TrimSpace(string(output))
+	if _, err := hg.run("pull", "-r", commit, repo); err != nil {
+		return nil, err
+	}
+	return hg.SwitchCommit(commit)
+}
+
+func (hg *hg) CheckoutCommit(repo, commit string) (*Commit, error) {
+	if err := hg.reset(); err != nil {
+		return nil, err
+	}
+	if _, err := hg.run("pull", "-r", commit, repo); err != nil {
+		return nil, err
+	}
+	return hg.SwitchCommit(commit)
+}
+
+func (hg *hg) SwitchCommit(commit string) (*Commit, error) {
+	if _, err := hg.run("update", "--clean", "-r", commit); err != nil {
+		return nil, err
+	}
+	return hg.HeadCommit()
+}
+
+// reset discards any local state, or creates an empty repo if there is no valid repo.
+func (hg *hg) reset() error {
+	hg.run("bisect", "--reset")
+	if _, err := hg.run("update", "--clean"); err != nil {
+		return hg.initRepo()
+	}
+	return nil
+}
+
+func (hg *hg) clone(repo string) error {
+	if err := hg.initRepo(); err != nil {
+		return err
+	}
+	hgrc := fmt.Sprintf("[paths]\ndefault = %v\n", repo)
+	return osutil.WriteFile(filepath.Join(hg.dir, ".hg", "hgrc"), []byte(hgrc))
+}
+
+func (hg *hg) initRepo() error {
+	if err := os.RemoveAll(hg.dir); err != nil {
+		return fmt.Errorf("failed to remove repo dir: %v", err)
+	}
+	if err := osutil.MkdirAll(hg.dir); err != nil {
+		return fmt.Errorf("failed to create repo dir: %v", err)
+	}
+	if err := osutil.SandboxChown(hg.dir); err != nil {
+		return err
+	}
+	if _, err := hg.run("init"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (hg *hg) HeadCommit() (*Commit, error) {
+	return hg.getCommit(".")
+}
+
+func (hg *hg) getCommit(commit string) (*Commit, error) {
+	output, err := hg.run("log", "-r", commit, "--template",
+		"{node}\n{desc|firstline}\n{author|email}\n{date|isodatesec}\n{p1node} {p2node}\n{desc}\n")
+	if err != nil {
+		return nil, err
+	}
+	return hgParseCommit(output)
+}
+
+func hgParseCommit(output []byte) (*Commit, error) {
+	lines := bytes.Split(output, []byte{'\n'})
+	if len(lines) < 5 || len(lines[0]) != 40 {
+		return nil, fmt.Errorf("unexpected hg log output: %q", output)
+	}
+	const dateFormat = "2006-01-02 15:04:05 -0700"
+	date, err := time.Parse(dateFormat, string(lines[3]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date in hg log output: %v\n%q", err, output)
+	}
+	com := &Commit{
+		Hash:   string(lines[0]),
+		Title:  string(lines[1]),
+		Author: string(lines[2]),
+		CC:     extractCC(string(lines[2]), lines[5:]),
+		Date:   date,
+	}
+	// Missing parents are denoted by the null node.
+	for _, parent := range strings.Fields(string(lines[4])) {
+		if strings.Trim(parent, "0") != "" {
+			com.Parents = append(com.Parents, parent)
+		}
+	}
+	return com, nil
+}
+
+func (hg *hg) ListRecentCommits(baseCommit string) ([]string, error) {
+	output, err := hg.run("log", "-r", fmt.Sprintf("reverse(ancestors(%v))", baseCommit),
+		"--no-merges", "-l", "200000", "--template", "{desc|firstline}\n")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(output), "\n"), "\n"), nil
+}
+
+func (hg *hg) ExtractFixTagsFromCommits(baseCommit, email string) ([]FixCommit, error) {
+	since := time.Now().Add(-time.Hour * 24 * 365).Format("2006-01-02")
+	// Produce output in the git log format, so that we can reuse the git parser.
+	cmd := exec.Command("hg", "log", "--no-merges",
+		"-r", fmt.Sprintf("reverse(ancestors(%v)) and date('>%v')", baseCommit, since),
+		"--template", "commit {node}\n\n{indent(desc, '    ')}\n\n")
+	cmd.Dir = hg.dir
+	cmd.Env = hgEnv()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	return gitExtractFixTags(stdout, email)
+}
+
+func (hg *hg) Bisect(bad, good string, trace io.Writer, pred func() (BisectResult, error)) ([]*Commit, error) {
+	hg.run("bisect", "--reset")
+	hg.run("update", "--clean")
+	if _, err := hg.run("bisect", "--bad", bad); err != nil {
+		return nil, err
+	}
+	defer hg.run("bisect", "--reset")
+	output, err := hg.run("bisect", "--good", good)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(trace, "# hg bisect --bad %v --good %v\n%s", bad, good, output)
+	if hashes := hgParseBisectResult(output); len(hashes) != 0 {
+		return hg.getCommits(hashes) // nothing to bisect
+	}
+	current, err := hg.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	var bisectTerms = [...]string{
+		BisectBad:  "--bad",
+		BisectGood: "--good",
+		BisectSkip: "--skip",
+	}
+	for {
+		res, err := pred()
+		if err != nil {
+			return nil, err
+		}
+		output, err = hg.run("bisect", bisectTerms[res])
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(trace, "# hg bisect %v %v\n%s", bisectTerms[res], current.Hash, output)
+		if hashes := hgParseBisectResult(output); len(hashes) != 0 {
+			return hg.getCommits(hashes)
+		}
+		next, err := hg.HeadCommit()
+		if err != nil {
+			return nil, err
+		}
+		if current.Hash == next.Hash {
+			return nil, fmt.Errorf("hg bisect did not finish and did not advance: %s", output)
+		}
+		current = next
+	}
+}
+
+var hgChangesetRe = regexp.MustCompile(`^changeset:\s+[0-9]+:([0-9a-f]+)$`)
+
+// hgParseBisectResult returns the first bad revision or the candidates for the first bad revision
+// if hg bisect output says that the bisection is finished.
+func hgParseBisectResult(output []byte) []string {
+	lines := strings.Split(string(output), "\n")
+	for i, line := range lines {
+		if line != "The first bad revision is:" &&
+			line != "Due to skipped revisions, the first bad revision could be any of:" {
+			continue
+		}
+		// The revisions are printed as changeset/user/date/summary blocks separated by empty lines.
+		var hashes []string
+		for _, line := range lines[i+1:] {
+			if match := hgChangesetRe.FindStringSubmatch(line); match != nil {
+				hashes = append(hashes, match[1])
+			}
+		}
+		return hashes
+	}
+	return nil
+}
+
+func (hg *hg) getCommits(hashes []string) ([]*Commit, error) {
+	var commits []*Commit
+	for _, hash := range hashes {
+		com, err := hg.getCommit(hash)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, com)
+	}
+	return commits, nil
+}
+
+// Note: linux-specific.
+func (hg *hg) PreviousReleaseTags(commit string) ([]string, error) {
+	output, err := hg.run("log", "-r", fmt.Sprintf("ancestors(%v) and tag() and not %v", commit, commit),
+		"--template", "{join(tags, '\\n')}\n")
+	if err != nil {
+		return nil, err
+	}
+	return gitParseReleaseTags(output)
+}
+
+func (hg *hg) run(args ...string) ([]byte, error) {
+	cmd := osutil.Command("hg", args...)
+	cmd.Dir = hg.dir
+	cmd.Env = hgEnv()
+	if err := osutil.Sandbox(cmd, true, false); err != nil {
+		return nil, err
+	}
+	return osutil.Run(time.Hour, cmd)
+}
+
+func hgEnv() []string {
+	// HGPLAIN disables user configuration that can affect output (aliases, color, localization, etc).
+	return append(append([]string{}, os.Environ()...), "HGPLAIN=1")
+}
@@ -0,0 +1,225 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package vcs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+func TestHgRepo(t *testing.T) {
+	checkHg(t)
+	baseDir, err := ioutil.TempDir("", "syz-hg-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	repo1 := createTestHgRepo(t, baseDir, "repo1")
+	repo2 := createTestHgRepo(t, baseDir, "repo2")
+	repo := newHg(filepath.Join(baseDir, "repo"))
+	{
+		com, err := repo.Poll(repo1.dir, "master")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(com, repo1.commits["master"]["1"]); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+	{
+		com, err := repo.CheckoutBranch(repo1.dir, "branch1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(com, repo1.commits["branch1"]["1"]); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+	{
+		want := repo1.commits["branch1"]["0"]
+		com, err := repo.CheckoutCommit(repo1.dir, want.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(com, want); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+	{
+		commits, err := repo.ListRecentCommits(repo1.commits["branch1"]["1"].Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"repo1-branch1-1", "repo1-branch1-0", "repo1-master-0"}
+		if diff := cmp.Diff(commits, want); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+	{
+		want := repo2.commits["branch1"]["1"]
+		com, err := repo.CheckoutCommit(repo2.dir, want.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(com, want); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+	{
+		com, err := repo.CheckoutBranch(repo2.dir, "branch2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(com, repo2.commits["branch2"]["1"]); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+	{
+		want := repo2.commits["branch2"]["0"]
+		com, err := repo.SwitchCommit(want.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(com, want); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+	{
+		fixes, err := repo.ExtractFixTagsFromCommits(repo1.commits["master"]["1"].Hash, "syzbot@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []FixCommit{{"abcdef", "repo1-master-1"}}
+		if diff := cmp.Diff(fixes, want); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+}
+
+func TestHgBisect(t *testing.T) {
+	checkHg(t)
+	baseDir, err := ioutil.TempDir("", "syz-hg-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	repo := makeTestHgRepo(t, filepath.Join(baseDir, "repo"))
+	repo.hg("branch", "master")
+	for i := 0; i < 10; i++ {
+		repo.commitFileChange("master", fmt.Sprint(i))
+	}
+	repo.hg("tag", "-r", repo.commits["master"]["2"].Hash, "v4.1")
+	hg := newHg(repo.dir)
+	tags, err := hg.PreviousReleaseTags(repo.commits["master"]["5"].Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(tags, []string{"v4.1"}); diff != "" {
+		t.Fatal(diff)
+	}
+	guilty := repo.commits["master"]["6"]
+	commits, err := hg.Bisect(repo.commits["master"]["9"].Hash, repo.commits["master"]["0"].Hash, ioutil.Discard,
+		testBisectPred(hg, guilty, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(commits, []*Commit{guilty}); diff != "" {
+		t.Fatal(diff)
+	}
+	// Skipped revisions around the guilty one make the result ambiguous.
+	skip := []*Commit{repo.commits["master"]["5"], guilty, repo.commits["master"]["7"]}
+	commits, err = hg.Bisect(repo.commits["master"]["9"].Hash, repo.commits["master"]["0"].Hash, ioutil.Discard,
+		testBisectPred(hg, guilty, skip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(commits, func(i, j int) bool { return commits[i].Title < commits[j].Title })
+	want := []*Commit{repo.commits["master"]["5"], guilty, repo.commits["master"]["7"], repo.commits["master"]["8"]}
+	if diff := cmp.Diff(commits, want); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func checkHg(t *testing.T) {
+	if _, err := exec.LookPath("hg"); err != nil {
+		if os.Getenv("TRAVIS") != "" {
+			// CI installs mercurial (see install_prerequisites in Makefile), don't skip the tests silently.
+			t.Fatalf("hg is not found: %v", err)
+		}
+		t.Skipf("hg is not found: %v", err)
+	}
+}
+
+func createTestHgRepo(t *testing.T, baseDir, name string) *testHgRepo {
+	repo := makeTestHgRepo(t, filepath.Join(baseDir, name))
+	repo.hg("branch", "master")
+	repo.commitFileChange("master", "0")
+	for _, branch := range []string{"branch1", "branch2"} {
+		repo.hg("update", "--clean", "master")
+		repo.hg("branch", branch)
+		repo.commitFileChange(branch, "0")
+		repo.commitFileChange(branch, "1")
+	}
+	repo.hg("update", "--clean", "master")
+	repo.commitFileChange("master", "1", "Reported-by: syzbot+abcdef@example.com")
+	return repo
+}
+
+type testHgRepo struct {
+	t       *testing.T
+	dir     string
+	name    string
+	commits map[string]map[string]*Commit
+}
+
+func makeTestHgRepo(t *testing.T, dir string) *testHgRepo {
+	if err := osutil.MkdirAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	repo := &testHgRepo{
+		t:       t,
+		dir:     dir,
+		name:    filepath.Base(dir),
+		commits: make(map[string]map[string]*Commit),
+	}
+	repo.hg("init")
+	return repo
+}
+
+func (repo *testHgRepo) hg(args ...string) {
+	args = append([]string{"--config", "ui.username=Test <test@example.com>"}, args...)
+	if _, err := osutil.RunCmd(time.Minute, repo.dir, "hg", args...); err != nil {
+		repo.t.Fatal(err)
+	}
+}
+
+func (repo *testHgRepo) commitFileChange(branch, change string, body ...string) {
+	id := fmt.Sprintf("%v-%v-%v", repo.name, branch, change)
+	file := filepath.Join(repo.dir, "file")
+	if err := osutil.WriteFile(file, []byte(id)); err != nil {
+		repo.t.Fatal(err)
+	}
+	msg := id
+	for _, line := range body {
+		msg += "\n\n" + line
+	}
+	repo.hg("commit", "--addremove", "-m", msg)
+	if repo.commits[branch] == nil {
+		repo.commits[branch] = make(map[string]*Commit)
+	}
+	com, err := newHg(repo.dir).HeadCommit()
+	if err != nil {
+		repo.t.Fatal(err)
+	}
+	repo.commits[branch][change] = com
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package vcs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHgParseCommit(t *testing.T) {
+	input := `0b1c6c3d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b
+mm: fix foo
+foo@bar.com
+2018-05-11 16:02:14 -0700
+a2b1c6c3d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2 0000000000000000000000000000000000000000
+mm: fix foo
+
+Description.
+
+Reviewed-by: Reviewer <Reviewer@email.org>
+Signed-off-by: Foo Bar <foo@bar.com>
+`
+	com, err := hgParseCommit([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Commit{
+		Hash:    "0b1c6c3d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b",
+		Title:   "mm: fix foo",
+		Author:  "foo@bar.com",
+		CC:      []string{"foo@bar.com", "reviewer@email.org"},
+		Date:    time.Date(2018, 5, 11, 16, 02, 14, 0, time.FixedZone("", -7*60*60)),
+		Parents: []string{"a2b1c6c3d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2"},
+	}
+	if !com.Date.Equal(want.Date) {
+		t.Fatalf("want date %v, got %v", want.Date, com.Date)
+	}
+	com.Date = want.Date
+	if diff := cmp.Diff(com, want); diff != "" {
+		t.Fatal(diff)
+	}
+	if _, err := hgParseCommit([]byte("foo\nbar\n")); err == nil {
+		t.Fatalf("parsed bad hg log output")
+	}
+}
+
+func TestNewRepoType(t *testing.T) {
+	// The type must not depend on the current contents of dir (e.g. a fresh checkout dir).
+	if repo, err := NewRepo("linux", "qemu", TypeHg, "/nonexistent"); err != nil {
+		t.Fatal(err)
+	} else if _, ok := repo.(*hg); !ok {
+		t.Fatalf("got %T for hg repo type", repo)
+	}
+	for _, typ := range []string{"", TypeGit} {
+		if repo, err := NewRepo("linux", "qemu", typ, "/nonexistent"); err != nil {
+			t.Fatal(err)
+		} else if _, ok := repo.(*git); !ok {
+			t.Fatalf("got %T for repo type %q", repo, typ)
+		}
+	}
+	if _, err := NewRepo("linux", "qemu", "svn", "/nonexistent"); err == nil {
+		t.Fatalf("no error for unknown repo type")
+	}
+}
+
+func TestHgParseBisectResult(t *testing.T) {
+	tests := []struct {
+		output string
+		hashes []string
+	}{
+		{
+			output: `Testing changeset 4:5e2a8c1b0f3d (9 changesets remaining, ~3 tests)
+1 files updated, 0 files merged, 0 files removed, 0 files unresolved
+`,
+		},
+		{
+			output: `The first bad revision is:
+changeset:   6:9c2c8e4b5e7f
+user:        Test <test@example.com>
+date:        Thu Jan 01 00:00:00 1970 +0000
+summary:     repo-master-6
+
+`,
+			hashes: []string{"9c2c8e4b5e7f"},
+		},
+		{
+			output: `Due to skipped revisions, the first bad revision could be any of:
+changeset:   5:1a2b3c4d5e6f
+user:        Test <test@example.com>
+date:        Thu Jan 01 00:00:00 1970 +0000
+summary:     repo-master-5
+
+changeset:   6:9c2c8e4b5e7f
+user:        Test <test@example.com>
+date:        Thu Jan 01 00:00:00 1970 +0000
+summary:     repo-master-6
+
+`,
+			hashes: []string{"1a2b3c4d5e6f", "9c2c8e4b5e7f"},
+		},
+	}
+	for i, test := range tests {
+		if diff := cmp.Diff(hgParseBisectResult([]byte(test.output)), test.hashes); diff != "" {
+			t.Errorf("#%v: %v", i, diff)
+		}
+	}
+}
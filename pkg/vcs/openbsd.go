@@ -46,7 +46,7 @@ func (ctx *openbsd) ExtractFixTagsFromCommits(baseCommit, email string) ([]FixCo
 	return ctx.git.ExtractFixTagsFromCommits(baseCommit, email)
 }
 
-func (ctx *openbsd) Bisect(bad, good string, trace io.Writer, pred func() (BisectResult, error)) (*Commit, error) {
+func (ctx *openbsd) Bisect(bad, good string, trace io.Writer, pred func() (BisectResult, error)) ([]*Commit, error) {
 	return nil, fmt.Errorf("not implemented for openbsd")
 }
 
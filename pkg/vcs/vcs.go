@@ -48,16 +48,18 @@ type Repo interface {
 	// The predicate should return an error only if there is no way to proceed
 	// (it will abort the process), if possible it should prefer to return BisectSkip.
 	// Progress of the process is streamed to the provided trace.
-	// Returns the first commit on which the predicate returns BisectBad.
-	Bisect(bad, good string, trace io.Writer, pred func() (BisectResult, error)) (*Commit, error)
+	// Returns the first commit on which the predicate returns BisectBad,
+	// or several candidate commits if the first bad commit is ambiguous due to skipped commits.
+	Bisect(bad, good string, trace io.Writer, pred func() (BisectResult, error)) ([]*Commit, error)
 }
 
 type Commit struct {
-	Hash   string
-	Title  string
-	Author string
-	CC     []string
-	Date   time.Time
+	Hash    string
+	Title   string
+	Author  string
+	CC      []string
+	Date    time.Time
+	Parents []string // hashes of parent commits
 }
 
 type FixCommit struct {
@@ -73,7 +75,35 @@ const (
 	BisectSkip
 )
 
-func NewRepo(os, vm, dir string) (Repo, error) {
+func (res BisectResult) String() string {
+	switch res {
+	case BisectBad:
+		return "bad"
+	case BisectGood:
+		return "good"
+	case BisectSkip:
+		return "skip"
+	default:
+		return fmt.Sprintf("BisectResult(%d)", int(res))
+	}
+}
+
+// Supported types of kernel repositories (see NewRepo).
+const (
+	TypeGit = "git"
+	TypeHg  = "hg"
+)
+
+// NewRepo returns Repo of type typ (TypeGit or TypeHg) for the kernel checkout in dir.
+// Empty typ means the default for the OS (git-based).
+func NewRepo(os, vm, typ, dir string) (Repo, error) {
+	switch typ {
+	case TypeHg:
+		return newHg(dir), nil
+	case "", TypeGit:
+	default:
+		return nil, fmt.Errorf("unknown repo type %q, supported: %v, %v", typ, TypeGit, TypeHg)
+	}
 	switch os {
 	case "linux":
 		return newGit(dir), nil
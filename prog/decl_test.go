@@ -109,3 +109,34 @@ func TestClockGettime(t *testing.T) {
 			len(calls), len(trans), len(disabled))
 	}
 }
+
+func TestExplainDisabledCalls(t *testing.T) {
+	t.Parallel()
+	target, err := GetTarget("test", "64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := make(map[*Syscall]bool)
+	for _, c := range target.Syscalls {
+		calls[c] = true
+	}
+	reasons := make(map[*Syscall]string)
+	for _, c := range target.calcResourceCtors([]string{"fd"}, true) {
+		reasons[c] = "ENOSYS"
+		delete(calls, c)
+	}
+	trans, _ := target.TransitivelyEnabledCalls(calls)
+	explained := target.ExplainDisabledCalls(calls, trans, reasons)
+	for c, reason := range explained {
+		t.Logf("%v: %v", c.Name, reason)
+	}
+	want := "needs fd, which can only be created by "
+	if reason := explained[target.SyscallMap["fallback$1"]]; !strings.HasPrefix(reason, want) ||
+		!strings.Contains(reason, "test$res2 (ENOSYS)") {
+		t.Fatalf("wrong explanation: %v", reason)
+	}
+	want = "needs unsupported, which can only be created by unsupported$0 (cyclic dependency)"
+	if reason := explained[target.SyscallMap["unsupported$0"]]; !strings.HasPrefix(reason, want) {
+		t.Fatalf("wrong explanation: %v", reason)
+	}
+}
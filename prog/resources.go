@@ -5,6 +5,7 @@ package prog
 
 import (
 	"fmt"
+	"strings"
 )
 
 // We need to support structs as resources,
@@ -188,3 +189,96 @@ func (target *Target) TransitivelyEnabledCalls(enabled map[*Syscall]bool) (map[*
 	}
 	return supported, disabled
 }
+
+// ExplainDisabledCalls returns detailed reasons for calls that are present in enabled,
+// but are disabled transitively (not present in supported returned by TransitivelyEnabledCalls).
+// Unlike TransitivelyEnabledCalls reasons, these include the whole chain: which resource is missing,
+// what calls can create it and why each of them is disabled (recursively).
+// reasons contains reasons for calls that are disabled on their own (not present in enabled),
+// e.g. because they are not supported by the kernel.
+func (target *Target) ExplainDisabledCalls(enabled, supported map[*Syscall]bool,
+	reasons map[*Syscall]string) map[*Syscall]string {
+	e := &disabledExplainer{
+		target:    target,
+		enabled:   enabled,
+		supported: supported,
+		reasons:   reasons,
+		canCreate: make(map[string]bool),
+		ctors:     make(map[string][]*Syscall),
+		visiting:  make(map[string]bool),
+	}
+	for c := range supported {
+		for _, res := range target.outputResources(c) {
+			for _, kind := range res.Kind {
+				e.canCreate[kind] = true
+			}
+		}
+	}
+	explained := make(map[*Syscall]string)
+	for c := range enabled {
+		if !supported[c] {
+			explained[c] = e.explain(c, 0)
+		}
+	}
+	return explained
+}
+
+type disabledExplainer struct {
+	target    *Target
+	enabled   map[*Syscall]bool
+	supported map[*Syscall]bool
+	reasons   map[*Syscall]string
+	canCreate map[string]bool
+	ctors     map[string][]*Syscall
+	visiting  map[string]bool // resources that we are currently explaining
+}
+
+const (
+	// Limits on the explanation size, there can be lots of ctors for popular resources.
+	maxExplainDepth = 3
+	maxExplainCtors = 5
+)
+
+func (e *disabledExplainer) explain(c *Syscall, depth int) string {
+	if !e.enabled[c] {
+		if reason := e.reasons[c]; reason != "" {
+			return reason
+		}
+		return "disabled"
+	}
+	var res *ResourceDesc
+	for _, r := range e.target.inputResources(c) {
+		if !e.canCreate[r.Name] {
+			res = r
+			break
+		}
+	}
+	if res == nil {
+		return "enabled"
+	}
+	ctors, ok := e.ctors[res.Name]
+	if !ok {
+		ctors = e.target.calcResourceCtors(res.Kind, true)
+		e.ctors[res.Name] = ctors
+	}
+	if len(ctors) == 0 {
+		return fmt.Sprintf("needs %v, which no syscall can create", res.Name)
+	}
+	if e.visiting[res.Name] {
+		return "cyclic dependency"
+	}
+	if depth >= maxExplainDepth {
+		return fmt.Sprintf("needs %v", res.Name)
+	}
+	e.visiting[res.Name] = true
+	defer delete(e.visiting, res.Name)
+	var explanations []string
+	for i, ctor := range ctors {
+		if i == maxExplainCtors {
+			explanations = append(explanations, fmt.Sprintf("and %v more", len(ctors)-i))
+			break
+		}
+		explanations = append(explanations, fmt.Sprintf("%v (%v)", ctor.Name, e.explain(ctor, depth+1)))
+	}
+	return fmt.Sprintf("needs %v, which can only be created by %v", res.Name, strings.Join(explanations, ", "))
+}
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/google/syzkaller/dashboard/dashapi"
+	"github.com/google/syzkaller/pkg/bisect"
 	"github.com/google/syzkaller/pkg/build"
 	"github.com/google/syzkaller/pkg/instance"
 	"github.com/google/syzkaller/pkg/log"
@@ -27,6 +29,8 @@ type JobProcessor struct {
 	dash            *dashapi.Dashboard
 	syzkallerRepo   string
 	syzkallerBranch string
+	buildCache      *build.Cache
+	bisectBinDir    string
 }
 
 func newJobProcessor(cfg *Config, managers []*Manager, stop chan struct{}) *JobProcessor {
@@ -36,6 +40,8 @@ func newJobProcessor(cfg *Config, managers []*Manager, stop chan struct{}) *JobP
 		stop:            stop,
 		syzkallerRepo:   cfg.SyzkallerRepo,
 		syzkallerBranch: cfg.SyzkallerBranch,
+		buildCache:      cfg.buildCache,
+		bisectBinDir:    cfg.BisectBinDir,
 	}
 	if cfg.DashboardAddr != "" && cfg.DashboardClient != "" {
 		jp.dash = dashapi.New(cfg.DashboardClient, cfg.DashboardAddr, cfg.DashboardKey)
@@ -61,11 +67,14 @@ func (jp *JobProcessor) loop() {
 }
 
 func (jp *JobProcessor) poll() {
-	var names []string
+	var names, bisectNames []string
 	for _, mgr := range jp.managers {
 		names = append(names, mgr.name)
 	}
-	req, err := jp.dash.JobPoll(names)
+	if jp.bisectBinDir != "" {
+		bisectNames = names
+	}
+	req, err := jp.dash.JobPoll(names, bisectNames)
 	if err != nil {
 		jp.Errorf("failed to poll jobs: %v", err)
 		return
@@ -146,6 +155,13 @@ func (jp *JobProcessor) process(job *Job) *dashapi.JobDoneReq {
 		{"reproducer options", len(req.ReproOpts) != 0},
 		{"reproducer program", len(req.ReproSyz) != 0},
 	}
+	bisection := req.Type == dashapi.JobBisectCause || req.Type == dashapi.JobBisectFix
+	if bisection {
+		required = append(required, struct {
+			name string
+			ok   bool
+		}{"kernel commit", req.KernelCommit != ""})
+	}
 	for _, req := range required {
 		if !req.ok {
 			job.resp.Error = []byte(req.name + " is empty")
@@ -158,7 +174,11 @@ func (jp *JobProcessor) process(job *Job) *dashapi.JobDoneReq {
 		jp.Errorf("%s", job.resp.Error)
 		return job.resp
 	}
-	if err := jp.test(job); err != nil {
+	run := jp.test
+	if bisection {
+		run = jp.bisect
+	}
+	if err := run(job); err != nil {
 		job.resp.Error = []byte(err.Error())
 	}
 	return job.resp
@@ -191,7 +211,7 @@ func (jp *JobProcessor) test(job *Job) error {
 	}
 
 	log.Logf(0, "job: fetching kernel...")
-	repo, err := vcs.NewRepo(mgrcfg.TargetOS, mgrcfg.Type, kernelDir)
+	repo, err := vcs.NewRepo(mgrcfg.TargetOS, mgrcfg.Type, mgr.mgrcfg.RepoType, kernelDir)
 	if err != nil {
 		return fmt.Errorf("failed to create kernel repo: %v", err)
 	}
@@ -214,7 +234,7 @@ func (jp *JobProcessor) test(job *Job) error {
 	resp.Build.KernelCommitTitle = kernelCommit.Title
 	resp.Build.KernelCommitDate = kernelCommit.Date
 
-	if err := build.Clean(mgrcfg.TargetOS, mgrcfg.TargetVMArch, mgrcfg.Type, kernelDir); err != nil {
+	if err := build.Clean(mgrcfg.TargetOS, mgrcfg.TargetVMArch, mgrcfg.Type, kernelDir, mgr.mgrcfg.Build); err != nil {
 		return fmt.Errorf("kernel clean failed: %v", err)
 	}
 	if len(req.Patch) != 0 {
@@ -224,11 +244,17 @@ func (jp *JobProcessor) test(job *Job) error {
 	}
 
 	log.Logf(0, "job: building kernel...")
-	if err := env.BuildKernel(mgr.mgrcfg.Compiler, mgr.mgrcfg.Userspace, mgr.mgrcfg.KernelCmdline,
-		mgr.mgrcfg.KernelSysctl, req.KernelConfig); err != nil {
+	cached, err := env.BuildKernelCached(jp.buildCache, kernelCommit.Hash, req.Patch, mgr.mgrcfg.Compiler,
+		mgr.mgrcfg.Userspace, mgr.mgrcfg.KernelCmdline, mgr.mgrcfg.KernelSysctl, req.KernelConfig,
+		mgr.mgrcfg.Build)
+	if err != nil {
 		return err
 	}
-	resp.Build.KernelConfig, err = ioutil.ReadFile(filepath.Join(mgrcfg.KernelSrc, ".config"))
+	if cached {
+		log.Logf(0, "job: using cached kernel build (%v)", jp.buildCache.Stats())
+	}
+	// Note: the kernel tree does not contain the config if the kernel was taken from the cache.
+	resp.Build.KernelConfig, err = ioutil.ReadFile(filepath.Join(mgrcfg.Workdir, "image", "kernel.config"))
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %v", err)
 	}
@@ -276,6 +302,80 @@ func (jp *JobProcessor) test(job *Job) error {
 	return anyErr
 }
 
+func (jp *JobProcessor) bisect(job *Job) error {
+	req, resp, mgr := job.req, job.resp, job.mgr
+	if jp.bisectBinDir == "" {
+		return fmt.Errorf("bisection is not enabled on this instance")
+	}
+
+	dir := osutil.Abs(filepath.Join("jobs", mgr.managercfg.TargetOS))
+	mgrcfg := new(mgrconfig.Config)
+	*mgrcfg = *mgr.managercfg
+	mgrcfg.Name += "-job"
+	mgrcfg.Workdir = filepath.Join(dir, "workdir")
+	mgrcfg.KernelSrc = filepath.Join(dir, "kernel")
+	mgrcfg.Syzkaller = filepath.Join(dir, "gopath", "src", "github.com", "google", "syzkaller")
+	os.RemoveAll(mgrcfg.Workdir)
+	defer os.RemoveAll(mgrcfg.Workdir)
+
+	trace := new(bytes.Buffer)
+	cfg := &bisect.Config{
+		Trace:  trace,
+		Fix:    req.Type == dashapi.JobBisectFix,
+		BinDir: jp.bisectBinDir,
+		Kernel: bisect.KernelConfig{
+			Repo:      req.KernelRepo,
+			RepoType:  mgr.mgrcfg.RepoType,
+			Branch:    req.KernelBranch,
+			Commit:    req.KernelCommit,
+			Cmdline:   mgr.mgrcfg.KernelCmdline,
+			Sysctl:    mgr.mgrcfg.KernelSysctl,
+			Config:    req.KernelConfig,
+			Userspace: mgr.mgrcfg.Userspace,
+			Build:     mgr.mgrcfg.Build,
+		},
+		Syzkaller: bisect.SyzkallerConfig{
+			Repo:   jp.syzkallerRepo,
+			Commit: req.SyzkallerCommit,
+		},
+		Repro: bisect.ReproConfig{
+			Opts: req.ReproOpts,
+			Syz:  req.ReproSyz,
+			C:    req.ReproC,
+		},
+		Manager:    *mgrcfg,
+		BuildCache: jp.buildCache,
+	}
+	resp.Build.KernelCommit = req.KernelCommit
+	resp.Build.SyzkallerCommit = req.SyzkallerCommit
+	log.Logf(0, "job: bisecting...")
+	res, err := bisect.Run(cfg)
+	if err != nil {
+		resp.Bisect = &dashapi.BisectResult{Log: trace.Bytes()}
+		return err
+	}
+	resp.Bisect = &dashapi.BisectResult{
+		Confidence: res.Confidence,
+		Log:        trace.Bytes(),
+	}
+	if res.Commit != nil {
+		resp.Bisect.Commit = res.Commit.Hash
+		resp.Bisect.CommitTitle = res.Commit.Title
+		resp.Bisect.CC = res.Commit.CC
+	}
+	for _, step := range res.Steps {
+		resp.Bisect.Steps = append(resp.Bisect.Steps, dashapi.BisectStep{
+			Commit:  step.Commit,
+			Title:   step.Title,
+			Result:  step.Result.String(),
+			Crashed: step.Crashed,
+			Total:   step.Total,
+			Titles:  step.Titles,
+		})
+	}
+	return nil
+}
+
 // Errorf logs non-fatal error and sends it to dashboard.
 func (jp *JobProcessor) Errorf(msg string, args ...interface{}) {
 	log.Logf(0, "job: "+msg, args...)
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -103,12 +104,21 @@ func createManager(cfg *Config, mgrcfg *ManagerConfig, stop chan struct{}) *Mana
 			log.Fatal(err)
 		}
 	}
+	configTag := hash.String(configData)
+	if mgrcfg.Build != nil {
+		// Changes in the build commands must trigger kernel rebuild, so include them into the tag.
+		buildData, err := json.Marshal(mgrcfg.Build)
+		if err != nil {
+			log.Fatal(err)
+		}
+		configTag = hash.String(configData, buildData)
+	}
 	syzkallerCommit, _ := readTag(filepath.FromSlash("syzkaller/current/tag"))
 	if syzkallerCommit == "" {
 		log.Fatalf("no tag in syzkaller/current/tag")
 	}
 	kernelDir := filepath.Join(dir, "kernel")
-	repo, err := vcs.NewRepo(mgrcfg.managercfg.TargetOS, mgrcfg.managercfg.Type, kernelDir)
+	repo, err := vcs.NewRepo(mgrcfg.managercfg.TargetOS, mgrcfg.managercfg.Type, mgrcfg.RepoType, kernelDir)
 	if err != nil {
 		log.Fatalf("failed to create repo for %v: %v", mgrcfg.Name, err)
 	}
@@ -121,7 +131,7 @@ func createManager(cfg *Config, mgrcfg *ManagerConfig, stop chan struct{}) *Mana
 		latestDir:       filepath.Join(dir, "latest"),
 		compilerID:      compilerID,
 		syzkallerCommit: syzkallerCommit,
-		configTag:       hash.String(configData),
+		configTag:       configTag,
 		configData:      configData,
 		cfg:             cfg,
 		repo:            repo,
@@ -292,22 +302,46 @@ func (mgr *Manager) build(kernelCommit *vcs.Commit) error {
 	if err := osutil.MkdirAll(tmpDir); err != nil {
 		return fmt.Errorf("failed to create tmp dir: %v", err)
 	}
-	if err := config.SaveFile(filepath.Join(tmpDir, "tag"), info); err != nil {
-		return fmt.Errorf("failed to write tag file: %v", err)
+	cacheKey := &build.CacheKey{
+		TargetOS:   mgr.managercfg.TargetOS,
+		TargetArch: mgr.managercfg.TargetVMArch,
+		VMType:     mgr.managercfg.Type,
+		Commit:     kernelCommit.Hash,
+		CompilerID: mgr.compilerID,
+		Config:     mgr.configData,
+		Userspace:  mgr.mgrcfg.Userspace,
+		Cmdline:    mgr.mgrcfg.KernelCmdline,
+		Sysctl:     mgr.mgrcfg.KernelSysctl,
+		Command:    mgr.mgrcfg.Build,
+	}
+	cached, err := mgr.getCachedBuild(cacheKey, tmpDir)
+	if err != nil {
+		return err
 	}
-	if err := build.Image(mgr.managercfg.TargetOS, mgr.managercfg.TargetVMArch, mgr.managercfg.Type,
-		mgr.kernelDir, tmpDir, mgr.mgrcfg.Compiler, mgr.mgrcfg.Userspace,
-		mgr.mgrcfg.KernelCmdline, mgr.mgrcfg.KernelSysctl, mgr.configData); err != nil {
-		if _, ok := err.(build.KernelBuildError); ok {
-			rep := &report.Report{
-				Title:  fmt.Sprintf("%v build error", mgr.mgrcfg.RepoAlias),
-				Output: []byte(err.Error()),
+	if !cached {
+		if err := build.Image(mgr.managercfg.TargetOS, mgr.managercfg.TargetVMArch, mgr.managercfg.Type,
+			mgr.kernelDir, tmpDir, mgr.mgrcfg.Compiler, mgr.mgrcfg.Userspace,
+			mgr.mgrcfg.KernelCmdline, mgr.mgrcfg.KernelSysctl, mgr.configData, mgr.mgrcfg.Build); err != nil {
+			if _, ok := err.(build.KernelBuildError); ok {
+				rep := &report.Report{
+					Title:  fmt.Sprintf("%v build error", mgr.mgrcfg.RepoAlias),
+					Output: []byte(err.Error()),
+				}
+				if err := mgr.reportBuildError(rep, info, tmpDir); err != nil {
+					mgr.Errorf("failed to report image error: %v", err)
+				}
 			}
-			if err := mgr.reportBuildError(rep, info, tmpDir); err != nil {
-				mgr.Errorf("failed to report image error: %v", err)
+			return fmt.Errorf("kernel build failed: %v", err)
+		}
+		if cache := mgr.cfg.buildCache; cache != nil {
+			if err := cache.Put(cacheKey, tmpDir); err != nil {
+				mgr.Errorf("failed to store kernel build in cache: %v", err)
 			}
 		}
-		return fmt.Errorf("kernel build failed: %v", err)
+	}
+	// Tag is written after the build, so that it's not stored in and restored from the build cache.
+	if err := config.SaveFile(filepath.Join(tmpDir, "tag"), info); err != nil {
+		return fmt.Errorf("failed to write tag file: %v", err)
 	}
 
 	if err := mgr.testImage(tmpDir, info); err != nil {
@@ -321,6 +355,22 @@ func (mgr *Manager) build(kernelCommit *vcs.Commit) error {
 	return osutil.Rename(tmpDir, mgr.latestDir)
 }
 
+// getCachedBuild copies the kernel build for key from the build cache into dir, if present.
+func (mgr *Manager) getCachedBuild(key *build.CacheKey, dir string) (bool, error) {
+	cache := mgr.cfg.buildCache
+	if cache == nil {
+		return false, nil
+	}
+	cached, err := cache.Get(key, dir)
+	if err != nil {
+		return false, fmt.Errorf("failed to get kernel build from cache: %v", err)
+	}
+	if cached {
+		log.Logf(0, "%v: using cached kernel build (%v)", mgr.name, cache.Stats())
+	}
+	return cached, nil
+}
+
 func (mgr *Manager) restartManager() {
 	if !osutil.FilesExist(mgr.latestDir, imageFiles) {
 		mgr.Errorf("can't start manager, image files missing")
@@ -63,10 +63,12 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/google/syzkaller/pkg/build"
 	"github.com/google/syzkaller/pkg/config"
 	"github.com/google/syzkaller/pkg/log"
 	"github.com/google/syzkaller/pkg/mgrconfig"
 	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/pkg/vcs"
 )
 
 var flagConfig = flag.String("config", "", "config file")
@@ -89,8 +91,16 @@ type Config struct {
 	// GCS path to upload coverage reports from managers (optional).
 	CoverUploadPath string `json:"cover_upload_path"`
 	// Enable patch testing jobs.
-	EnableJobs bool             `json:"enable_jobs"`
-	Managers   []*ManagerConfig `json:"managers"`
+	EnableJobs bool `json:"enable_jobs"`
+	// Dir with cache of kernel builds shared by managers and jobs (optional).
+	// Kernels built for the same commit, config and compiler are reused from the cache.
+	BuildCache string `json:"build_cache"`
+	// Max size of the build cache in MB (optional, unlimited by default).
+	BuildCacheSize int `json:"build_cache_size"`
+	// Dir with compilers used for bisection (see pkg/bisect), enables bisection jobs (optional).
+	BisectBinDir string           `json:"bisect_bin_dir"`
+	Managers     []*ManagerConfig `json:"managers"`
+	buildCache   *build.Cache
 }
 
 type ManagerConfig struct {
@@ -98,17 +108,23 @@ type ManagerConfig struct {
 	DashboardClient string `json:"dashboard_client"`
 	DashboardKey    string `json:"dashboard_key"`
 	Repo            string `json:"repo"`
+	// Type of the repo: "git" (default) or "hg".
+	RepoType string `json:"repo_type"`
 	// Short name of the repo (e.g. "linux-next"), used only for reporting.
 	RepoAlias    string `json:"repo_alias"`
-	Branch       string `json:"branch"` // Defaults to "master".
+	Branch       string `json:"branch"` // Defaults to "master" for git and "default" for hg.
 	Compiler     string `json:"compiler"`
 	Userspace    string `json:"userspace"`
 	KernelConfig string `json:"kernel_config"`
 	// File with kernel cmdline values (optional).
 	KernelCmdline string `json:"kernel_cmdline"`
 	// File with sysctl values (e.g. output of sysctl -a, optional).
-	KernelSysctl  string          `json:"kernel_sysctl"`
-	ManagerConfig json.RawMessage `json:"manager_config"`
+	KernelSysctl string `json:"kernel_sysctl"`
+	// Custom kernel build commands (optional), see build.CommandConfig.
+	// If specified, used instead of the built-in build procedure for the target OS.
+	// Allows to build kernels with out-of-tree cross toolchains and custom make targets.
+	Build         *build.CommandConfig `json:"build"`
+	ManagerConfig json.RawMessage      `json:"manager_config"`
 	managercfg    *mgrconfig.Config
 }
 
@@ -120,6 +136,13 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
+	if cfg.BuildCache != "" {
+		cfg.buildCache, err = build.NewCache(osutil.Abs(cfg.BuildCache), int64(cfg.BuildCacheSize)<<20)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	shutdownPending := make(chan struct{})
 	osutil.HandleInterrupts(shutdownPending)
 
@@ -218,12 +241,24 @@ func loadConfig(filename string) (*Config, error) {
 	if len(cfg.Managers) == 0 {
 		return nil, fmt.Errorf("no managers specified")
 	}
+	if cfg.BuildCacheSize < 0 {
+		return nil, fmt.Errorf("param 'build_cache_size' is negative")
+	}
 	for i, mgr := range cfg.Managers {
 		if mgr.Name == "" {
 			return nil, fmt.Errorf("param 'managers[%v].name' is empty", i)
 		}
-		if mgr.Branch == "" {
-			mgr.Branch = "master"
+		switch mgr.RepoType {
+		case "", vcs.TypeGit:
+			if mgr.Branch == "" {
+				mgr.Branch = "master"
+			}
+		case vcs.TypeHg:
+			if mgr.Branch == "" {
+				mgr.Branch = "default"
+			}
+		default:
+			return nil, fmt.Errorf("manager %v: unknown repo_type %q", mgr.Name, mgr.RepoType)
 		}
 		managercfg, err := mgrconfig.LoadPartialData(mgr.ManagerConfig)
 		if err != nil {
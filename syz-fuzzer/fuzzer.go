@@ -42,6 +42,7 @@ type Fuzzer struct {
 
 	faultInjectionEnabled    bool
 	comparisonTracingEnabled bool
+	coverFilter              *signal.Filter // restricts signal to manager-selected code, if not nil
 
 	corpusMu     sync.RWMutex
 	corpus       []*prog.Prog
@@ -52,9 +53,15 @@ type Fuzzer struct {
 	maxSignal    signal.Signal // max signal ever observed including flakes
 	newSignal    signal.Signal // diff of maxSignal since last sync with master
 
+	slowCallsMu sync.Mutex
+	slowCalls   map[string]*rpctype.SlowCallStat // since last sync with master
+
 	logMu sync.Mutex
 }
 
+// Calls that take longer than this are considered slow and reported to manager.
+const slowCallThreshold = 100 * time.Millisecond
+
 type Stat int
 
 const (
@@ -66,6 +73,7 @@ const (
 	StatSmash
 	StatHint
 	StatSeed
+	StatFilter
 	StatCount
 )
 
@@ -78,6 +86,7 @@ var statNames = [StatCount]string{
 	StatSmash:     "exec smash",
 	StatHint:      "exec hints",
 	StatSeed:      "exec seeds",
+	StatFilter:    "exec cover filter",
 }
 
 type OutputType int
@@ -102,6 +111,10 @@ func main() {
 		flagPprof   = flag.String("pprof", "", "address to serve pprof profiles")
 		flagTest    = flag.Bool("test", false, "enable image testing mode")      // used by syz-ci
 		flagRunTest = flag.Bool("runtest", false, "enable program testing mode") // used by pkg/runtest
+		// Used by vm/qemu in snapshot mode.
+		flagSnapshotMonitor = flag.String("snapshot_monitor", "", "qemu monitor socket to restore VM snapshots")
+		flagSnapshotEvery   = flag.Int("snapshot_every", 1, "restore VM snapshot every that many programs")
+		flagSnapshotConn    = flag.String("snapshot_conn", "", "unix socket connected to executor in the VM")
 	)
 	flag.Parse()
 	outputType := parseOutputType(*flagOutput)
@@ -116,6 +129,19 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to create default ipc config: %v", err)
 	}
+	if *flagSnapshotMonitor != "" {
+		// Restoring the snapshot affects all executors running in the VM.
+		if *flagProcs != 1 {
+			log.Fatalf("snapshot mode requires procs=1, got %v", *flagProcs)
+		}
+		// In snapshot mode we run on host and talk to executor over a connection
+		// that survives snapshot restore, shared memory is emulated over this connection.
+		if *flagSnapshotConn == "" {
+			log.Fatalf("snapshot mode requires executor connection")
+		}
+		config.Flags |= ipc.FlagRemote
+		config.ExecutorConn = *flagSnapshotConn
+	}
 	sandbox := ipc.FlagsToSandbox(config.Flags)
 	shutdown := make(chan struct{})
 	osutil.HandleInterrupts(shutdown)
@@ -131,6 +157,7 @@ func main() {
 		sandbox:     sandbox,
 		ipcConfig:   config,
 		ipcExecOpts: execOpts,
+		remote:      *flagSnapshotMonitor != "",
 	}
 	if *flagTest {
 		testImage(*flagManager, checkArgs)
@@ -156,11 +183,13 @@ func main() {
 	if err := manager.Call("Manager.Connect", a, r); err != nil {
 		log.Fatalf("failed to connect to manager: %v ", err)
 	}
+	config.SignalMode = r.SignalMode
 	if r.CheckResult == nil {
 		checkArgs.gitRevision = r.GitRevision
 		checkArgs.targetRevision = r.TargetRevision
 		checkArgs.enabledCalls = r.EnabledCalls
 		checkArgs.allSandboxes = r.AllSandboxes
+		checkArgs.featureOverrides = r.FeatureOverrides
 		r.CheckResult, err = checkMachine(checkArgs)
 		if err != nil {
 			r.CheckResult = &rpctype.CheckArgs{
@@ -179,9 +208,12 @@ func main() {
 	for _, feat := range r.CheckResult.Features {
 		log.Logf(0, "%v: %v", feat.Name, feat.Reason)
 	}
-	periodicCallback, err := host.Setup(target, r.CheckResult.Features)
-	if err != nil {
-		log.Fatalf("BUG: %v", err)
+	var periodicCallback func(leakFrames [][]byte)
+	if !checkArgs.remote {
+		periodicCallback, err = host.Setup(target, r.CheckResult.Features)
+		if err != nil {
+			log.Fatalf("BUG: %v", err)
+		}
 	}
 	var gateCallback func()
 	if periodicCallback != nil {
@@ -201,6 +233,10 @@ func main() {
 		runTest(target, manager, *flagName, config.Executor)
 		return
 	}
+	var coverFilter *signal.Filter
+	if len(r.CoverFilter) != 0 && config.Flags&ipc.FlagSignal != 0 {
+		coverFilter = signal.NewFilter(r.CoverFilter, r.CoverFilterWeight)
+	}
 
 	needPoll := make(chan struct{}, 1)
 	needPoll <- struct{}{}
@@ -217,8 +253,9 @@ func main() {
 		faultInjectionEnabled:    r.CheckResult.Features[host.FeatureFaultInjection].Enabled,
 		comparisonTracingEnabled: r.CheckResult.Features[host.FeatureComparisons].Enabled,
 		corpusHashes:             make(map[hash.Sig]struct{}),
+		coverFilter:              coverFilter,
 	}
-	for i := 0; fuzzer.poll(i == 0, nil); i++ {
+	for i := 0; fuzzer.poll(i == 0, nil, nil); i++ {
 	}
 	calls := make(map[*prog.Syscall]bool)
 	for _, id := range r.CheckResult.EnabledCalls[sandbox] {
@@ -232,6 +269,12 @@ func main() {
 		if err != nil {
 			log.Fatalf("failed to create proc: %v", err)
 		}
+		if *flagSnapshotMonitor != "" {
+			snapshotter := ipc.NewQemuSnapshotter(*flagSnapshotMonitor)
+			if err := proc.env.EnableSnapshots(snapshotter, *flagSnapshotEvery); err != nil {
+				log.Fatalf("failed to enable snapshots: %v", err)
+			}
+		}
 		fuzzer.procs = append(fuzzer.procs, proc)
 		go proc.loop()
 	}
@@ -265,25 +308,33 @@ func (fuzzer *Fuzzer) pollLoop() {
 			for _, proc := range fuzzer.procs {
 				stats["exec total"] += atomic.SwapUint64(&proc.env.StatExecs, 0)
 				stats["executor restarts"] += atomic.SwapUint64(&proc.env.StatRestarts, 0)
+				stats["snapshot restores"] += atomic.SwapUint64(&proc.env.StatRestores, 0)
+				stats["snapshot restore ms"] += atomic.SwapUint64(&proc.env.StatRestoreTime, 0) / 1e6
 			}
 			for stat := Stat(0); stat < StatCount; stat++ {
 				v := atomic.SwapUint64(&fuzzer.stats[stat], 0)
 				stats[statNames[stat]] = v
 				execTotal += v
 			}
-			if !fuzzer.poll(needCandidates, stats) {
+			slowCalls := fuzzer.grabSlowCalls()
+			for _, stat := range slowCalls {
+				stats["slow calls"] += stat.Count
+			}
+			if !fuzzer.poll(needCandidates, stats, slowCalls) {
 				lastPoll = time.Now()
 			}
 		}
 	}
 }
 
-func (fuzzer *Fuzzer) poll(needCandidates bool, stats map[string]uint64) bool {
+func (fuzzer *Fuzzer) poll(needCandidates bool, stats map[string]uint64,
+	slowCalls map[string]*rpctype.SlowCallStat) bool {
 	a := &rpctype.PollArgs{
 		Name:           fuzzer.name,
 		NeedCandidates: needCandidates,
-		MaxSignal:      fuzzer.grabNewSignal().Serialize(),
+		MaxSignal:      fuzzer.grabNewSignal().Serialize(fuzzer.config.SignalMode),
 		Stats:          stats,
+		SlowCalls:      slowCalls,
 	}
 	r := &rpctype.PollRes{}
 	if err := fuzzer.manager.Call("Manager.Poll", a, r); err != nil {
@@ -403,6 +454,55 @@ func (fuzzer *Fuzzer) checkNewSignal(p *prog.Prog, info *ipc.ProgInfo) (calls []
 	return
 }
 
+// filterSignal drops signal that comes from code outside of the cover filter.
+// info must contain coverage traces (collected without ipc.FlagDedupCover).
+func (fuzzer *Fuzzer) filterSignal(p *prog.Prog, info *ipc.ProgInfo) {
+	if fuzzer.coverFilter == nil {
+		return
+	}
+	for i := range info.Calls {
+		inf := &info.Calls[i]
+		inf.Signal = fuzzer.coverFilter.FilterRaw(fuzzer.config.SignalMode, inf.Signal, inf.Cover, p.Calls[i].Meta.ID)
+	}
+}
+
+// noteSlowCalls records calls in info that took longer than slowCallThreshold.
+func (fuzzer *Fuzzer) noteSlowCalls(p *prog.Prog, info *ipc.ProgInfo) {
+	for i, inf := range info.Calls {
+		if inf.Flags&ipc.CallExecuted == 0 || inf.Time < slowCallThreshold {
+			continue
+		}
+		name := p.Calls[i].Meta.Name
+		fuzzer.slowCallsMu.Lock()
+		if fuzzer.slowCalls == nil {
+			fuzzer.slowCalls = make(map[string]*rpctype.SlowCallStat)
+		}
+		stat := fuzzer.slowCalls[name]
+		if stat == nil {
+			stat = new(rpctype.SlowCallStat)
+			fuzzer.slowCalls[name] = stat
+		}
+		stat.Merge(&rpctype.SlowCallStat{
+			Count:     1,
+			TotalTime: inf.Time,
+			MaxTime:   inf.Time,
+			CPUTime:   inf.CPUTime,
+		})
+		if inf.Flags&ipc.CallFinished == 0 {
+			stat.Blocked++
+		}
+		fuzzer.slowCallsMu.Unlock()
+	}
+}
+
+func (fuzzer *Fuzzer) grabSlowCalls() map[string]*rpctype.SlowCallStat {
+	fuzzer.slowCallsMu.Lock()
+	defer fuzzer.slowCallsMu.Unlock()
+	slowCalls := fuzzer.slowCalls
+	fuzzer.slowCalls = nil
+	return slowCalls
+}
+
 func signalPrio(target *prog.Target, c *prog.Call, ci *ipc.CallInfo) (prio uint8) {
 	if ci.Errno == 0 {
 		prio |= 1 << 1
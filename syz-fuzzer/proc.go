@@ -48,6 +48,11 @@ func newProc(fuzzer *Fuzzer, pid int) (*Proc, error) {
 	execOptsNoCollide.Flags &= ^ipc.FlagCollide
 	execOptsCover := execOptsNoCollide
 	execOptsCover.Flags |= ipc.FlagCollectCover
+	if fuzzer.coverFilter != nil {
+		// Filtering requires the coverage trace that signal was computed from,
+		// so triage and minimization use these options (see checkNewSignal).
+		execOptsCover.Flags &^= ipc.FlagDedupCover
+	}
 	execOptsComps := execOptsNoCollide
 	execOptsComps.Flags |= ipc.FlagCollectComps
 	proc := &Proc{
@@ -142,10 +147,14 @@ func (proc *Proc) triageInput(item *WorkTriage) {
 		inputCover.Merge(inf.Cover)
 	}
 	if item.flags&ProgMinimized == 0 {
+		minimizeOpts := proc.execOptsNoCollide
+		if proc.fuzzer.coverFilter != nil {
+			minimizeOpts = proc.execOptsCover
+		}
 		item.p, item.call = prog.Minimize(item.p, item.call, false,
 			func(p1 *prog.Prog, call1 int) bool {
 				for i := 0; i < minimizeAttempts; i++ {
-					info := proc.execute(proc.execOptsNoCollide, p1, ProgNormal, StatMinimize)
+					info := proc.execute(minimizeOpts, p1, ProgNormal, StatMinimize)
 					if info == nil || len(info.Calls) == 0 || len(info.Calls[call1].Signal) == 0 {
 						continue // The call was not executed.
 					}
@@ -172,7 +181,7 @@ func (proc *Proc) triageInput(item *WorkTriage) {
 	proc.fuzzer.sendInputToManager(rpctype.RPCInput{
 		Call:   call.Meta.CallName,
 		Prog:   data,
-		Signal: inputSignal.Serialize(),
+		Signal: inputSignal.Serialize(proc.fuzzer.config.SignalMode),
 		Cover:  inputCover.Serialize(),
 	})
 
@@ -232,7 +241,25 @@ func (proc *Proc) executeHintSeed(p *prog.Prog, call int) {
 
 func (proc *Proc) execute(execOpts *ipc.ExecOpts, p *prog.Prog, flags ProgTypes, stat Stat) *ipc.ProgInfo {
 	info := proc.executeRaw(execOpts, p, stat)
-	for _, callIndex := range proc.fuzzer.checkNewSignal(p, info) {
+	proc.checkNewSignal(execOpts, p, info, flags)
+	return info
+}
+
+// checkNewSignal queues calls of p that give new signal for triage.
+// opts are the options that p was executed with.
+func (proc *Proc) checkNewSignal(opts *ipc.ExecOpts, p *prog.Prog, info *ipc.ProgInfo, flags ProgTypes) {
+	if info == nil {
+		return
+	}
+	calls := proc.fuzzer.checkNewSignal(p, info)
+	if len(calls) != 0 && proc.fuzzer.coverFilter != nil && opts.Flags&ipc.FlagDedupCover != 0 {
+		// The signal is not filtered because there is no coverage trace.
+		// Re-execute the program with the trace to see if the new signal comes from
+		// the filtered code. This happens only for programs that give new raw signal,
+		// so collecting traces for every execution is avoided.
+		calls, info = proc.filterNewSignal(p, calls)
+	}
+	for _, callIndex := range calls {
 		info := info.Calls[callIndex]
 		// info.Signal points to the output shmem region, detach it before queueing.
 		info.Signal = append([]uint32{}, info.Signal...)
@@ -246,11 +273,28 @@ func (proc *Proc) execute(execOpts *ipc.ExecOpts, p *prog.Prog, flags ProgTypes,
 			flags: flags,
 		})
 	}
-	return info
+}
+
+// filterNewSignal re-executes p with coverage trace and returns calls that give new filtered signal
+// and the new info.
+func (proc *Proc) filterNewSignal(p *prog.Prog, calls []int) ([]int, *ipc.ProgInfo) {
+	info := proc.executeRaw(proc.execOptsCover, p, StatFilter)
+	if info == nil {
+		return nil, nil
+	}
+	var res []int
+	for _, call := range calls {
+		inf := &info.Calls[call]
+		sign := signal.FromRaw(inf.Signal, signalPrio(p.Target, p.Calls[call], inf))
+		if !proc.fuzzer.corpusSignalDiff(sign).Empty() {
+			res = append(res, call)
+		}
+	}
+	return res, info
 }
 
 func (proc *Proc) executeRaw(opts *ipc.ExecOpts, p *prog.Prog, stat Stat) *ipc.ProgInfo {
-	if opts.Flags&ipc.FlagDedupCover == 0 {
+	if opts.Flags&ipc.FlagDedupCover == 0 && proc.fuzzer.coverFilter == nil {
 		log.Fatalf("dedup cover is not enabled")
 	}
 
@@ -278,6 +322,10 @@ func (proc *Proc) executeRaw(opts *ipc.ExecOpts, p *prog.Prog, stat Stat) *ipc.P
 			continue
 		}
 		log.Logf(2, "result failed=%v hanged=%v: %s\n", failed, hanged, output)
+		proc.fuzzer.noteSlowCalls(p, info)
+		if opts.Flags&ipc.FlagDedupCover == 0 {
+			proc.fuzzer.filterSignal(p, info)
+		}
 		return info
 	}
 }
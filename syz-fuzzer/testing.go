@@ -20,14 +20,18 @@ import (
 )
 
 type checkArgs struct {
-	target         *prog.Target
-	sandbox        string
-	gitRevision    string
-	targetRevision string
-	enabledCalls   []int
-	allSandboxes   bool
-	ipcConfig      *ipc.Config
-	ipcExecOpts    *ipc.ExecOpts
+	target           *prog.Target
+	sandbox          string
+	gitRevision      string
+	targetRevision   string
+	enabledCalls     []int
+	allSandboxes     bool
+	featureOverrides map[string]bool
+	ipcConfig        *ipc.Config
+	ipcExecOpts      *ipc.ExecOpts
+	// We run on host and executor runs in a VM (snapshot mode),
+	// so we can't inspect the machine directly.
+	remote bool
 }
 
 func testImage(hostAddr string, args *checkArgs) {
@@ -125,6 +129,20 @@ func checkMachine(args *checkArgs) (*rpctype.CheckArgs, error) {
 	if err != nil {
 		return nil, err
 	}
+	if args.remote {
+		for i := range features {
+			features[i].Enabled = false
+			features[i].Reason = "can't be checked when fuzzer runs outside of the machine"
+		}
+		// Coverage is verified by checkSimpleProgram below.
+		if args.ipcConfig.Flags&ipc.FlagSignal != 0 {
+			features[host.FeatureCoverage].Enabled = true
+			features[host.FeatureCoverage].Reason = "enabled"
+		}
+	}
+	if err := features.Override(args.featureOverrides); err != nil {
+		return nil, err
+	}
 	if feat := features[host.FeatureCoverage]; !feat.Enabled &&
 		args.ipcConfig.Flags&ipc.FlagSignal != 0 {
 		return nil, fmt.Errorf("coverage is not supported (%v)", feat.Reason)
@@ -159,7 +177,7 @@ func checkMachine(args *checkArgs) (*rpctype.CheckArgs, error) {
 		}
 	}
 	for _, sandbox := range sandboxes {
-		enabledCalls, disabledCalls, err := buildCallList(args.target, args.enabledCalls, sandbox)
+		enabledCalls, disabledCalls, err := buildCallList(args.target, args.enabledCalls, sandbox, args.remote)
 		if err != nil {
 			return nil, err
 		}
@@ -253,7 +271,7 @@ func checkSimpleProgram(args *checkArgs) error {
 	return nil
 }
 
-func buildCallList(target *prog.Target, enabledCalls []int, sandbox string) (
+func buildCallList(target *prog.Target, enabledCalls []int, sandbox string, remote bool) (
 	enabled []int, disabled []rpctype.SyscallReason, err error) {
 	log.Logf(0, "building call list...")
 	calls := make(map[*prog.Syscall]bool)
@@ -269,30 +287,20 @@ func buildCallList(target *prog.Target, enabledCalls []int, sandbox string) (
 			calls[c] = true
 		}
 	}
-	_, unsupported, err := host.DetectSupportedSyscalls(target, sandbox)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to detect host supported syscalls: %v", err)
-	}
-	for c := range calls {
-		if reason, ok := unsupported[c]; ok {
-			log.Logf(1, "unsupported syscall: %v: %v", c.Name, reason)
-			disabled = append(disabled, rpctype.SyscallReason{
-				ID:     c.ID,
-				Reason: reason,
-			})
-			delete(calls, c)
+	unsupported := make(map[*prog.Syscall]string)
+	if !remote {
+		_, unsupported, err = host.DetectSupportedSyscalls(target, sandbox)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to detect host supported syscalls: %v", err)
 		}
 	}
-	_, unsupported = target.TransitivelyEnabledCalls(calls)
-	for c := range calls {
-		if reason, ok := unsupported[c]; ok {
-			log.Logf(1, "transitively unsupported: %v: %v", c.Name, reason)
-			disabled = append(disabled, rpctype.SyscallReason{
-				ID:     c.ID,
-				Reason: reason,
-			})
-			delete(calls, c)
-		}
+	calls, unsupported = host.CheckSyscalls(target, calls, unsupported)
+	for c, reason := range unsupported {
+		log.Logf(1, "unsupported syscall: %v: %v", c.Name, reason)
+		disabled = append(disabled, rpctype.SyscallReason{
+			ID:     c.ID,
+			Reason: reason,
+		})
 	}
 	if len(calls) == 0 {
 		return nil, nil, fmt.Errorf("all system calls are disabled")
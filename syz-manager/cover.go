@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -15,7 +16,9 @@ import (
 	"time"
 
 	"github.com/google/syzkaller/pkg/cover"
+	"github.com/google/syzkaller/pkg/mgrconfig"
 	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/pkg/signal"
 )
 
 var (
@@ -39,19 +42,76 @@ func initCover(kernelObj, kernelObjName, kernelSrc, arch, OS string) error {
 	return err
 }
 
-func generateCoverHTML(w io.Writer, kernelObj, kernelObjName, kernelSrc, arch, OS string, cov cover.Cover) error {
+func generateCover(w io.Writer, format, kernelObj, kernelObjName, kernelSrc, arch, OS string, cov cover.Cover) error {
+	pcs, err := coverPCs(kernelObj, kernelObjName, kernelSrc, arch, OS, cov)
+	if err != nil {
+		return err
+	}
+	return reportGenerator.DoFormat(w, pcs, format)
+}
+
+func generateFuncStats(kernelObj, kernelObjName, kernelSrc, arch, OS string, cov cover.Cover) (
+	[]*cover.FuncStats, error) {
+	pcs, err := coverPCs(kernelObj, kernelObjName, kernelSrc, arch, OS, cov)
+	if err != nil {
+		return nil, err
+	}
+	return reportGenerator.FuncStats(pcs)
+}
+
+// coverFilterRanges returns ranges of truncated PCs selected by cfg.CoverFilter.
+func coverFilterRanges(cfg *mgrconfig.Config, kernelObjName string) ([]signal.PCRange, error) {
+	f := cfg.CoverFilter
+	var ranges []cover.PCRange
+	for _, pcs := range f.PCs {
+		start, end, err := mgrconfig.ParsePCRange(pcs)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, cover.PCRange{Start: start, End: end})
+	}
+	if len(f.Files) != 0 || len(f.Functions) != 0 {
+		initCoverOnce.Do(func() {
+			initCoverError = initCover(cfg.KernelObj, kernelObjName, cfg.KernelSrc, cfg.TargetVMArch, cfg.TargetOS)
+		})
+		if initCoverError != nil {
+			return nil, initCoverError
+		}
+		funcRanges, err := reportGenerator.FilterRanges(f.Files, f.Functions)
+		if err != nil {
+			return nil, err
+		}
+		if len(funcRanges) == 0 {
+			return nil, fmt.Errorf("cover_filter files/functions don't match any functions")
+		}
+		ranges = append(ranges, funcRanges...)
+	}
+	res := make([]signal.PCRange, 0, len(ranges))
+	for _, r := range ranges {
+		// Coverage PCs are truncated to 32 bits, see cover.RestorePC.
+		start, end := uint32(r.Start), uint32(r.End)
+		if r.End-r.Start > math.MaxUint32 || end < start {
+			end = math.MaxUint32
+		}
+		res = append(res, signal.PCRange{Start: start, End: end})
+	}
+	return res, nil
+}
+
+// coverPCs initializes report generator and restores full PCs from cov.
+func coverPCs(kernelObj, kernelObjName, kernelSrc, arch, OS string, cov cover.Cover) ([]uint64, error) {
 	if len(cov) == 0 {
-		return fmt.Errorf("no coverage data available")
+		return nil, fmt.Errorf("no coverage data available")
 	}
 	initCoverOnce.Do(func() { initCoverError = initCover(kernelObj, kernelObjName, kernelSrc, arch, OS) })
 	if initCoverError != nil {
-		return initCoverError
+		return nil, initCoverError
 	}
 	pcs := make([]uint64, 0, len(cov))
 	for pc := range cov {
 		pcs = append(pcs, cover.RestorePC(pc, initCoverVMOffset))
 	}
-	return reportGenerator.Do(w, pcs)
+	return pcs, nil
 }
 
 func getVMOffset(vmlinux, OS string) (uint32, error) {
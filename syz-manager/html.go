@@ -20,9 +20,11 @@ import (
 	"time"
 
 	"github.com/google/syzkaller/pkg/cover"
+	"github.com/google/syzkaller/pkg/host"
 	"github.com/google/syzkaller/pkg/html"
 	"github.com/google/syzkaller/pkg/log"
 	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/pkg/report/stack"
 	"github.com/google/syzkaller/pkg/signal"
 	"github.com/google/syzkaller/prog"
 )
@@ -30,6 +32,8 @@ import (
 func (mgr *Manager) initHTTP() {
 	http.HandleFunc("/", mgr.httpSummary)
 	http.HandleFunc("/syscalls", mgr.httpSyscalls)
+	http.HandleFunc("/slowcalls", mgr.httpSlowCalls)
+	http.HandleFunc("/disabled", mgr.httpDisabled)
 	http.HandleFunc("/corpus", mgr.httpCorpus)
 	http.HandleFunc("/crash", mgr.httpCrash)
 	http.HandleFunc("/cover", mgr.httpCover)
@@ -37,7 +41,12 @@ func (mgr *Manager) initHTTP() {
 	http.HandleFunc("/file", mgr.httpFile)
 	http.HandleFunc("/report", mgr.httpReport)
 	http.HandleFunc("/rawcover", mgr.httpRawCover)
+	http.HandleFunc("/lcovcover", mgr.httpLCOVCover)
+	http.HandleFunc("/jsoncover", mgr.httpJSONCover)
+	http.HandleFunc("/funccover", mgr.httpFuncCover)
 	http.HandleFunc("/input", mgr.httpInput)
+	http.HandleFunc("/repros", mgr.httpRepros)
+	http.HandleFunc("/repro", mgr.httpRepro)
 	// Browsers like to request this, without special handler this goes to / handler.
 	http.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {})
 
@@ -54,9 +63,10 @@ func (mgr *Manager) initHTTP() {
 
 func (mgr *Manager) httpSummary(w http.ResponseWriter, r *http.Request) {
 	data := &UISummaryData{
-		Name:  mgr.cfg.Name,
-		Log:   log.CachedLogOutput(),
-		Stats: mgr.collectStats(),
+		Name:     mgr.cfg.Name,
+		Log:      log.CachedLogOutput(),
+		Stats:    mgr.collectStats(),
+		Features: mgr.collectFeatures(),
 	}
 
 	var err error
@@ -93,6 +103,88 @@ func (mgr *Manager) httpSyscalls(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (mgr *Manager) httpSlowCalls(w http.ResponseWriter, r *http.Request) {
+	data := &UISlowCallsData{
+		Name: mgr.cfg.Name,
+	}
+	for call, stat := range mgr.stats.allSlowCalls() {
+		ui := UISlowCall{
+			Name:    call,
+			Count:   stat.Count,
+			Blocked: stat.Blocked,
+			MaxTime: uint64(stat.MaxTime / time.Millisecond),
+		}
+		if stat.Count != 0 {
+			ui.AvgTime = uint64(stat.TotalTime / time.Duration(stat.Count) / time.Millisecond)
+			ui.AvgCPU = uint64(stat.CPUTime / time.Duration(stat.Count) / time.Millisecond)
+		}
+		data.Calls = append(data.Calls, ui)
+	}
+	sort.Slice(data.Calls, func(i, j int) bool {
+		if data.Calls[i].Count != data.Calls[j].Count {
+			return data.Calls[i].Count > data.Calls[j].Count
+		}
+		return data.Calls[i].Name < data.Calls[j].Name
+	})
+	if err := slowCallsTemplate.Execute(w, data); err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err),
+			http.StatusInternalServerError)
+		return
+	}
+}
+
+func (mgr *Manager) httpRepros(w http.ResponseWriter, r *http.Request) {
+	data := &UIReprosData{
+		Name:   mgr.cfg.Name,
+		Repros: mgr.reproSched.jobs(),
+	}
+	if err := reprosTemplate.Execute(w, data); err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err),
+			http.StatusInternalServerError)
+		return
+	}
+}
+
+func (mgr *Manager) httpRepro(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad repro id: %v", err), http.StatusBadRequest)
+		return
+	}
+	repro := mgr.reproSched.job(id)
+	if repro == nil {
+		http.Error(w, "unknown repro", http.StatusBadRequest)
+		return
+	}
+	if err := reproTemplate.Execute(w, repro); err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err),
+			http.StatusInternalServerError)
+		return
+	}
+}
+
+func (mgr *Manager) httpDisabled(w http.ResponseWriter, r *http.Request) {
+	mgr.mu.Lock()
+	data := &UIDisabledData{
+		Name: mgr.cfg.Name,
+	}
+	for _, dc := range mgr.disabledCalls {
+		data.Calls = append(data.Calls, UIDisabledCall{
+			Name:   mgr.target.Syscalls[dc.ID].Name,
+			Reason: dc.Reason,
+		})
+	}
+	mgr.mu.Unlock()
+	sort.Slice(data.Calls, func(i, j int) bool {
+		return data.Calls[i].Name < data.Calls[j].Name
+	})
+	if err := disabledTemplate.Execute(w, data); err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err),
+			http.StatusInternalServerError)
+		return
+	}
+}
+
 type CallCov struct {
 	count int
 	cov   cover.Cover
@@ -119,7 +211,36 @@ func (mgr *Manager) collectStats() []UIStat {
 			Value: fmt.Sprint(len(mgr.checkResult.EnabledCalls[mgr.cfg.Sandbox])),
 			Link:  "/syscalls",
 		})
+		if len(mgr.disabledCalls) != 0 {
+			stats = append(stats, UIStat{
+				Name:  "disabled syscalls",
+				Value: fmt.Sprint(len(mgr.disabledCalls)),
+				Link:  "/disabled",
+			})
+		}
+	}
+	if slowCalls, ok := rawStats["slow calls"]; ok {
+		stats = append(stats, UIStat{
+			Name:  "slow calls",
+			Value: fmt.Sprint(slowCalls),
+			Link:  "/slowcalls",
+		})
+		delete(rawStats, "slow calls")
+	}
+	queued, running := mgr.reproSched.stats()
+	stats = append(stats, UIStat{
+		Name:  "repros",
+		Value: fmt.Sprintf("%v running, %v queued", running, queued),
+		Link:  "/repros",
+	})
+	if restores := rawStats["snapshot restores"]; restores != 0 {
+		// Restore time is what we pay for snapshot mode in terms of throughput.
+		stats = append(stats, UIStat{
+			Name:  "snapshot restore time",
+			Value: fmt.Sprintf("%v ms/restore", rawStats["snapshot restore ms"]/restores),
+		})
 	}
+	delete(rawStats, "snapshot restore ms")
 
 	secs := uint64(1)
 	if !mgr.firstConnect.IsZero() {
@@ -133,6 +254,32 @@ func (mgr *Manager) collectStats() []UIStat {
 	return stats
 }
 
+func (mgr *Manager) collectFeatures() []UIFeature {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	if mgr.checkResult == nil || mgr.checkResult.Features == nil {
+		return nil
+	}
+	var features []UIFeature
+	for i, feat := range mgr.checkResult.Features {
+		config := ""
+		if enable, ok := mgr.cfg.Features[host.FeatureIDs[i]]; ok {
+			config = "disable"
+			if enable {
+				config = "require"
+			}
+		}
+		features = append(features, UIFeature{
+			Name:    feat.Name,
+			ID:      host.FeatureIDs[i],
+			Enabled: feat.Enabled,
+			Config:  config,
+			Reason:  feat.Reason,
+		})
+	}
+	return features
+}
+
 func convertStats(stats map[string]uint64, secs uint64) []UIStat {
 	var intStats []UIStat
 	for k, v := range stats {
@@ -168,17 +315,80 @@ func (mgr *Manager) collectSyscallInfo() map[string]*CallCov {
 
 func (mgr *Manager) httpCrash(w http.ResponseWriter, r *http.Request) {
 	crashID := r.FormValue("id")
-	crash := readCrash(mgr.cfg.Workdir, crashID, nil, mgr.startTime, true)
+	crash := readCrash(mgr.cfg.Workdir, crashID, nil, mgr.startTime, mgr.cfg.ReproAttempts, true)
 	if crash == nil {
 		http.Error(w, fmt.Sprintf("failed to read crash info"), http.StatusInternalServerError)
 		return
 	}
+	crash.PossibleDups = mgr.possibleDups(crash.ID)
 	if err := crashTemplate.Execute(w, crash); err != nil {
 		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
 		return
 	}
 }
 
+type crashStack struct {
+	title string
+	stack []string
+}
+
+// crashStacksLocked returns stack signatures of all crash types keyed by crash dir name.
+// They are loaded from workdir on first use and then updated by saveCrash,
+// so that the crash page does not need to rescan all crash dirs.
+// crashStacksMu must be held.
+func (mgr *Manager) crashStacksLocked() map[string]*crashStack {
+	if mgr.crashStacks != nil {
+		return mgr.crashStacks
+	}
+	mgr.crashStacks = make(map[string]*crashStack)
+	dirs, _ := osutil.ListDir(mgr.crashdir)
+	for _, dir := range dirs {
+		desc, err := ioutil.ReadFile(filepath.Join(mgr.crashdir, dir, "description"))
+		if err != nil {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(mgr.crashdir, dir, "stack"))
+		if err != nil {
+			continue
+		}
+		mgr.crashStacks[dir] = &crashStack{
+			title: string(trimNewLines(desc)),
+			stack: strings.Split(string(trimNewLines(data)), "\n"),
+		}
+	}
+	return mgr.crashStacks
+}
+
+func (mgr *Manager) addCrashStack(id, title string, stack []string) {
+	mgr.crashStacksMu.Lock()
+	defer mgr.crashStacksMu.Unlock()
+	// If stacks are not loaded yet, the new one will be loaded from disk along with the rest.
+	if mgr.crashStacks != nil && mgr.crashStacks[id] == nil {
+		mgr.crashStacks[id] = &crashStack{title, stack}
+	}
+}
+
+// possibleDups returns crash types with different titles, but stacks similar to the crash type id.
+func (mgr *Manager) possibleDups(id string) []UICrashRef {
+	mgr.crashStacksMu.Lock()
+	defer mgr.crashStacksMu.Unlock()
+	stacks := mgr.crashStacksLocked()
+	crash := stacks[id]
+	if crash == nil {
+		return nil
+	}
+	var dups []UICrashRef
+	for id1, crash1 := range stacks {
+		if id1 != id && stack.Similar(crash.stack, crash1.stack) {
+			dups = append(dups, UICrashRef{id1, crash1.title})
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		return strings.ToLower(dups[i].Description) < strings.ToLower(dups[j].Description)
+	})
+	return dups
+}
+
 func (mgr *Manager) httpCorpus(w http.ResponseWriter, r *http.Request) {
 	mgr.mu.Lock()
 	defer mgr.mu.Unlock()
@@ -224,17 +434,50 @@ func (mgr *Manager) httpCover(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if mgr.cfg.Cover {
-		mgr.httpCoverCover(w, r)
+		mgr.httpCoverCover(w, r, cover.FormatHTML)
 	} else {
 		mgr.httpCoverFallback(w, r)
 	}
 }
 
-func (mgr *Manager) httpCoverCover(w http.ResponseWriter, r *http.Request) {
+// httpLCOVCover exports coverage as lcov tracefile, accepts the same input/call filters as /cover.
+func (mgr *Manager) httpLCOVCover(w http.ResponseWriter, r *http.Request) {
+	mgr.httpCoverExport(w, r, cover.FormatLCOV, "text/plain; charset=utf-8")
+}
+
+// httpJSONCover exports per-file/per-function coverage summary as JSON.
+func (mgr *Manager) httpJSONCover(w http.ResponseWriter, r *http.Request) {
+	mgr.httpCoverExport(w, r, cover.FormatJSON, "application/json")
+}
+
+func (mgr *Manager) httpCoverExport(w http.ResponseWriter, r *http.Request, format, contentType string) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	if !mgr.cfg.Cover {
+		http.Error(w, "coverage is not enabled", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	mgr.httpCoverCover(w, r, format)
+}
+
+func (mgr *Manager) httpCoverCover(w http.ResponseWriter, r *http.Request, format string) {
 	if mgr.cfg.KernelObj == "" {
 		http.Error(w, fmt.Sprintf("no kernel_obj in config file"), http.StatusInternalServerError)
 		return
 	}
+	if err := generateCover(w, format, mgr.cfg.KernelObj, mgr.sysTarget.KernelObject,
+		mgr.cfg.KernelSrc, mgr.cfg.TargetVMArch, mgr.cfg.TargetOS, mgr.requestCover(r)); err != nil {
+		http.Error(w, fmt.Sprintf("failed to generate coverage profile: %v", err), http.StatusInternalServerError)
+		return
+	}
+	runtime.GC()
+}
+
+// requestCover returns coverage of the corpus input (input=sig), of all inputs of a call (call=name),
+// or of the whole corpus.
+func (mgr *Manager) requestCover(r *http.Request) cover.Cover {
 	var cov cover.Cover
 	if sig := r.FormValue("input"); sig != "" {
 		cov.Merge(mgr.corpus[sig].Cover)
@@ -246,10 +489,31 @@ func (mgr *Manager) httpCoverCover(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	return cov
+}
 
-	if err := generateCoverHTML(w, mgr.cfg.KernelObj, mgr.sysTarget.KernelObject,
-		mgr.cfg.KernelSrc, mgr.cfg.TargetVMArch, mgr.cfg.TargetOS, cov); err != nil {
-		http.Error(w, fmt.Sprintf("failed to generate coverage profile: %v", err), http.StatusInternalServerError)
+func (mgr *Manager) httpFuncCover(w http.ResponseWriter, r *http.Request) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	if !mgr.cfg.Cover {
+		http.Error(w, "coverage is not enabled", http.StatusInternalServerError)
+		return
+	}
+	funcs, err := generateFuncStats(mgr.cfg.KernelObj, mgr.sysTarget.KernelObject,
+		mgr.cfg.KernelSrc, mgr.cfg.TargetVMArch, mgr.cfg.TargetOS, mgr.requestCover(r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to generate function coverage: %v", err),
+			http.StatusInternalServerError)
+		return
+	}
+	data := &UIFuncCoverData{
+		Name:  mgr.cfg.Name,
+		Funcs: funcs,
+	}
+	if err := funcCoverTemplate.Execute(w, data); err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err),
+			http.StatusInternalServerError)
 		return
 	}
 	runtime.GC()
@@ -431,10 +695,10 @@ func (mgr *Manager) httpRawCover(w http.ResponseWriter, r *http.Request) {
 
 func (mgr *Manager) collectCrashes(workdir string) ([]*UICrashType, error) {
 	// Note: mu is not locked here.
-	reproReply := make(chan map[string]bool)
-	mgr.reproRequest <- reproReply
-	repros := <-reproReply
+	return readCrashes(workdir, mgr.reproSched.activeTitles(), mgr.startTime, mgr.cfg.ReproAttempts)
+}
 
+func readCrashes(workdir string, repros map[string]bool, start time.Time, maxAttempts int) ([]*UICrashType, error) {
 	crashdir := filepath.Join(workdir, "crashes")
 	dirs, err := osutil.ListDir(crashdir)
 	if err != nil {
@@ -442,7 +706,7 @@ func (mgr *Manager) collectCrashes(workdir string) ([]*UICrashType, error) {
 	}
 	var crashTypes []*UICrashType
 	for _, dir := range dirs {
-		crash := readCrash(workdir, dir, repros, mgr.startTime, false)
+		crash := readCrash(workdir, dir, repros, start, maxAttempts, false)
 		if crash != nil {
 			crashTypes = append(crashTypes, crash)
 		}
@@ -450,10 +714,21 @@ func (mgr *Manager) collectCrashes(workdir string) ([]*UICrashType, error) {
 	sort.Slice(crashTypes, func(i, j int) bool {
 		return strings.ToLower(crashTypes[i].Description) < strings.ToLower(crashTypes[j].Description)
 	})
+	// Crashes with different titles, but similar stacks are possibly the same bug.
+	for i, crash := range crashTypes {
+		for _, crash1 := range crashTypes[i+1:] {
+			if !stack.Similar(crash.stack, crash1.stack) {
+				continue
+			}
+			crash.PossibleDups = append(crash.PossibleDups, UICrashRef{crash1.ID, crash1.Description})
+			crash1.PossibleDups = append(crash1.PossibleDups, UICrashRef{crash.ID, crash.Description})
+		}
+	}
 	return crashTypes, nil
 }
 
-func readCrash(workdir, dir string, repros map[string]bool, start time.Time, full bool) *UICrashType {
+func readCrash(workdir, dir string, repros map[string]bool, start time.Time, maxAttempts int,
+	full bool) *UICrashType {
 	if len(dir) != 40 {
 		return nil
 	}
@@ -475,6 +750,17 @@ func readCrash(workdir, dir string, repros map[string]bool, start time.Time, ful
 	modTime := stat.ModTime()
 	descFile.Close()
 
+	var stack []string
+	if data, err := ioutil.ReadFile(filepath.Join(crashdir, dir, "stack")); err == nil {
+		stack = strings.Split(string(trimNewLines(data)), "\n")
+	}
+	var labels []string
+	if data, err := ioutil.ReadFile(filepath.Join(crashdir, dir, "labels")); err == nil {
+		labels = strings.Split(string(trimNewLines(data)), "\n")
+	}
+	severity, _ := ioutil.ReadFile(filepath.Join(crashdir, dir, "severity"))
+	severity = trimNewLines(severity)
+
 	files, err := osutil.ListDir(filepath.Join(crashdir, dir))
 	if err != nil {
 		return nil
@@ -497,8 +783,8 @@ func readCrash(workdir, dir string, repros map[string]bool, start time.Time, ful
 			hasRepro = true
 		} else if f == "repro.cprog" {
 			hasCRepro = true
-		} else if f == "repro.report" {
-		} else if f == "repro0" || f == "repro1" || f == "repro2" {
+		} else if f == "repro.report" || f == "stack" || f == "labels" || f == "severity" {
+		} else if _, err := strconv.ParseUint(strings.TrimPrefix(f, "repro"), 10, 64); err == nil {
 			reproAttempts++
 		}
 	}
@@ -523,7 +809,8 @@ func readCrash(workdir, dir string, repros map[string]bool, start time.Time, ful
 		})
 	}
 
-	triaged := reproStatus(hasRepro, hasCRepro, repros[desc], reproAttempts >= maxReproAttempts)
+	triaged := reproStatus(hasRepro, hasCRepro, repros[desc],
+		reproAttempts >= maxAttempts)
 	return &UICrashType{
 		Description: desc,
 		LastTime:    modTime,
@@ -532,6 +819,9 @@ func readCrash(workdir, dir string, repros map[string]bool, start time.Time, ful
 		Count:       len(crashes),
 		Triaged:     triaged,
 		Crashes:     crashes,
+		Labels:      labels,
+		Severity:    string(severity),
+		stack:       stack,
 	}
 }
 
@@ -558,10 +848,19 @@ func trimNewLines(data []byte) []byte {
 }
 
 type UISummaryData struct {
+	Name     string
+	Stats    []UIStat
+	Features []UIFeature
+	Crashes  []*UICrashType
+	Log      string
+}
+
+type UIFeature struct {
 	Name    string
-	Stats   []UIStat
-	Crashes []*UICrashType
-	Log     string
+	ID      string
+	Enabled bool
+	Config  string // override from manager config: "disable", "require" or empty
+	Reason  string
 }
 
 type UISyscallsData struct {
@@ -569,6 +868,54 @@ type UISyscallsData struct {
 	Calls []UICallType
 }
 
+type UIDisabledData struct {
+	Name  string
+	Calls []UIDisabledCall
+}
+
+type UIDisabledCall struct {
+	Name   string
+	Reason string
+}
+
+type UISlowCallsData struct {
+	Name  string
+	Calls []UISlowCall
+}
+
+type UISlowCall struct {
+	Name    string
+	Count   uint64
+	Blocked uint64
+	AvgTime uint64 // in ms
+	MaxTime uint64 // in ms
+	AvgCPU  uint64 // in ms
+}
+
+type UIReprosData struct {
+	Name   string
+	Repros []*UIRepro
+}
+
+type UIRepro struct {
+	ID            int
+	Title         string
+	State         string
+	Severity      string
+	DashRequested bool
+	NewTitle      bool
+	Hub           bool
+	Attempt       int
+	Queued        time.Time
+	Started       time.Time
+	Finished      time.Time
+	NotBefore     time.Time
+	Duration      time.Duration
+	VMs           []int
+	Error         string
+	Log           string
+}
+
 type UICrashType struct {
 	Description string
 	LastTime    time.Time
@@ -577,6 +924,18 @@ type UICrashType struct {
 	Count       int
 	Triaged     string
 	Crashes     []*UICrash
+	// Labels are attached by crash_rules in manager config.
+	Labels []string
+	// Severity is the highest severity of the crashes (see report.Report.Severity).
+	Severity string
+	// PossibleDups are crashes with different titles, but similar stacks.
+	PossibleDups []UICrashRef
+	stack        []string
+}
+
+type UICrashRef struct {
+	ID          string
+	Description string
 }
 
 type UICrash struct {
@@ -638,17 +997,47 @@ var summaryTemplate = html.CreatePage(`
 	{{end}}
 </table>
 
+{{if $.Features}}
+<table class="list_table">
+	<caption>Features:</caption>
+	<tr>
+		<th>Feature</th>
+		<th>Config name</th>
+		<th>Enabled</th>
+		<th>Config</th>
+		<th>Reason</th>
+	</tr>
+	{{range $f := $.Features}}
+	<tr>
+		<td>{{$f.Name}}</td>
+		<td>{{$f.ID}}</td>
+		<td {{if not $f.Enabled}}class="inactive"{{end}}>{{$f.Enabled}}</td>
+		<td>{{$f.Config}}</td>
+		<td>{{$f.Reason}}</td>
+	</tr>
+	{{end}}
+</table>
+{{end}}
+
 <table class="list_table">
 	<caption>Crashes:</caption>
 	<tr>
 		<th><a onclick="return sortTable(this, 'Description', textSort)" href="#">Description</a></th>
+		<th><a onclick="return sortTable(this, 'Severity', severitySort)" href="#">Severity</a></th>
 		<th><a onclick="return sortTable(this, 'Count', numSort)" href="#">Count</a></th>
 		<th><a onclick="return sortTable(this, 'Last Time', textSort, true)" href="#">Last Time</a></th>
 		<th><a onclick="return sortTable(this, 'Report', textSort)" href="#">Report</a></th>
 	</tr>
 	{{range $c := $.Crashes}}
 	<tr>
-		<td class="title"><a href="/crash?id={{$c.ID}}">{{$c.Description}}</a></td>
+		<td class="title">
+			<a href="/crash?id={{$c.ID}}">{{$c.Description}}</a>
+			{{range $l := $c.Labels}} [{{$l}}]{{end}}
+			{{range $d := $c.PossibleDups}}
+				<br>&nbsp;possible duplicate of <a href="/crash?id={{$d.ID}}">{{$d.Description}}</a>
+			{{end}}
+		</td>
+		<td class="stat">{{$c.Severity}}</td>
 		<td class="stat {{if not $c.Active}}inactive{{end}}">{{$c.Count}}</td>
 		<td class="time {{if not $c.Active}}inactive{{end}}">{{formatTime $c.LastTime}}</td>
 		<td>
@@ -688,6 +1077,7 @@ var syscallsTemplate = html.CreatePage(`
 		<th><a onclick="return sortTable(this, 'Inputs', numSort)" href="#">Inputs</a></th>
 		<th><a onclick="return sortTable(this, 'Coverage', numSort)" href="#">Coverage</a></th>
 		<th>Prio</th>
+		<th>Functions</th>
 	</tr>
 	{{range $c := $.Calls}}
 	<tr>
@@ -695,12 +1085,141 @@ var syscallsTemplate = html.CreatePage(`
 		<td><a href='/corpus?call={{$c.Name}}'>{{$c.Inputs}}</a></td>
 		<td><a href='/cover?call={{$c.Name}}'>{{$c.Cover}}</a></td>
 		<td><a href='/prio?call={{$c.Name}}'>prio</a></td>
+		<td><a href='/funccover?call={{$c.Name}}'>functions</a></td>
+	</tr>
+	{{end}}
+</table>
+</body></html>
+`)
+
+var disabledTemplate = html.CreatePage(`
+<!doctype html>
+<html>
+<head>
+	<title>{{.Name }} syzkaller</title>
+	{{HEAD}}
+</head>
+<body>
+
+<table class="list_table">
+	<caption>Disabled syscalls:</caption>
+	<tr>
+		<th><a onclick="return sortTable(this, 'Syscall', textSort)" href="#">Syscall</a></th>
+		<th><a onclick="return sortTable(this, 'Reason', textSort)" href="#">Reason</a></th>
+	</tr>
+	{{range $c := $.Calls}}
+	<tr>
+		<td>{{$c.Name}}</td>
+		<td>{{$c.Reason}}</td>
+	</tr>
+	{{end}}
+</table>
+</body></html>
+`)
+
+var slowCallsTemplate = html.CreatePage(`
+<!doctype html>
+<html>
+<head>
+	<title>{{.Name }} syzkaller</title>
+	{{HEAD}}
+</head>
+<body>
+
+<table class="list_table">
+	<caption>Slow syscalls:</caption>
+	<tr>
+		<th><a onclick="return sortTable(this, 'Syscall', textSort)" href="#">Syscall</a></th>
+		<th><a onclick="return sortTable(this, 'Count', numSort)" href="#">Count</a></th>
+		<th><a onclick="return sortTable(this, 'Blocked', numSort)" href="#">Blocked</a></th>
+		<th><a onclick="return sortTable(this, 'Avg ms', numSort)" href="#">Avg ms</a></th>
+		<th><a onclick="return sortTable(this, 'Max ms', numSort)" href="#">Max ms</a></th>
+		<th><a onclick="return sortTable(this, 'Avg CPU ms', numSort)" href="#">Avg CPU ms</a></th>
+	</tr>
+	{{range $c := $.Calls}}
+	<tr>
+		<td>{{$c.Name}}</td>
+		<td>{{$c.Count}}</td>
+		<td>{{$c.Blocked}}</td>
+		<td>{{$c.AvgTime}}</td>
+		<td>{{$c.MaxTime}}</td>
+		<td>{{$c.AvgCPU}}</td>
+	</tr>
+	{{end}}
+</table>
+</body></html>
+`)
+
+var reprosTemplate = html.CreatePage(`
+<!doctype html>
+<html>
+<head>
+	<title>{{.Name }} syzkaller</title>
+	{{HEAD}}
+</head>
+<body>
+
+<table class="list_table">
+	<caption>Repros:</caption>
+	<tr>
+		<th><a onclick="return sortTable(this, 'Title', textSort)" href="#">Title</a></th>
+		<th><a onclick="return sortTable(this, 'State', textSort)" href="#">State</a></th>
+		<th><a onclick="return sortTable(this, 'Severity', severitySort)" href="#">Severity</a></th>
+		<th>Priority</th>
+		<th><a onclick="return sortTable(this, 'Attempt', numSort)" href="#">Attempt</a></th>
+		<th><a onclick="return sortTable(this, 'Queued', textSort, true)" href="#">Queued</a></th>
+		<th><a onclick="return sortTable(this, 'Duration', textSort)" href="#">Duration</a></th>
+		<th>VMs</th>
+		<th>Log</th>
+	</tr>
+	{{range $r := $.Repros}}
+	<tr>
+		<td class="title">{{$r.Title}}</td>
+		<td>
+			{{$r.State}}
+			{{if and (eq $r.State "queued") (not $r.NotBefore.IsZero)}}
+				<br>not before {{formatTime $r.NotBefore}}
+			{{end}}
+			{{if $r.Error}}<br>{{$r.Error}}{{end}}
+		</td>
+		<td class="stat">{{$r.Severity}}</td>
+		<td>
+			{{if $r.DashRequested}}dashboard{{end}}
+			{{if $r.NewTitle}}new{{end}}
+			{{if $r.Hub}}hub{{end}}
+		</td>
+		<td class="stat">{{$r.Attempt}}</td>
+		<td class="time">{{formatTime $r.Queued}}</td>
+		<td class="time">{{if $r.Duration}}{{$r.Duration}}{{end}}</td>
+		<td>{{if $r.VMs}}{{$r.VMs}}{{end}}</td>
+		<td><a href="/repro?id={{$r.ID}}">log</a></td>
 	</tr>
 	{{end}}
 </table>
 </body></html>
 `)
 
+var reproTemplate = html.CreatePage(`
+<!doctype html>
+<html>
+<head>
+	<title>{{.Title}} repro</title>
+	{{HEAD}}
+</head>
+<body>
+<b>{{.Title}}</b>: {{.State}}{{if .Attempt}} (attempt {{.Attempt}}){{end}}{{if .Duration}}, {{.Duration}}{{end}}
+{{if .Error}}<br>{{.Error}}{{end}}
+<br>
+<textarea id="log_textarea" readonly rows="40" wrap=off>
+{{.Log}}
+</textarea>
+<script>
+	var textarea = document.getElementById("log_textarea");
+	textarea.scrollTop = textarea.scrollHeight;
+</script>
+</body></html>
+`)
+
 var crashTemplate = html.CreatePage(`
 <!doctype html>
 <html>
@@ -714,6 +1233,9 @@ var crashTemplate = html.CreatePage(`
 {{if .Triaged}}
 Report: <a href="/report?id={{.ID}}">{{.Triaged}}</a>
 {{end}}
+{{range $d := .PossibleDups}}
+<br>Possible duplicate of <a href="/crash?id={{$d.ID}}">{{$d.Description}}</a>
+{{end}}
 
 <table class="list_table">
 	<tr>
@@ -799,6 +1321,42 @@ var prioTemplate = html.CreatePage(`
 </body></html>
 `)
 
+type UIFuncCoverData struct {
+	Name  string
+	Funcs []*cover.FuncStats
+}
+
+var funcCoverTemplate = html.CreatePage(`
+<!doctype html>
+<html>
+<head>
+	<title>{{.Name }} syzkaller</title>
+	{{HEAD}}
+</head>
+<body>
+
+<table class="list_table">
+	<caption>Per-function coverage:</caption>
+	<tr>
+		<th><a onclick="return sortTable(this, 'Function', textSort)" href="#">Function</a></th>
+		<th><a onclick="return sortTable(this, 'File', textSort)" href="#">File</a></th>
+		<th><a onclick="return sortTable(this, 'Covered', numSort)" href="#">Covered</a></th>
+		<th><a onclick="return sortTable(this, 'Total', numSort)" href="#">Total</a></th>
+		<th><a onclick="return sortTable(this, 'Percent', numSort)" href="#">Percent</a></th>
+	</tr>
+	{{range $f := $.Funcs}}
+	<tr>
+		<td>{{$f.Name}}</td>
+		<td>{{$f.File}}</td>
+		<td>{{$f.Covered}}</td>
+		<td>{{$f.Total}}</td>
+		<td>{{$f.Percent}}%</td>
+	</tr>
+	{{end}}
+</table>
+</body></html>
+`)
+
 type UIFallbackCoverData struct {
 	Calls []UIFallbackCall
 }
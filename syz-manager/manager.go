@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"net"
 	"os"
@@ -20,7 +21,6 @@ import (
 
 	"github.com/google/syzkaller/dashboard/dashapi"
 	"github.com/google/syzkaller/pkg/cover"
-	"github.com/google/syzkaller/pkg/csource"
 	"github.com/google/syzkaller/pkg/db"
 	"github.com/google/syzkaller/pkg/gce"
 	"github.com/google/syzkaller/pkg/hash"
@@ -60,6 +60,9 @@ type Manager struct {
 	crashTypes     map[string]bool
 	vmStop         chan bool
 	checkResult    *rpctype.CheckArgs
+	disabledCalls  []rpctype.SyscallReason // for the current sandbox
+	coverFilter    []signal.PCRange        // see mgrconfig.Config.CoverFilter
+	signalMode     signal.Mode
 	fresh          bool
 	numFuzzing     uint32
 	numReproducing uint32
@@ -79,7 +82,11 @@ type Manager struct {
 
 	needMoreRepros chan chan bool
 	hubReproQueue  chan *Crash
-	reproRequest   chan chan map[string]bool
+	reproSched     *reproScheduler
+
+	// Stack signatures of local crash types for possible dups lookup (see crashStacksLocked).
+	crashStacksMu sync.Mutex
+	crashStacks   map[string]*crashStack
 
 	// For checking that files that we are using are not changing under us.
 	// Maps file name to modification time.
@@ -104,8 +111,10 @@ const (
 const currentDBVersion = 3
 
 type Crash struct {
-	vmIndex int
-	hub     bool // this crash was created based on a repro from hub
+	vmIndex       int
+	hub           bool // this crash was created based on a repro from hub
+	newTitle      bool // this is the first crash with this title since manager start
+	dashRequested bool // dashboard asked to reproduce this crash
 	*report.Report
 }
 
@@ -173,15 +182,35 @@ func RunManager(cfg *mgrconfig.Config, target *prog.Target, sysTarget *targets.T
 		vmStop:           make(chan bool),
 		hubReproQueue:    make(chan *Crash, 10),
 		needMoreRepros:   make(chan chan bool),
-		reproRequest:     make(chan chan map[string]bool),
 		usedFiles:        make(map[string]time.Time),
 	}
+	vmCount := 0
+	if vmPool != nil {
+		vmCount = vmPool.Count()
+	}
+	mgr.reproSched = newReproScheduler(vmCount, instancesPerRepro(vmCount),
+		cfg.ReproAttempts, cfg.ReproVMFraction)
 
 	log.Logf(0, "loading corpus...")
 	mgr.corpusDB, err = db.Open(filepath.Join(cfg.Workdir, "corpus.db"))
 	if err != nil {
 		log.Fatalf("failed to open corpus database: %v", err)
 	}
+	mgr.signalMode, err = signal.ParseMode(cfg.SignalMode)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := mgr.checkSignalMode(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if cfg.CoverFilter != nil {
+		mgr.coverFilter, err = coverFilterRanges(cfg, sysTarget.KernelObject)
+		if err != nil {
+			log.Fatalf("failed to resolve cover_filter: %v", err)
+		}
+		log.Logf(0, "cover filter: %v PC ranges", len(mgr.coverFilter))
+	}
 
 	// Create HTTP server.
 	mgr.initHTTP()
@@ -274,12 +303,19 @@ type RunResult struct {
 }
 
 type ReproResult struct {
-	instances []int
-	title0    string
-	res       *repro.Result
-	stats     *repro.Stats
-	err       error
-	hub       bool // repro came from hub
+	job   *reproJob
+	res   *repro.Result
+	stats *repro.Stats
+	err   error
+}
+
+// instancesPerRepro returns number of VMs used for a single repro.
+func instancesPerRepro(vmCount int) int {
+	instances := 4
+	if instances > vmCount {
+		instances = vmCount
+	}
+	return instances
 }
 
 // Manager needs to be refactored (#605).
@@ -287,20 +323,19 @@ type ReproResult struct {
 func (mgr *Manager) vmLoop() {
 	log.Logf(0, "booting test machines...")
 	log.Logf(0, "wait for the connection from test machine...")
-	instancesPerRepro := 4
 	vmCount := mgr.vmPool.Count()
-	if instancesPerRepro > vmCount {
-		instancesPerRepro = vmCount
-	}
+	instancesPerRepro := instancesPerRepro(vmCount)
 	instances := make([]int, vmCount)
 	for i := range instances {
 		instances[i] = vmCount - i - 1
 	}
 	runDone := make(chan *RunResult, 1)
 	pendingRepro := make(map[*Crash]bool)
-	reproducing := make(map[string]bool)
-	reproInstances := 0
-	var reproQueue []*Crash
+	sched := mgr.reproSched
+	for _, crash := range mgr.checkpointedRepros() {
+		log.Logf(0, "resuming repro of '%v' from checkpoint", crash.Title)
+		pendingRepro[crash] = true
+	}
 	reproDone := make(chan *ReproResult, 1)
 	stopPending := false
 	shutdown := vm.Shutdown
@@ -310,41 +345,41 @@ func (mgr *Manager) vmLoop() {
 		mgr.mu.Unlock()
 
 		for crash := range pendingRepro {
-			if reproducing[crash.Title] {
+			if sched.pending(crash.Title) {
 				continue
 			}
 			delete(pendingRepro, crash)
 			if !mgr.needRepro(crash) {
 				continue
 			}
+			if !sched.enqueue(crash) {
+				log.Logf(1, "loop: no repro attempts left for '%v'", crash.Title)
+				continue
+			}
 			log.Logf(1, "loop: add to repro queue '%v'", crash.Title)
-			reproducing[crash.Title] = true
-			reproQueue = append(reproQueue, crash)
 		}
 
+		queued, running := sched.stats()
 		log.Logf(1, "loop: phase=%v shutdown=%v instances=%v/%v %+v repro: pending=%v reproducing=%v queued=%v",
 			phase, shutdown == nil, len(instances), vmCount, instances,
-			len(pendingRepro), len(reproducing), len(reproQueue))
+			len(pendingRepro), running, queued)
 
 		canRepro := func() bool {
-			return phase >= phaseTriagedHub &&
-				len(reproQueue) != 0 && reproInstances+instancesPerRepro <= vmCount
+			return phase >= phaseTriagedHub && sched.ready()
 		}
 
 		if shutdown != nil {
 			for canRepro() && len(instances) >= instancesPerRepro {
-				last := len(reproQueue) - 1
-				crash := reproQueue[last]
-				reproQueue[last] = nil
-				reproQueue = reproQueue[:last]
 				vmIndexes := append([]int{}, instances[len(instances)-instancesPerRepro:]...)
+				job := sched.start(vmIndexes)
 				instances = instances[:len(instances)-instancesPerRepro]
-				reproInstances += instancesPerRepro
 				atomic.AddUint32(&mgr.numReproducing, 1)
-				log.Logf(1, "loop: starting repro of '%v' on instances %+v", crash.Title, vmIndexes)
+				log.Logf(1, "loop: starting repro of '%v' (attempt %v) on instances %+v",
+					job.crash.Title, job.attempt, vmIndexes)
 				go func() {
-					res, stats, err := repro.Run(crash.Output, mgr.cfg, mgr.reporter, mgr.vmPool, vmIndexes)
-					reproDone <- &ReproResult{vmIndexes, crash.Title, res, stats, err, crash.hub}
+					res, stats, err := repro.Run(job.crash.Output, mgr.cfg, mgr.reporter, mgr.vmPool,
+						vmIndexes, mgr.reproCheckpoint(job.crash.Title), &job.progress)
+					reproDone <- &ReproResult{job, res, stats, err}
 				}()
 			}
 			for !canRepro() && len(instances) != 0 {
@@ -393,20 +428,20 @@ func (mgr *Manager) vmLoop() {
 				crepro = res.res.CRepro
 				title = res.res.Report.Title
 			}
+			job := res.job
 			log.Logf(1, "loop: repro on %+v finished '%v', repro=%v crepro=%v desc='%v'",
-				res.instances, res.title0, res.res != nil, crepro, title)
+				job.vmIndexes, job.crash.Title, res.res != nil, crepro, title)
 			if res.err != nil {
 				log.Logf(0, "repro failed: %v", res.err)
 			}
-			delete(reproducing, res.title0)
-			instances = append(instances, res.instances...)
-			reproInstances -= instancesPerRepro
+			sched.done(job, res.res, res.stats, res.err)
+			instances = append(instances, job.vmIndexes...)
 			if res.res == nil {
-				if !res.hub {
-					mgr.saveFailedRepro(res.title0, res.stats)
+				if !job.crash.hub {
+					mgr.saveFailedRepro(job.crash.Title, res.stats)
 				}
 			} else {
-				mgr.saveRepro(res.res, res.stats, res.hub)
+				mgr.saveRepro(res.res, res.stats, job.crash.hub)
 			}
 		case <-shutdown:
 			log.Logf(1, "loop: shutting down...")
@@ -415,18 +450,30 @@ func (mgr *Manager) vmLoop() {
 			log.Logf(1, "loop: get repro from hub")
 			pendingRepro[crash] = true
 		case reply := <-mgr.needMoreRepros:
-			reply <- phase >= phaseTriagedHub &&
-				len(reproQueue)+len(pendingRepro)+len(reproducing) == 0
+			reply <- phase >= phaseTriagedHub && len(pendingRepro) == 0 && sched.idle()
 			goto wait
-		case reply := <-mgr.reproRequest:
-			repros := make(map[string]bool)
-			for title := range reproducing {
-				repros[title] = true
+		}
+	}
+}
+
+// checkSignalMode ensures that we don't mix corpus collected with different signal modes:
+// corpus programs are minimized and smashed according to signal they give in a particular mode.
+func (mgr *Manager) checkSignalMode() error {
+	modeFile := filepath.Join(mgr.cfg.Workdir, "corpus.signal_mode")
+	if len(mgr.corpusDB.Records) != 0 {
+		// Corpus collected before signal modes were introduced has no mode file.
+		corpusMode := signal.ModeEdge
+		if data, err := ioutil.ReadFile(modeFile); err == nil {
+			if corpusMode, err = signal.ParseMode(strings.TrimSpace(string(data))); err != nil {
+				return fmt.Errorf("failed to parse %v: %v", modeFile, err)
 			}
-			reply <- repros
-			goto wait
+		}
+		if corpusMode != mgr.signalMode {
+			return fmt.Errorf("corpus in %v was collected with signal_mode %v, but config requests %v;"+
+				" use a separate workdir for each signal mode", mgr.cfg.Workdir, corpusMode, mgr.signalMode)
 		}
 	}
+	return osutil.WriteFile(modeFile, []byte(mgr.signalMode.String()))
 }
 
 func (mgr *Manager) loadCorpus() {
@@ -599,6 +646,7 @@ func (mgr *Manager) saveCrash(crash *Crash) bool {
 	mgr.stats.crashes.inc()
 	mgr.mu.Lock()
 	if !mgr.crashTypes[crash.Title] {
+		crash.newTitle = true
 		mgr.crashTypes[crash.Title] = true
 		mgr.stats.crashTypes.inc()
 	}
@@ -615,6 +663,24 @@ func (mgr *Manager) saveCrash(crash *Crash) bool {
 			Maintainers: crash.Maintainers,
 			Log:         crash.Output,
 			Report:      crash.Report.Report,
+			Stack:       crash.Report.Stack,
+			Severity:    crash.Severity,
+			Labels:      crash.Report.Labels,
+		}
+		if d := crash.Report.Details; d != nil {
+			dc.Details = &dashapi.CrashDetails{
+				Tool:       d.Tool,
+				BugType:    d.BugType,
+				Access:     d.Access,
+				AccessSize: d.AccessSize,
+				Address:    d.Address,
+				Task:       d.Task,
+				PID:        d.PID,
+				CallTrace:  d.CallTrace,
+				AllocStack: d.AllocStack,
+				FreeStack:  d.FreeStack,
+				LockChain:  d.LockChain,
+			}
 		}
 		resp, err := mgr.dash.ReportCrash(dc)
 		if err != nil {
@@ -633,6 +699,22 @@ func (mgr *Manager) saveCrash(crash *Crash) bool {
 	if err := osutil.WriteFile(filepath.Join(dir, "description"), []byte(crash.Title+"\n")); err != nil {
 		log.Logf(0, "failed to write crash: %v", err)
 	}
+	// Stack signature is used to find possible duplicates with different titles,
+	// stack of the first crash is good enough for that.
+	if stackFile := filepath.Join(dir, "stack"); len(crash.Report.Stack) != 0 && !osutil.IsExist(stackFile) {
+		osutil.WriteFile(stackFile, []byte(strings.Join(crash.Report.Stack, "\n")+"\n"))
+		mgr.addCrashStack(id, crash.Title, crash.Report.Stack)
+	}
+	if len(crash.Report.Labels) != 0 {
+		osutil.WriteFile(filepath.Join(dir, "labels"), []byte(strings.Join(crash.Report.Labels, "\n")+"\n"))
+	}
+	// Different crashes with the same title can have different severity
+	// (e.g. KASAN reports with different access types), we keep the highest one.
+	severityFile := filepath.Join(dir, "severity")
+	oldSeverity, _ := ioutil.ReadFile(severityFile)
+	if mgrconfig.CrashSeverity(crash.Severity) > mgrconfig.CrashSeverity(string(trimNewLines(oldSeverity))) {
+		osutil.WriteFile(severityFile, []byte(crash.Severity+"\n"))
+	}
 	// Save up to 100 reports. If we already have 100, overwrite the oldest one.
 	// Newer reports are generally more useful. Overwriting is also needed
 	// to be able to understand if a particular bug still happens or already fixed.
@@ -663,7 +745,33 @@ func (mgr *Manager) saveCrash(crash *Crash) bool {
 	return mgr.needLocalRepro(crash)
 }
 
-const maxReproAttempts = 3
+// reproCheckpoint returns file where progress of reproduction of the crash with the title is saved.
+func (mgr *Manager) reproCheckpoint(title string) string {
+	dir := filepath.Join(mgr.crashdir, hash.String([]byte(title)))
+	osutil.MkdirAll(dir)
+	return filepath.Join(dir, "repro.checkpoint")
+}
+
+// checkpointedRepros returns crashes which reproduction was interrupted by a restart.
+func (mgr *Manager) checkpointedRepros() []*Crash {
+	dirs, err := osutil.ListDir(mgr.crashdir)
+	if err != nil {
+		return nil
+	}
+	var crashes []*Crash
+	for _, dir := range dirs {
+		crashLog := repro.CheckpointLog(filepath.Join(mgr.crashdir, dir, "repro.checkpoint"))
+		if crashLog == nil {
+			continue
+		}
+		rep := mgr.reporter.Parse(crashLog)
+		if rep == nil {
+			continue
+		}
+		crashes = append(crashes, &Crash{vmIndex: -1, Report: rep})
+	}
+	return crashes
+}
 
 func (mgr *Manager) needLocalRepro(crash *Crash) bool {
 	if !mgr.cfg.Reproduce || crash.Corrupted {
@@ -674,7 +782,7 @@ func (mgr *Manager) needLocalRepro(crash *Crash) bool {
 	if osutil.IsExist(filepath.Join(dir, "repro.prog")) {
 		return false
 	}
-	for i := 0; i < maxReproAttempts; i++ {
+	for i := 0; i < mgr.cfg.ReproAttempts; i++ {
 		if !osutil.IsExist(filepath.Join(dir, fmt.Sprintf("repro%v", i))) {
 			return true
 		}
@@ -701,6 +809,7 @@ func (mgr *Manager) needRepro(crash *Crash) bool {
 	if err != nil {
 		log.Logf(0, "dashboard.NeedRepro failed: %v", err)
 	}
+	crash.dashRequested = needRepro
 	return needRepro
 }
 
@@ -723,7 +832,7 @@ func (mgr *Manager) saveFailedRepro(title string, stats *repro.Stats) {
 	}
 	dir := filepath.Join(mgr.crashdir, hash.String([]byte(title)))
 	osutil.MkdirAll(dir)
-	for i := 0; i < maxReproAttempts; i++ {
+	for i := 0; i < mgr.cfg.ReproAttempts; i++ {
 		name := filepath.Join(dir, fmt.Sprintf("repro%v", i))
 		if !osutil.IsExist(name) {
 			saveReproStats(name, stats)
@@ -738,7 +847,7 @@ func (mgr *Manager) saveRepro(res *repro.Result, stats *repro.Stats, hub bool) {
 		log.Logf(0, "failed to symbolize repro: %v", err)
 	}
 	opts := fmt.Sprintf("# %+v\n", res.Opts)
-	prog := res.Prog.Serialize()
+	prog := res.Serialize()
 
 	// Append this repro to repro list to send to hub if it didn't come from hub originally.
 	if !hub {
@@ -749,16 +858,10 @@ func (mgr *Manager) saveRepro(res *repro.Result, stats *repro.Stats, hub bool) {
 		mgr.mu.Unlock()
 	}
 
-	var cprogText []byte
+	var cprogs [][]byte
 	if res.CRepro {
-		cprog, err := csource.Write(res.Prog, res.Opts)
-		if err == nil {
-			formatted, err := csource.Format(cprog)
-			if err == nil {
-				cprog = formatted
-			}
-			cprogText = cprog
-		} else {
+		var err error
+		if cprogs, err = res.CSources(); err != nil {
 			log.Logf(0, "failed to write C source: %v", err)
 		}
 	}
@@ -775,9 +878,23 @@ func (mgr *Manager) saveRepro(res *repro.Result, stats *repro.Stats, hub bool) {
 			Maintainers: res.Report.Maintainers,
 			Log:         res.Report.Output,
 			Report:      res.Report.Report,
+			Labels:      res.Report.Labels,
 			ReproOpts:   res.Opts.Serialize(),
-			ReproSyz:    res.Prog.Serialize(),
-			ReproC:      cprogText,
+			ReproSyz:    prog,
+		}
+		if len(cprogs) != 0 {
+			dc.ReproC = cprogs[0]
+			dc.ReproCExtra = cprogs[1:]
+		}
+		if stats != nil && stats.SyzReliability.Runs != 0 {
+			dc.ReproReliability = &dashapi.ReproReliability{
+				SyzRuns:            stats.SyzReliability.Runs,
+				SyzCrashes:         stats.SyzReliability.Crashes,
+				SyzMeanTimeToCrash: stats.SyzReliability.MeanTimeToCrash,
+				CRuns:              stats.CReliability.Runs,
+				CCrashes:           stats.CReliability.Crashes,
+				CMeanTimeToCrash:   stats.CReliability.MeanTimeToCrash,
+			}
 		}
 		if _, err := mgr.dash.ReportCrash(dc); err != nil {
 			log.Logf(0, "failed to report repro to dashboard: %v", err)
@@ -804,8 +921,13 @@ func (mgr *Manager) saveRepro(res *repro.Result, stats *repro.Stats, hub bool) {
 	if len(rep.Report) > 0 {
 		osutil.WriteFile(filepath.Join(dir, "repro.report"), rep.Report)
 	}
-	if len(cprogText) > 0 {
-		osutil.WriteFile(filepath.Join(dir, "repro.cprog"), cprogText)
+	for i, cprog := range cprogs {
+		// Programs of a multi-program reproducer need to be run concurrently.
+		name := "repro.cprog"
+		if i != 0 {
+			name = fmt.Sprintf("repro.%v.cprog", i)
+		}
+		osutil.WriteFile(filepath.Join(dir, name), cprog)
 	}
 	saveReproStats(filepath.Join(dir, "repro.stats"), stats)
 }
@@ -813,10 +935,12 @@ func (mgr *Manager) saveRepro(res *repro.Result, stats *repro.Stats, hub bool) {
 func saveReproStats(filename string, stats *repro.Stats) {
 	text := ""
 	if stats != nil {
-		text = fmt.Sprintf("Extracting prog: %v\nMinimizing prog: %v\n"+
-			"Simplifying prog options: %v\nExtracting C: %v\nSimplifying C: %v\n\n\n%s",
-			stats.ExtractProgTime, stats.MinimizeProgTime,
-			stats.SimplifyProgTime, stats.ExtractCTime, stats.SimplifyCTime, stats.Log)
+		text = fmt.Sprintf("Extracting prog: %v\nExtracting parallel progs: %v\nMinimizing prog: %v\n"+
+			"Simplifying prog options: %v\nExtracting C: %v\nSimplifying C: %v\n"+
+			"Measuring reliability: %v\nSyz repro reliability: %v\nC repro reliability: %v\n\n\n%s",
+			stats.ExtractProgTime, stats.ParallelTime, stats.MinimizeProgTime,
+			stats.SimplifyProgTime, stats.ExtractCTime, stats.SimplifyCTime,
+			stats.ReliabilityTime, stats.SyzReliability, stats.CReliability, stats.Log)
 	}
 	osutil.WriteFile(filename, []byte(text))
 }
@@ -926,6 +1050,7 @@ func (mgr *Manager) machineChecked(a *rpctype.CheckArgs) {
 		log.Logf(0, "%-24v: %v", feat.Name, feat.Reason)
 	}
 	mgr.checkResult = a
+	mgr.disabledCalls = a.DisabledCalls[mgr.cfg.Sandbox]
 	mgr.loadCorpus()
 	mgr.firstConnect = time.Now()
 }
@@ -937,7 +1062,7 @@ func (mgr *Manager) newInput(inp rpctype.RPCInput, sign signal.Signal) {
 	if old, ok := mgr.corpus[sig]; ok {
 		// The input is already present, but possibly with diffent signal/coverage/call.
 		sign.Merge(old.Signal.Deserialize())
-		old.Signal = sign.Serialize()
+		old.Signal = sign.Serialize(mgr.signalMode)
 		var cov cover.Cover
 		cov.Merge(old.Cover)
 		cov.Merge(inp.Cover)
@@ -0,0 +1,370 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/pkg/repro"
+)
+
+const (
+	// Delay before the next repro attempt for a title after a failed one,
+	// doubled after each subsequent failure.
+	reproBackoff    = 30 * time.Minute
+	reproMaxBackoff = 8 * time.Hour
+	// Number of finished repros shown on the repros page.
+	reproHistory = 100
+)
+
+// Repro job states.
+const (
+	reproQueued = iota
+	reproRunning
+	reproSucceeded
+	reproFailed
+)
+
+var reproStateNames = []string{"queued", "running", "succeeded", "failed"}
+
+// reproJob is a single reproduction attempt of a crash.
+type reproJob struct {
+	id        int
+	crash     *Crash
+	state     int
+	queued    time.Time
+	started   time.Time
+	finished  time.Time
+	notBefore time.Time // the job can't be started before this time (backoff)
+	attempt   int       // 1-based attempt number for the title
+	vmIndexes []int
+	progress  progressLog
+	err       string
+}
+
+// progressLog accumulates progress output of a running repro.
+type progressLog struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *progressLog) Write(data []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(data)
+}
+
+func (l *progressLog) reset(data []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf.Reset()
+	l.buf.Write(data)
+}
+
+func (l *progressLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
+
+// reproTitle is per-title repro history.
+type reproTitle struct {
+	attempts  int
+	failures  int
+	notBefore time.Time
+}
+
+// reproScheduler decides what crashes to reproduce and when.
+// Crashes are prioritized as follows: repros requested by dashboard, new crash titles,
+// more severe crashes, local crashes before hub repros, and newer crashes.
+// Every title has a budget of attempts (maxAttempts) with exponential backoff
+// between failed attempts (dashboard requests bypass both),
+// and the total number of VMs used for reproduction is capped.
+type reproScheduler struct {
+	mu          sync.Mutex
+	perRepro    int // VMs used by a single repro
+	maxVMs      int // max VMs used by all repros
+	maxAttempts int // max repro attempts per title
+	usedVMs     int
+	nextID      int
+	queue       []*reproJob
+	running     map[string]*reproJob
+	history     []*reproJob // finished jobs, the most recent last
+	titles      map[string]*reproTitle
+	timeNow     func() time.Time
+}
+
+func newReproScheduler(vmCount, perRepro, maxAttempts int, vmFraction float64) *reproScheduler {
+	maxVMs := int(float64(vmCount) * vmFraction)
+	if maxVMs < perRepro {
+		// Always allow at least one repro, otherwise crashes are never reproduced.
+		maxVMs = perRepro
+	}
+	return &reproScheduler{
+		perRepro:    perRepro,
+		maxVMs:      maxVMs,
+		maxAttempts: maxAttempts,
+		running:     make(map[string]*reproJob),
+		titles:      make(map[string]*reproTitle),
+		timeNow:     time.Now,
+	}
+}
+
+// pending returns true if the crash with the title is queued or being reproduced.
+func (s *reproScheduler) pending(title string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[title] != nil {
+		return true
+	}
+	for _, job := range s.queue {
+		if job.crash.Title == title {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue adds crash to the repro queue. Returns false if the title has exhausted its budget.
+// Repros requested by dashboard are not subject to the budget and backoff.
+func (s *reproScheduler) enqueue(crash *Crash) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.title(crash.Title)
+	if !crash.hub && !crash.dashRequested && t.attempts >= s.maxAttempts {
+		return false
+	}
+	s.nextID++
+	job := &reproJob{
+		id:        s.nextID,
+		crash:     crash,
+		state:     reproQueued,
+		queued:    s.timeNow(),
+		notBefore: t.notBefore,
+	}
+	if crash.dashRequested {
+		job.notBefore = time.Time{}
+	}
+	s.queue = append(s.queue, job)
+	return true
+}
+
+func (s *reproScheduler) title(title string) *reproTitle {
+	t := s.titles[title]
+	if t == nil {
+		t = new(reproTitle)
+		s.titles[title] = t
+	}
+	return t
+}
+
+// ready returns true if a queued repro can be started now within the VM budget.
+func (s *reproScheduler) ready() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.next() != -1
+}
+
+// next returns index of the highest priority job that can be started now, or -1.
+func (s *reproScheduler) next() int {
+	if s.usedVMs+s.perRepro > s.maxVMs {
+		return -1
+	}
+	now := s.timeNow()
+	best := -1
+	for i, job := range s.queue {
+		if now.Before(job.notBefore) {
+			continue
+		}
+		if best == -1 || reproLess(s.queue[best], job) {
+			best = i
+		}
+	}
+	return best
+}
+
+// reproLess returns true if job a has lower priority than job b.
+func reproLess(a, b *reproJob) bool {
+	if a.crash.dashRequested != b.crash.dashRequested {
+		return b.crash.dashRequested
+	}
+	if a.crash.newTitle != b.crash.newTitle {
+		return b.crash.newTitle
+	}
+	sevA, sevB := mgrconfig.CrashSeverity(a.crash.Severity), mgrconfig.CrashSeverity(b.crash.Severity)
+	if sevA != sevB {
+		return sevA < sevB
+	}
+	if a.crash.hub != b.crash.hub {
+		return a.crash.hub
+	}
+	return a.id < b.id
+}
+
+// start dequeues the highest priority job that can be started now and assigns VMs to it.
+// vmIndexes must contain the number of VMs required for a single repro.
+func (s *reproScheduler) start(vmIndexes []int) *reproJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := s.next()
+	if idx == -1 {
+		return nil
+	}
+	job := s.queue[idx]
+	copy(s.queue[idx:], s.queue[idx+1:])
+	s.queue[len(s.queue)-1] = nil
+	s.queue = s.queue[:len(s.queue)-1]
+	t := s.title(job.crash.Title)
+	t.attempts++
+	job.attempt = t.attempts
+	job.state = reproRunning
+	job.started = s.timeNow()
+	job.vmIndexes = vmIndexes
+	s.usedVMs += s.perRepro
+	s.running[job.crash.Title] = job
+	return job
+}
+
+// done records result of the job and releases its VMs.
+func (s *reproScheduler) done(job *reproJob, res *repro.Result, stats *repro.Stats, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, job.crash.Title)
+	s.usedVMs -= s.perRepro
+	job.finished = s.timeNow()
+	job.state = reproSucceeded
+	if err != nil {
+		job.err = err.Error()
+	}
+	if stats != nil && len(stats.Log) != 0 {
+		// Stats log is the complete log of the repro (includes checkpointed part).
+		job.progress.reset(stats.Log)
+	}
+	if res == nil {
+		job.state = reproFailed
+		t := s.title(job.crash.Title)
+		t.failures++
+		backoff := reproBackoff << uint(t.failures-1)
+		if backoff > reproMaxBackoff || backoff <= 0 {
+			backoff = reproMaxBackoff
+		}
+		t.notBefore = job.finished.Add(backoff)
+	}
+	s.history = append(s.history, job)
+	if len(s.history) > reproHistory {
+		s.history[0] = nil
+		s.history = s.history[1:]
+	}
+}
+
+// idle returns true if there are no running repros and no queued repros that can be started now.
+// Jobs waiting for backoff don't count, otherwise they would stall hub repro exchange for hours.
+func (s *reproScheduler) idle() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.running) != 0 {
+		return false
+	}
+	now := s.timeNow()
+	for _, job := range s.queue {
+		if !now.Before(job.notBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// activeTitles returns titles of crashes that are queued or being reproduced.
+func (s *reproScheduler) activeTitles() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	titles := make(map[string]bool)
+	for title := range s.running {
+		titles[title] = true
+	}
+	for _, job := range s.queue {
+		titles[job.crash.Title] = true
+	}
+	return titles
+}
+
+// stats returns number of queued and running repros.
+func (s *reproScheduler) stats() (queued, running int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue), len(s.running)
+}
+
+// jobs returns all known jobs: running, queued (in priority order) and finished (most recent first).
+func (s *reproScheduler) jobs() []*UIRepro {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []*UIRepro
+	var running []*reproJob
+	for _, job := range s.running {
+		running = append(running, job)
+	}
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].id < running[j].id
+	})
+	queue := append([]*reproJob{}, s.queue...)
+	sort.Slice(queue, func(i, j int) bool {
+		return reproLess(queue[j], queue[i])
+	})
+	for _, job := range append(running, queue...) {
+		res = append(res, s.uiJob(job))
+	}
+	for i := len(s.history) - 1; i >= 0; i-- {
+		res = append(res, s.uiJob(s.history[i]))
+	}
+	return res
+}
+
+// job returns the job with the given id, or nil.
+func (s *reproScheduler) job(id int) *UIRepro {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.running {
+		if job.id == id {
+			return s.uiJob(job)
+		}
+	}
+	for _, job := range append(append([]*reproJob{}, s.queue...), s.history...) {
+		if job.id == id {
+			return s.uiJob(job)
+		}
+	}
+	return nil
+}
+
+func (s *reproScheduler) uiJob(job *reproJob) *UIRepro {
+	ui := &UIRepro{
+		ID:            job.id,
+		Title:         job.crash.Title,
+		State:         reproStateNames[job.state],
+		Severity:      job.crash.Severity,
+		DashRequested: job.crash.dashRequested,
+		NewTitle:      job.crash.newTitle,
+		Hub:           job.crash.hub,
+		Attempt:       job.attempt,
+		Queued:        job.queued,
+		Started:       job.started,
+		Finished:      job.finished,
+		NotBefore:     job.notBefore,
+		VMs:           job.vmIndexes,
+		Error:         job.err,
+		Log:           job.progress.String(),
+	}
+	switch job.state {
+	case reproRunning:
+		ui.Duration = s.timeNow().Sub(job.started) / time.Second * time.Second
+	case reproSucceeded, reproFailed:
+		ui.Duration = job.finished.Sub(job.started) / time.Second * time.Second
+	}
+	return ui
+}
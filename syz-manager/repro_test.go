@@ -0,0 +1,141 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/pkg/report"
+	"github.com/google/syzkaller/pkg/repro"
+)
+
+func newTestCrash(title, severity string) *Crash {
+	return &Crash{Report: &report.Report{Title: title, Severity: severity}}
+}
+
+func TestReproSchedulerPriority(t *testing.T) {
+	s := newReproScheduler(16, 4, 3, 1)
+	low := newTestCrash("low", "low")
+	high := newTestCrash("high", "high")
+	hub := newTestCrash("hub", "low")
+	hub.hub = true
+	fresh := newTestCrash("new", "low")
+	fresh.newTitle = true
+	dash := newTestCrash("dash", "low")
+	dash.dashRequested = true
+	for _, crash := range []*Crash{low, high, hub, fresh, dash} {
+		if !s.enqueue(crash) {
+			t.Fatalf("failed to enqueue %v", crash.Title)
+		}
+	}
+	for _, want := range []string{"dash", "new", "high", "low"} {
+		job := s.start(nil)
+		if job == nil {
+			t.Fatalf("no job started, want %v", want)
+		}
+		if job.crash.Title != want {
+			t.Fatalf("started %v, want %v", job.crash.Title, want)
+		}
+	}
+	// VM budget is exhausted.
+	if s.ready() {
+		t.Fatalf("scheduler is ready with all VMs in use")
+	}
+	if !s.pending("hub") || !s.pending("dash") || s.pending("foo") {
+		t.Fatalf("wrong pending state")
+	}
+}
+
+func TestReproSchedulerVMFraction(t *testing.T) {
+	s := newReproScheduler(16, 4, 3, 0.5)
+	for i := 0; i < 3; i++ {
+		s.enqueue(newTestCrash(fmt.Sprint(i), ""))
+	}
+	if s.start(nil) == nil || s.start(nil) == nil {
+		t.Fatalf("failed to start repros")
+	}
+	if s.ready() {
+		t.Fatalf("repros use more than half of VMs")
+	}
+	// At least one repro is always allowed.
+	s = newReproScheduler(2, 2, 3, 0.1)
+	s.enqueue(newTestCrash("foo", ""))
+	if s.start(nil) == nil {
+		t.Fatalf("failed to start repro")
+	}
+}
+
+func TestReproSchedulerBackoff(t *testing.T) {
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newReproScheduler(4, 4, 2, 1)
+	s.timeNow = func() time.Time { return now }
+	crash := newTestCrash("foo", "")
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		if !s.enqueue(crash) {
+			t.Fatalf("attempt %v: failed to enqueue", attempt)
+		}
+		if attempt != 1 {
+			if s.ready() {
+				t.Fatalf("attempt %v: ready during backoff", attempt)
+			}
+			now = now.Add(reproBackoff << uint(attempt-2))
+		}
+		job := s.start(nil)
+		if job == nil {
+			t.Fatalf("attempt %v: not started after backoff", attempt)
+		}
+		if job.attempt != attempt {
+			t.Fatalf("bad attempt number %v, want %v", job.attempt, attempt)
+		}
+		s.done(job, nil, &repro.Stats{Log: []byte("log")}, nil)
+		if job.state != reproFailed || job.progress.String() != "log" {
+			t.Fatalf("bad job state %v, log %q", job.state, job.progress.String())
+		}
+	}
+	if s.enqueue(crash) {
+		t.Fatalf("enqueued crash with exhausted budget")
+	}
+	if !s.idle() {
+		t.Fatalf("scheduler is not idle")
+	}
+	if jobs := s.jobs(); len(jobs) != s.maxAttempts || jobs[0].Attempt != s.maxAttempts {
+		t.Fatalf("bad jobs history: %+v", jobs)
+	}
+	// Dashboard requests ignore the budget and the backoff.
+	dash := newTestCrash("foo", "")
+	dash.dashRequested = true
+	if !s.enqueue(dash) {
+		t.Fatalf("failed to enqueue dashboard request with exhausted budget")
+	}
+	if job := s.start(nil); job == nil || job.crash != dash {
+		t.Fatalf("dashboard request is not started: %+v", job)
+	}
+}
+
+func TestReproSchedulerIdle(t *testing.T) {
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newReproScheduler(4, 4, 3, 1)
+	s.timeNow = func() time.Time { return now }
+	crash := newTestCrash("foo", "")
+	s.enqueue(crash)
+	if s.idle() {
+		t.Fatalf("idle with a queued repro")
+	}
+	job := s.start(nil)
+	if s.idle() {
+		t.Fatalf("idle with a running repro")
+	}
+	s.done(job, nil, nil, nil)
+	s.enqueue(crash)
+	// The only queued job waits for backoff.
+	if !s.idle() {
+		t.Fatalf("not idle with a backed off repro")
+	}
+	now = now.Add(reproBackoff)
+	if s.idle() {
+		t.Fatalf("idle with a repro after backoff")
+	}
+}
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"fmt"
 	"net"
 	"sync"
 
@@ -16,11 +17,15 @@ import (
 )
 
 type RPCServer struct {
-	mgr             RPCManagerView
-	target          *prog.Target
-	enabledSyscalls []int
-	stats           *Stats
-	batchSize       int
+	mgr               RPCManagerView
+	target            *prog.Target
+	enabledSyscalls   []int
+	featureOverrides  map[string]bool
+	coverFilter       []signal.PCRange
+	coverFilterWeight float64
+	signalMode        signal.Mode
+	stats             *Stats
+	batchSize         int
 
 	mu           sync.Mutex
 	fuzzers      map[string]*Fuzzer
@@ -46,11 +51,17 @@ type RPCManagerView interface {
 
 func startRPCServer(mgr *Manager) (int, error) {
 	serv := &RPCServer{
-		mgr:             mgr,
-		target:          mgr.target,
-		enabledSyscalls: mgr.enabledSyscalls,
-		stats:           mgr.stats,
-		fuzzers:         make(map[string]*Fuzzer),
+		mgr:              mgr,
+		target:           mgr.target,
+		enabledSyscalls:  mgr.enabledSyscalls,
+		featureOverrides: mgr.cfg.Features,
+		coverFilter:      mgr.coverFilter,
+		signalMode:       mgr.signalMode,
+		stats:            mgr.stats,
+		fuzzers:          make(map[string]*Fuzzer),
+	}
+	if mgr.cfg.CoverFilter != nil {
+		serv.coverFilterWeight = mgr.cfg.CoverFilter.Weight
 	}
 	serv.batchSize = 5
 	if serv.batchSize < mgr.cfg.Procs {
@@ -82,6 +93,10 @@ func (serv *RPCServer) Connect(a *rpctype.ConnectArgs, r *rpctype.ConnectRes) er
 	}
 	r.MemoryLeakFrames = memoryLeakFrames
 	r.EnabledCalls = serv.enabledSyscalls
+	r.FeatureOverrides = serv.featureOverrides
+	r.CoverFilter = serv.coverFilter
+	r.CoverFilterWeight = serv.coverFilterWeight
+	r.SignalMode = serv.signalMode
 	r.CheckResult = serv.checkResult
 	r.GitRevision = sys.GitRevision
 	r.TargetRevision = serv.target.Revision
@@ -95,13 +110,18 @@ func (serv *RPCServer) Check(a *rpctype.CheckArgs, r *int) error {
 	if serv.checkResult != nil {
 		return nil
 	}
+	serv.mgr.machineChecked(a)
+	// Disabled calls are needed only for manager, don't send them to all fuzzers.
 	a.DisabledCalls = nil
 	serv.checkResult = a
-	serv.mgr.machineChecked(a)
 	return nil
 }
 
 func (serv *RPCServer) NewInput(a *rpctype.NewInputArgs, r *int) error {
+	if !a.Signal.Compatible(serv.signalMode) {
+		return fmt.Errorf("fuzzer %v sent signal in mode %v, but manager uses %v",
+			a.Name, a.Signal.Mode, serv.signalMode)
+	}
 	inputSignal := a.Signal.Deserialize()
 	log.Logf(4, "new input from %v for syscall %v (signal=%v, cover=%v)",
 		a.Name, a.Call, inputSignal.Len(), len(a.Cover))
@@ -136,6 +156,7 @@ func (serv *RPCServer) NewInput(a *rpctype.NewInputArgs, r *int) error {
 
 func (serv *RPCServer) Poll(a *rpctype.PollArgs, r *rpctype.PollRes) error {
 	serv.stats.mergeNamed(a.Stats)
+	serv.stats.mergeSlowCalls(a.SlowCalls)
 
 	serv.mu.Lock()
 	defer serv.mu.Unlock()
@@ -144,6 +165,10 @@ func (serv *RPCServer) Poll(a *rpctype.PollArgs, r *rpctype.PollRes) error {
 	if f == nil {
 		log.Fatalf("fuzzer %v is not connected", a.Name)
 	}
+	if !a.MaxSignal.Compatible(serv.signalMode) {
+		return fmt.Errorf("fuzzer %v sent signal in mode %v, but manager uses %v",
+			a.Name, a.MaxSignal.Mode, serv.signalMode)
+	}
 	newMaxSignal := serv.maxSignal.Diff(a.MaxSignal.Deserialize())
 	if !newMaxSignal.Empty() {
 		serv.maxSignal.Merge(newMaxSignal)
@@ -154,7 +179,7 @@ func (serv *RPCServer) Poll(a *rpctype.PollArgs, r *rpctype.PollRes) error {
 			f1.newMaxSignal.Merge(newMaxSignal)
 		}
 	}
-	r.MaxSignal = f.newMaxSignal.Split(500).Serialize()
+	r.MaxSignal = f.newMaxSignal.Split(500).Serialize(serv.signalMode)
 	if a.NeedCandidates {
 		r.Candidates = serv.mgr.candidateBatch(serv.batchSize)
 	}
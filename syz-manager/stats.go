@@ -6,6 +6,8 @@ package main
 import (
 	"sync"
 	"sync/atomic"
+
+	"github.com/google/syzkaller/pkg/rpctype"
 )
 
 type Stat uint64
@@ -29,6 +31,7 @@ type Stats struct {
 
 	mu         sync.Mutex
 	namedStats map[string]uint64
+	slowCalls  map[string]*rpctype.SlowCallStat
 }
 
 func (stats *Stats) all() map[string]uint64 {
@@ -73,6 +76,33 @@ func (stats *Stats) mergeNamed(named map[string]uint64) {
 	}
 }
 
+func (stats *Stats) mergeSlowCalls(slowCalls map[string]*rpctype.SlowCallStat) {
+	if len(slowCalls) == 0 {
+		return
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.slowCalls == nil {
+		stats.slowCalls = make(map[string]*rpctype.SlowCallStat)
+	}
+	for call, stat := range slowCalls {
+		if stats.slowCalls[call] == nil {
+			stats.slowCalls[call] = new(rpctype.SlowCallStat)
+		}
+		stats.slowCalls[call].Merge(stat)
+	}
+}
+
+func (stats *Stats) allSlowCalls() map[string]rpctype.SlowCallStat {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	m := make(map[string]rpctype.SlowCallStat)
+	for call, stat := range stats.slowCalls {
+		m[call] = *stat
+	}
+	return m
+}
+
 func (s *Stat) get() uint64 {
 	return atomic.LoadUint64((*uint64)(s))
 }
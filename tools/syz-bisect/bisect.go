@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/google/syzkaller/pkg/bisect"
+	"github.com/google/syzkaller/pkg/build"
 	"github.com/google/syzkaller/pkg/config"
 	"github.com/google/syzkaller/pkg/mgrconfig"
 )
@@ -21,18 +22,26 @@ var (
 	flagConfig = flag.String("config", "", "bisect config file")
 	flagCrash  = flag.String("crash", "", "dir with crash info")
 	flagFix    = flag.Bool("fix", false, "search for crash fix")
+	flagKconf  = flag.Bool("kconfig", false, "bisect kernel config options instead of commits"+
+		" (crash dir must contain kernel.baseline_config without the crash)")
 )
 
 type Config struct {
-	BinDir        string          `json:"bin_dir"`
-	KernelRepo    string          `json:"kernel_repo"`
-	KernelBranch  string          `json:"kernel_branch"`
-	Compiler      string          `json:"compiler"`
-	Userspace     string          `json:"userspace"`
-	Sysctl        string          `json:"sysctl"`
-	Cmdline       string          `json:"cmdline"`
-	SyzkallerRepo string          `json:"syzkaller_repo"`
-	Manager       json.RawMessage `json:"manager"`
+	BinDir         string          `json:"bin_dir"`
+	KernelRepo     string          `json:"kernel_repo"`
+	KernelRepoType string          `json:"kernel_repo_type"` // "git" (default) or "hg"
+	KernelBranch   string          `json:"kernel_branch"`
+	Compiler       string          `json:"compiler"`
+	Userspace      string          `json:"userspace"`
+	Sysctl         string          `json:"sysctl"`
+	Cmdline        string          `json:"cmdline"`
+	SyzkallerRepo  string          `json:"syzkaller_repo"`
+	Manager        json.RawMessage `json:"manager"`
+	// Dir with cache of kernel builds that is reused across bisections (optional).
+	BuildCache     string `json:"build_cache"`
+	BuildCacheSize int    `json:"build_cache_size"` // in MB, 0 means no limit
+	// Custom kernel build commands (optional, see build.CommandConfig).
+	Build *build.CommandConfig `json:"build"`
 }
 
 func main() {
@@ -63,21 +72,38 @@ func main() {
 		DebugDir: *flagCrash,
 		Kernel: bisect.KernelConfig{
 			Repo:      mycfg.KernelRepo,
+			RepoType:  mycfg.KernelRepoType,
 			Branch:    mycfg.KernelBranch,
 			Userspace: mycfg.Userspace,
 			Sysctl:    mycfg.Sysctl,
 			Cmdline:   mycfg.Cmdline,
+			Build:     mycfg.Build,
 		},
 		Syzkaller: bisect.SyzkallerConfig{
 			Repo: mycfg.SyzkallerRepo,
 		},
 		Manager: *mgrcfg,
 	}
+	if mycfg.BuildCache != "" {
+		cfg.BuildCache, err = build.NewCache(mycfg.BuildCache, int64(mycfg.BuildCacheSize)<<20)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 	loadString("syzkaller.commit", &cfg.Syzkaller.Commit)
 	loadString("kernel.commit", &cfg.Kernel.Commit)
 	loadFile("kernel.config", &cfg.Kernel.Config)
 	loadFile("repro.syz", &cfg.Repro.Syz)
 	loadFile("repro.opts", &cfg.Repro.Opts)
+	if *flagKconf {
+		loadFile("kernel.baseline_config", &cfg.Kernel.BaselineConfig)
+		if _, err := bisect.RunConfig(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "config bisection failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	if _, err := bisect.Run(cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "bisection failed: %v\n", err)
 		os.Exit(1)
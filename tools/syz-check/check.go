@@ -0,0 +1,80 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// syz-check checks what features and syscalls are supported on the machine
+// and explains why syscalls are disabled (including transitive dependencies).
+// It needs to run on the test machine (e.g. copy it into the image), similar to syz-stress.
+// Usage:
+//
+//	syz-check [-sandbox=none] [-enable=open,ioctl$*] [-disable=...]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/host"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/prog"
+	_ "github.com/google/syzkaller/sys"
+)
+
+var (
+	flagOS      = flag.String("os", runtime.GOOS, "target os")
+	flagArch    = flag.String("arch", runtime.GOARCH, "target arch")
+	flagSandbox = flag.String("sandbox", "none", "sandbox to check syscalls for")
+	flagEnable  = flag.String("enable", "", "comma-separated list of enabled syscalls (as in manager config)")
+	flagDisable = flag.String("disable", "", "comma-separated list of disabled syscalls (as in manager config)")
+)
+
+func main() {
+	flag.Parse()
+	target, err := prog.GetTarget(*flagOS, *flagArch)
+	if err != nil {
+		failf("%v", err)
+	}
+	features, err := host.Check(target)
+	if err != nil {
+		failf("failed to check features: %v", err)
+	}
+	fmt.Printf("features:\n")
+	for i, feat := range features {
+		fmt.Printf("  %-30v %-8v %v\n", host.FeatureIDs[i], feat.Enabled, feat.Reason)
+	}
+	ids, err := mgrconfig.ParseEnabledSyscalls(target, splitList(*flagEnable), splitList(*flagDisable))
+	if err != nil {
+		failf("%v", err)
+	}
+	enabled := make(map[*prog.Syscall]bool)
+	for _, id := range ids {
+		enabled[target.Syscalls[id]] = true
+	}
+	_, unsupported, err := host.DetectSupportedSyscalls(target, *flagSandbox)
+	if err != nil {
+		failf("failed to detect supported syscalls: %v", err)
+	}
+	supported, disabled := host.CheckSyscalls(target, enabled, unsupported)
+	var lines []string
+	for c, reason := range disabled {
+		lines = append(lines, fmt.Sprintf("  %v: %v\n", c.Name, reason))
+	}
+	sort.Strings(lines)
+	fmt.Printf("disabled syscalls:\n%v", strings.Join(lines, ""))
+	fmt.Printf("enabled %v/%v syscalls (sandbox %v)\n", len(supported), len(enabled), *flagSandbox)
+}
+
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+func failf(msg string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, msg+"\n", args...)
+	os.Exit(1)
+}
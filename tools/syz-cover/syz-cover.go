@@ -1,7 +1,8 @@
 // Copyright 2018 syzkaller project authors. All rights reserved.
 // Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
 
-// syz-cover generates coverage HTML report from raw coverage files.
+// syz-cover generates coverage HTML report (or lcov tracefile, JSON summary, or per-function table)
+// from raw coverage files.
 // Raw coverage files are text files with one PC in hex form per line, e.g.:
 //
 //	0xffffffff8398658d
@@ -11,8 +12,15 @@
 // Raw coverage files can be obtained either from /rawcover manager HTTP handler,
 // or from syz-execprog with -coverfile flag.
 //
+// HTML report is opened in browser, other reports are written to stdout
+// or to the file specified with -out flag.
+//
+// With -diff flag syz-cover compares two raw coverage files (old and new) and prints
+// functions which coverage differs along with newly covered and lost source lines.
+//
 // Usage:
-//	syz-cover [-os=OS -arch=ARCH -kernel_src=. -kernel_obj=.] rawcover.file*
+//	syz-cover [-os=OS -arch=ARCH -kernel_src=. -kernel_obj=.] [-format=html|lcov|json|funcs -out=file] rawcover.file*
+//	syz-cover [-os=OS -arch=ARCH -kernel_src=. -kernel_obj=.] -diff old.rawcover new.rawcover
 package main
 
 import (
@@ -26,6 +34,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 
 	"github.com/google/syzkaller/pkg/cover"
 	"github.com/google/syzkaller/pkg/osutil"
@@ -38,6 +47,10 @@ func main() {
 		flagArch      = flag.String("arch", runtime.GOARCH, "target arch")
 		flagKernelSrc = flag.String("kernel_src", "", "path to kernel sources")
 		flagKernelObj = flag.String("kernel_obj", "", "path to kernel build/obj dir")
+		flagFormat    = flag.String("format", cover.FormatHTML,
+			fmt.Sprintf("report format (%v)", strings.Join(cover.Formats, ", ")))
+		flagOut  = flag.String("out", "", "output file (by default HTML is opened in browser, other formats go to stdout)")
+		flagDiff = flag.Bool("diff", false, "print coverage diff between two raw coverage files")
 	)
 	flag.Parse()
 
@@ -56,9 +69,8 @@ func main() {
 	if target == nil {
 		failf("unknown target %v/%v", *flagOS, *flagArch)
 	}
-	pcs, err := readPCs(flag.Args())
-	if err != nil {
-		failf("%v", err)
+	if *flagDiff && len(flag.Args()) != 2 {
+		failf("-diff requires exactly 2 raw coverage files")
 	}
 	kernelObj := filepath.Join(*flagKernelObj, target.KernelObject)
 	rg, err := cover.MakeReportGenerator(kernelObj, *flagKernelSrc, *flagArch)
@@ -66,8 +78,40 @@ func main() {
 		failf("%v", err)
 	}
 	buf := new(bytes.Buffer)
-	if err := rg.Do(buf, pcs); err != nil {
-		failf("%v", err)
+	if *flagDiff {
+		oldPCs, err := readPCs(flag.Args()[:1])
+		if err != nil {
+			failf("%v", err)
+		}
+		newPCs, err := readPCs(flag.Args()[1:])
+		if err != nil {
+			failf("%v", err)
+		}
+		diff, err := rg.Diff(oldPCs, newPCs)
+		if err != nil {
+			failf("%v", err)
+		}
+		if err := cover.WriteDiff(buf, diff); err != nil {
+			failf("%v", err)
+		}
+	} else {
+		pcs, err := readPCs(flag.Args())
+		if err != nil {
+			failf("%v", err)
+		}
+		if err := rg.DoFormat(buf, pcs, *flagFormat); err != nil {
+			failf("%v", err)
+		}
+	}
+	if *flagOut != "" {
+		if err := osutil.WriteFile(*flagOut, buf.Bytes()); err != nil {
+			failf("%v", err)
+		}
+		return
+	}
+	if *flagDiff || *flagFormat != cover.FormatHTML {
+		os.Stdout.Write(buf.Bytes())
+		return
 	}
 	fn, err := osutil.TempFile("syz-cover")
 	if err != nil {
@@ -35,6 +35,8 @@ var (
 	flagFaultCall = flag.Int("fault_call", -1, "inject fault into this call (0-based)")
 	flagFaultNth  = flag.Int("fault_nth", 0, "inject fault on n-th operation (0-based)")
 	flagHints     = flag.Bool("hints", false, "do a hints-generation run")
+	flagParallel  = flag.Bool("parallel", false, "execute each program in a separate proc concurrently"+
+		" (the number of procs is set to the number of programs)")
 )
 
 func main() {
@@ -69,17 +71,22 @@ func main() {
 	}
 	config, execOpts := createConfig(target, entries, features)
 
+	procs := *flagProcs
+	if *flagParallel {
+		procs = len(entries)
+	}
 	ctx := &Context{
 		entries:  entries,
 		config:   config,
 		execOpts: execOpts,
-		gate:     ipc.NewGate(2**flagProcs, nil),
+		gate:     ipc.NewGate(2*procs, nil),
 		shutdown: make(chan struct{}),
 		repeat:   *flagRepeat,
+		parallel: *flagParallel,
 	}
 	var wg sync.WaitGroup
-	wg.Add(*flagProcs)
-	for p := 0; p < *flagProcs; p++ {
+	wg.Add(procs)
+	for p := 0; p < procs; p++ {
 		pid := p
 		go func() {
 			defer wg.Done()
@@ -99,6 +106,7 @@ type Context struct {
 	logMu     sync.Mutex
 	posMu     sync.Mutex
 	repeat    int
+	parallel  bool
 	pos       int
 	lastPrint time.Time
 }
@@ -109,12 +117,20 @@ func (ctx *Context) run(pid int) {
 		log.Fatalf("failed to create ipc env: %v", err)
 	}
 	defer env.Close()
-	for {
+	for iter := 0; ; iter++ {
 		select {
 		case <-ctx.shutdown:
 			return
 		default:
 		}
+		if ctx.parallel {
+			// Each proc executes only its own program.
+			if ctx.repeat > 0 && iter >= ctx.repeat {
+				return
+			}
+			ctx.execute(pid, env, ctx.entries[pid])
+			continue
+		}
 		idx := ctx.getProgramIndex()
 		if ctx.repeat > 0 && idx >= len(ctx.entries)*ctx.repeat {
 			return
@@ -188,8 +204,8 @@ func (ctx *Context) printCallResults(info *ipc.ProgInfo) {
 		if inf.Flags&ipc.CallFaultInjected != 0 {
 			flags += " faulted"
 		}
-		log.Logf(1, "CALL %v: signal %v, coverage %v errno %v%v",
-			i, len(inf.Signal), len(inf.Cover), inf.Errno, flags)
+		log.Logf(1, "CALL %v: signal %v, coverage %v errno %v time %v cpu %v%v",
+			i, len(inf.Signal), len(inf.Cover), inf.Errno, inf.Time, inf.CPUTime, flags)
 	}
 }
 
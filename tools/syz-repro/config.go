@@ -0,0 +1,202 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/prog"
+)
+
+// Flags used to create config when no manager config is given.
+var (
+	flagTarget   = flag.String("target", "", "target OS/arch, e.g. linux/amd64 (auto-detected from the log by default)")
+	flagVM       = flag.String("vm", "qemu", "VM type")
+	flagVMConfig = flag.String("vm_config", "", "VM-type-specific config in JSON"+
+		" (kernel and count are filled from flags)")
+	flagKernel    = flag.String("kernel", "", "kernel image to boot (e.g. arch/x86/boot/bzImage)")
+	flagImage     = flag.String("image", "", "disk image")
+	flagSSHKey    = flag.String("sshkey", "", "ssh key for the image")
+	flagKernelObj = flag.String("kernel_obj", "", "directory with vmlinux for report symbolization (optional)")
+	flagSyzkaller = flag.String("syzkaller", "", "syzkaller checkout with built binaries"+
+		" (by default the one that contains this binary)")
+	flagWorkdir = flag.String("workdir", "", "working directory (temp dir by default)")
+	flagSandbox = flag.String("sandbox", "none", "sandbox to use (none/setuid/namespace)")
+)
+
+// createConfig synthesizes a minimal manager config to reproduce crash in crashLog from flags.
+// If -workdir is not specified, the config uses a new temp workdir that the caller needs to remove.
+func createConfig(crashLog []byte) (*mgrconfig.Config, error) {
+	if *flagImage == "" {
+		return nil, fmt.Errorf("no manager config and no -image specified")
+	}
+	t, err := configTarget(crashLog)
+	if err != nil {
+		return nil, err
+	}
+	syzkaller := *flagSyzkaller
+	if syzkaller == "" {
+		exe, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate syzkaller checkout: %v", err)
+		}
+		// The binary is in syzkaller/bin/.
+		syzkaller = filepath.Dir(filepath.Dir(exe))
+	}
+	workdir := *flagWorkdir
+	if workdir == "" {
+		dir, err := ioutil.TempDir("", "syz-repro")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workdir: %v", err)
+		}
+		workdir = dir
+	}
+	cfg, err := loadConfig(t, crashLog, syzkaller, workdir)
+	if err != nil && *flagWorkdir == "" {
+		os.RemoveAll(workdir)
+	}
+	return cfg, err
+}
+
+// configTarget returns the target given with -target or the one detected from crashLog.
+func configTarget(crashLog []byte) (*prog.Target, error) {
+	if *flagTarget == "" {
+		t, err := detectTarget(crashLog)
+		if err != nil {
+			return nil, err
+		}
+		log.Logf(0, "detected target %v/%v", t.OS, t.Arch)
+		return t, nil
+	}
+	parts := strings.Split(*flagTarget, "/")
+	return prog.GetTarget(parts[0], parts[len(parts)-1])
+}
+
+func loadConfig(t *prog.Target, crashLog []byte, syzkaller, workdir string) (*mgrconfig.Config, error) {
+	cfg, err := makeConfig(t, crashLog, syzkaller, workdir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	log.Logf(1, "using config: %s", data)
+	return mgrconfig.LoadData(data)
+}
+
+// makeConfig returns manager config contents for target t in the form of a JSON object.
+func makeConfig(t *prog.Target, crashLog []byte, syzkaller, workdir string) (map[string]interface{}, error) {
+	vmCfg := make(map[string]interface{})
+	if *flagVMConfig != "" {
+		if err := json.Unmarshal([]byte(*flagVMConfig), &vmCfg); err != nil {
+			return nil, fmt.Errorf("failed to parse -vm_config: %v", err)
+		}
+	}
+	if *flagKernel != "" {
+		vmCfg["kernel"] = *flagKernel
+	}
+	if _, ok := vmCfg["count"]; !ok {
+		vmCfg["count"] = 4
+	}
+	if *flagCount > 0 {
+		vmCfg["count"] = *flagCount
+	}
+	cfg := map[string]interface{}{
+		"target":     t.OS + "/" + t.Arch,
+		"http":       "127.0.0.1:0",
+		"workdir":    workdir,
+		"syzkaller":  syzkaller,
+		"image":      *flagImage,
+		"sshkey":     *flagSSHKey,
+		"kernel_obj": *flagKernelObj,
+		"procs":      logProcs(t, crashLog),
+		"sandbox":    *flagSandbox,
+		"reproduce":  true,
+		"type":       *flagVM,
+		"vm":         vmCfg,
+	}
+	return cfg, nil
+}
+
+// archMarkers match console output that identifies the kernel arch
+// (uname/boot banners and register dumps in oops messages).
+var archMarkers = map[string]*regexp.Regexp{
+	"amd64":   regexp.MustCompile(`\b(x86_64|amd64)\b|\bRIP: `),
+	"386":     regexp.MustCompile(`\b(i[3-6]86)\b|\bEIP: `),
+	"arm64":   regexp.MustCompile(`\b(aarch64|arm64)\b`),
+	"arm":     regexp.MustCompile(`\b(armv[5-7]\w*)\b`),
+	"ppc64le": regexp.MustCompile(`\b(ppc64le|powerpc64le)\b`),
+}
+
+// detectTarget returns the target that is able to parse the most programs from crashLog.
+// If several targets parse the same number of programs, the arch is detected from
+// the console output in crashLog. If it's still ambiguous, the target needs to be
+// given explicitly with -target.
+func detectTarget(crashLog []byte) (*prog.Target, error) {
+	var best []*prog.Target
+	bestCalls := 0
+	for _, target := range prog.AllTargets() {
+		if target.OS == "test" {
+			continue
+		}
+		calls := 0
+		for _, ent := range target.ParseLog(crashLog) {
+			calls += len(ent.P.Calls)
+		}
+		if calls == 0 || calls < bestCalls {
+			continue
+		}
+		if calls > bestCalls {
+			best, bestCalls = nil, calls
+		}
+		best = append(best, target)
+	}
+	if len(best) == 0 {
+		return nil, fmt.Errorf("failed to detect target: the log does not contain programs for any target")
+	}
+	if len(best) == 1 {
+		return best[0], nil
+	}
+	var matched []*prog.Target
+	for _, target := range best {
+		if re := archMarkers[target.Arch]; re != nil && re.Match(crashLog) {
+			matched = append(matched, target)
+		}
+	}
+	if len(matched) == 1 {
+		return matched[0], nil
+	}
+	var names []string
+	for _, target := range best {
+		names = append(names, target.OS+"/"+target.Arch)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("failed to detect target: the log matches %v, specify -target",
+		strings.Join(names, ", "))
+}
+
+// logProcs returns the number of procs that executed programs in crashLog.
+func logProcs(target *prog.Target, crashLog []byte) int {
+	procs := 1
+	for _, ent := range target.ParseLog(crashLog) {
+		if ent.Proc+1 > procs {
+			procs = ent.Proc + 1
+		}
+	}
+	if procs > 32 {
+		procs = 32
+	}
+	return procs
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fchroot is present only in netbsd descriptions, getpid is present in most OSes.
+const netbsdLog = `
+[   12.345678] random kernel output
+2018/01/01 00:00:00 executing program 0:
+getpid()
+2018/01/01 00:00:01 executing program 5:
+fchroot(0xffffffffffffffff)
+getpid()
+[   13.345678] BUG: something bad happened
+`
+
+func TestDetectTarget(t *testing.T) {
+	target, err := detectTarget([]byte(netbsdLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.OS != "netbsd" || target.Arch != "amd64" {
+		t.Fatalf("detected %v/%v, want netbsd/amd64", target.OS, target.Arch)
+	}
+	if procs := logProcs(target, []byte(netbsdLog)); procs != 6 {
+		t.Fatalf("got %v procs, want 6", procs)
+	}
+	if _, err := detectTarget([]byte("no programs here\n")); err == nil {
+		t.Fatalf("detected target in a log without programs")
+	}
+}
+
+// linuxLog can be parsed by all linux arches, the arch is only known from the console output.
+const linuxLog = `
+2018/01/01 00:00:00 executing program 0:
+keyctl$get_keyring_id(0x0, 0x0, 0x0)
+getpid()
+`
+
+func TestDetectTargetArch(t *testing.T) {
+	if target, err := detectTarget([]byte(linuxLog)); err == nil {
+		t.Fatalf("detected %v/%v for a log that matches several arches", target.OS, target.Arch)
+	}
+	tests := map[string]string{
+		"[    0.000000] Linux version 4.19.0 (gcc) #1 SMP\n[    0.000000] x86_64 features\n": "amd64",
+		"[   10.000000] RIP: 0010:foo+0x10/0x20\n":                                           "amd64",
+		"[   10.000000] EIP: foo+0x10/0x20\n":                                                "386",
+		"Linux syzkaller 4.19.0 #1 SMP aarch64 GNU/Linux\n":                                  "arm64",
+	}
+	for console, arch := range tests {
+		target, err := detectTarget([]byte(console + linuxLog))
+		if err != nil {
+			t.Errorf("%q: %v", console, err)
+			continue
+		}
+		if target.OS != "linux" || target.Arch != arch {
+			t.Errorf("%q: detected %v/%v, want linux/%v", console, target.OS, target.Arch, arch)
+		}
+	}
+}
+
+func TestCreateConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syz-repro-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	*flagImage = filepath.Join(dir, "image")
+	*flagKernel = filepath.Join(dir, "kernel")
+	*flagVMConfig = `{"count": 2, "cpu": 4}`
+	defer func() {
+		*flagImage = ""
+		*flagKernel = ""
+		*flagVMConfig = ""
+	}()
+
+	// Workdir is temp dir by default and it is removed if the config is bad.
+	*flagSandbox = "bad"
+	tmpDirs, err := filepath.Glob(filepath.Join(os.TempDir(), "syz-repro[0-9]*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createConfig([]byte(netbsdLog)); err == nil {
+		t.Fatalf("created config with bad sandbox")
+	}
+	*flagSandbox = "none"
+	tmpDirs1, err := filepath.Glob(filepath.Join(os.TempDir(), "syz-repro[0-9]*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmpDirs1) != len(tmpDirs) {
+		t.Fatalf("workdir of bad config was not removed: %v -> %v", tmpDirs, tmpDirs1)
+	}
+
+	target, err := configTarget([]byte(netbsdLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := makeConfig(target, []byte(netbsdLog), "syzkaller", "workdir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg["target"] != "netbsd/amd64" || cfg["type"] != "qemu" || cfg["procs"] != 6 ||
+		cfg["image"] != *flagImage || cfg["syzkaller"] != "syzkaller" || cfg["workdir"] != "workdir" {
+		t.Fatalf("bad config: %+v", cfg)
+	}
+	vm := cfg["vm"].(map[string]interface{})
+	if vm["count"] != 2.0 || vm["cpu"] != 4.0 || vm["kernel"] != *flagKernel || len(vm) != 3 {
+		t.Fatalf("bad vm config: %+v", vm)
+	}
+	*flagVMConfig = "{"
+	if _, err := makeConfig(target, []byte(netbsdLog), "syzkaller", "workdir"); err == nil {
+		t.Fatalf("created config with bad -vm_config")
+	}
+
+	// Explicit target.
+	*flagTarget = "linux/arm64"
+	defer func() {
+		*flagTarget = ""
+	}()
+	target, err = configTarget([]byte("no programs\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.OS != "linux" || target.Arch != "arm64" || logProcs(target, []byte("no programs\n")) != 1 {
+		t.Fatalf("bad target %v/%v", target.OS, target.Arch)
+	}
+	*flagImage = ""
+	if _, err := createConfig([]byte(netbsdLog)); err == nil {
+		t.Fatalf("created config without image")
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"io/ioutil"
 	"os"
 
-	"github.com/google/syzkaller/pkg/csource"
 	"github.com/google/syzkaller/pkg/log"
 	"github.com/google/syzkaller/pkg/mgrconfig"
 	"github.com/google/syzkaller/pkg/osutil"
@@ -23,29 +22,54 @@ var (
 	flagConfig = flag.String("config", "", "manager configuration file (manager.cfg)")
 	flagCount  = flag.Int("count", 0, "number of VMs to use (overrides config count param)")
 	flagDebug  = flag.Bool("debug", false, "print debug output")
+	flagResume = flag.String("resume", "", "checkpoint file to save progress to and to resume from if it exists")
+	flagRuns   = flag.Int("reliability", -1, "number of runs to measure reliability of the final reproducer"+
+		" (overrides config repro_reliability_runs param)")
 )
 
 func main() {
 	os.Args = append(append([]string{}, os.Args[0], "-v=10"), os.Args[1:]...)
 	flag.Parse()
-	if len(flag.Args()) != 1 || *flagConfig == "" {
-		log.Fatalf("usage: syz-repro -config=manager.cfg execution.log")
-	}
-	cfg, err := mgrconfig.LoadFile(*flagConfig)
-	if err != nil {
-		log.Fatalf("%v: %v", *flagConfig, err)
+	if len(flag.Args()) != 1 {
+		log.Fatalf("usage: syz-repro -config=manager.cfg execution.log\n" +
+			"   or: syz-repro -image=disk.img -sshkey=key [-kernel=bzImage] [-vm=qemu] console.log")
 	}
 	logFile := flag.Args()[0]
 	data, err := ioutil.ReadFile(logFile)
 	if err != nil {
 		log.Fatalf("failed to open log file %v: %v", logFile, err)
 	}
-	if _, err := prog.GetTarget(cfg.TargetOS, cfg.TargetArch); err != nil {
+	var cfg *mgrconfig.Config
+	if *flagConfig != "" {
+		cfg, err = mgrconfig.LoadFile(*flagConfig)
+		if err != nil {
+			log.Fatalf("%v: %v", *flagConfig, err)
+		}
+	} else {
+		cfg, err = createConfig(data)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	err = reproduce(cfg, data)
+	if *flagConfig == "" && *flagWorkdir == "" {
+		os.RemoveAll(cfg.Workdir)
+	}
+	if err != nil {
 		log.Fatalf("%v", err)
 	}
+}
+
+func reproduce(cfg *mgrconfig.Config, data []byte) error {
+	if *flagRuns >= 0 {
+		cfg.ReproReliabilityRuns = *flagRuns
+	}
+	if _, err := prog.GetTarget(cfg.TargetOS, cfg.TargetArch); err != nil {
+		return err
+	}
 	vmPool, err := vm.Create(cfg, *flagDebug)
 	if err != nil {
-		log.Fatalf("%v", err)
+		return err
 	}
 	vmCount := vmPool.Count()
 	if *flagCount > 0 && *flagCount < vmCount {
@@ -60,35 +84,42 @@ func main() {
 	}
 	reporter, err := report.NewReporter(cfg)
 	if err != nil {
-		log.Fatalf("%v", err)
+		return err
 	}
 	osutil.HandleInterrupts(vm.Shutdown)
 
-	res, stats, err := repro.Run(data, cfg, reporter, vmPool, vmIndexes)
+	res, stats, err := repro.Run(data, cfg, reporter, vmPool, vmIndexes, *flagResume, nil)
 	if err != nil {
 		log.Logf(0, "reproduction failed: %v", err)
 	}
 	if stats != nil {
 		fmt.Printf("Extracting prog: %v\n", stats.ExtractProgTime)
+		fmt.Printf("Extracting parallel progs: %v\n", stats.ParallelTime)
 		fmt.Printf("Minimizing prog: %v\n", stats.MinimizeProgTime)
 		fmt.Printf("Simplifying prog options: %v\n", stats.SimplifyProgTime)
 		fmt.Printf("Extracting C: %v\n", stats.ExtractCTime)
 		fmt.Printf("Simplifying C: %v\n", stats.SimplifyCTime)
+		fmt.Printf("Measuring reliability: %v\n", stats.ReliabilityTime)
+		fmt.Printf("Syz repro reliability: %v\n", stats.SyzReliability)
+		fmt.Printf("C repro reliability: %v\n", stats.CReliability)
 	}
 	if res == nil {
-		return
+		return nil
 	}
 
 	fmt.Printf("opts: %+v crepro: %v\n\n", res.Opts, res.CRepro)
-	fmt.Printf("%s\n", res.Prog.Serialize())
+	fmt.Printf("%s\n", res.Serialize())
 	if res.CRepro {
-		src, err := csource.Write(res.Prog, res.Opts)
+		srcs, err := res.CSources()
 		if err != nil {
-			log.Fatalf("failed to generate C repro: %v", err)
+			return fmt.Errorf("failed to generate C repro: %v", err)
 		}
-		if formatted, err := csource.Format(src); err == nil {
-			src = formatted
+		for i, src := range srcs {
+			if len(srcs) > 1 {
+				fmt.Printf("// program %v (run concurrently with the others):\n", i)
+			}
+			fmt.Printf("%s\n", src)
 		}
-		fmt.Printf("%s\n", src)
 	}
+	return nil
 }
@@ -204,6 +204,7 @@ func (mgr *Manager) Connect(a *rpctype.ConnectArgs, r *rpctype.ConnectRes) error
 	r.GitRevision = sys.GitRevision
 	r.TargetRevision = mgr.target.Revision
 	r.AllSandboxes = true
+	r.FeatureOverrides = mgr.cfg.Features
 	select {
 	case <-mgr.checkResultReady:
 		r.CheckResult = mgr.checkResult
@@ -37,6 +37,17 @@ type Config struct {
 	ImageDevice string `json:"image_device"` // qemu image device (hda by default)
 	CPU         int    `json:"cpu"`          // number of VM CPUs
 	Mem         int    `json:"mem"`          // amount of VM memory in MBs
+	// Snapshot mode: VM state is saved once the machine is set up and is restored
+	// every SnapshotEvery programs (with savevm/loadvm via qemu monitor),
+	// so that every program starts in a clean machine.
+	// In this mode fuzzer runs on host (so host OS/arch must match the target)
+	// and talks to executor over a virtio-serial port (see ipc.FlagRemote), as the result it requires procs=1
+	// and does not support machine features that need setup inside of the VM (see syz-fuzzer).
+	// The snapshot is taken when executor is started and ready to execute programs.
+	// The image must be in qcow2 format (savevm is not supported for other formats)
+	// and must have udev, the kernel needs CONFIG_VIRTIO_CONSOLE.
+	Snapshot      bool `json:"snapshot"`
+	SnapshotEvery int  `json:"snapshot_every"` // restore snapshot every that many programs (1 by default)
 }
 
 type Pool struct {
@@ -169,10 +180,11 @@ var linuxCmdline = []string{
 func ctor(env *vmimpl.Env) (vmimpl.Pool, error) {
 	archConfig := archConfigs[env.OS+"/"+env.Arch]
 	cfg := &Config{
-		Count:       1,
-		ImageDevice: "hda",
-		Qemu:        archConfig.Qemu,
-		QemuArgs:    archConfig.QemuArgs,
+		Count:         1,
+		ImageDevice:   "hda",
+		Qemu:          archConfig.Qemu,
+		QemuArgs:      archConfig.QemuArgs,
+		SnapshotEvery: 1,
 	}
 	if err := config.LoadData(env.Config, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse qemu vm config: %v", err)
@@ -205,6 +217,21 @@ func ctor(env *vmimpl.Env) (vmimpl.Pool, error) {
 	if cfg.Mem < 128 || cfg.Mem > 1048576 {
 		return nil, fmt.Errorf("bad qemu mem: %v, want [128-1048576]", cfg.Mem)
 	}
+	if cfg.Snapshot {
+		// savevm needs a disk that supports snapshots and virtio-9p blocks migration.
+		if env.Image == "9p" {
+			return nil, fmt.Errorf("snapshot mode does not support 9p image")
+		}
+		if archConfig.HostFuzzer {
+			return nil, fmt.Errorf("snapshot mode is not supported on %v/%v", env.OS, env.Arch)
+		}
+		if cfg.SnapshotEvery <= 0 {
+			return nil, fmt.Errorf("bad qemu snapshot_every: %v, want >=1", cfg.SnapshotEvery)
+		}
+		if err := checkSnapshotImage(env.Image); err != nil {
+			return nil, err
+		}
+	}
 	cfg.Kernel = osutil.Abs(cfg.Kernel)
 	cfg.Initrd = osutil.Abs(cfg.Initrd)
 	pool := &Pool{
@@ -314,6 +341,14 @@ func (inst *instance) Boot() error {
 		"-serial", "stdio",
 		"-no-reboot",
 	}
+	if inst.cfg.Snapshot {
+		args = append(args,
+			"-monitor", "unix:"+inst.monitorFile()+",server,nowait",
+			"-device", "virtio-serial",
+			"-chardev", "socket,id=syz-executor,path="+inst.executorConnFile()+",server,nowait",
+			"-device", "virtserialport,chardev=syz-executor,name="+snapshotExecutorPort,
+		)
+	}
 	if inst.cfg.QemuArgs != "" {
 		args = append(args, strings.Split(inst.cfg.QemuArgs, " ")...)
 	}
@@ -397,9 +432,48 @@ func (inst *instance) Boot() error {
 	return nil
 }
 
+// checkSnapshotImage checks that the image supports savevm/loadvm.
+func checkSnapshotImage(image string) error {
+	f, err := os.Open(image)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil || string(magic) != "QFI\xfb" {
+		return fmt.Errorf("snapshot mode requires qcow2 image, %v is not qcow2"+
+			" (convert it with: qemu-img convert -O qcow2)", image)
+	}
+	return nil
+}
+
+// hostFuzzer says if Go binaries (fuzzer and execprog) run on host rather than in the VM.
+func (inst *instance) hostFuzzer() bool {
+	return inst.archConfig.HostFuzzer || inst.cfg.Snapshot
+}
+
+// onHost says if the binary runs on host.
+// In snapshot mode only fuzzer runs on host (it needs to restore the VM),
+// execprog runs in the VM because it uses shared memory to talk to executor.
+func (inst *instance) onHost(bin string) bool {
+	return inst.archConfig.HostFuzzer && (bin == "syz-fuzzer" || bin == "syz-execprog") ||
+		inst.cfg.Snapshot && bin == "syz-fuzzer"
+}
+
+func (inst *instance) monitorFile() string {
+	return filepath.Join(inst.workdir, "monitor")
+}
+
+// executorConnFile is host end of the virtio-serial port used by executor in snapshot mode.
+func (inst *instance) executorConnFile() string {
+	return filepath.Join(inst.workdir, "executor.sock")
+}
+
+const snapshotExecutorPort = "syz-executor"
+
 func (inst *instance) Forward(port int) (string, error) {
 	addr := hostAddr
-	if inst.archConfig.HostFuzzer {
+	if inst.hostFuzzer() {
 		addr = "127.0.0.1"
 	}
 	return fmt.Sprintf("%v:%v", addr, port), nil
@@ -415,8 +489,8 @@ func (inst *instance) targetDir() string {
 func (inst *instance) Copy(hostSrc string) (string, error) {
 	base := filepath.Base(hostSrc)
 	vmDst := filepath.Join(inst.targetDir(), base)
-	if inst.archConfig.HostFuzzer {
-		if base == "syz-fuzzer" || base == "syz-execprog" {
+	if inst.hostFuzzer() {
+		if inst.onHost(base) {
 			return hostSrc, nil // we will run these on host
 		}
 		if inst.files == nil {
@@ -447,20 +521,34 @@ func (inst *instance) Run(timeout time.Duration, stop <-chan bool, command strin
 
 	sshArgs := vmimpl.SSHArgs(inst.debug, inst.sshkey, inst.port)
 	args := strings.Split(command, " ")
-	if bin := filepath.Base(args[0]); inst.archConfig.HostFuzzer &&
-		(bin == "syz-fuzzer" || bin == "syz-execprog") {
-		// Weird mode for akaros.
+	if bin := filepath.Base(args[0]); inst.onHost(bin) {
+		// Weird mode for akaros and snapshot mode.
 		// Fuzzer and execprog are on host (we did not copy them), so we will run them as is,
 		// but we will also wrap executor with ssh invocation.
 		for i, arg := range args {
 			if strings.HasPrefix(arg, "-executor=") {
+				executor := arg[len("-executor="):]
+				if inst.cfg.Snapshot {
+					// Executor runs in background with control pipes connected to the virtio-serial port,
+					// ssh is used only to start it. The port can be opened only once.
+					port := "/dev/virtio-ports/" + snapshotExecutorPort
+					executor = "nohup " + executor + " <>" + port + " >&0 2>/dev/console &"
+				}
 				args[i] = "-executor=" + "/usr/bin/ssh " + strings.Join(sshArgs, " ") +
-					" " + inst.sshuser + "@localhost " + arg[len("-executor="):]
+					" " + inst.sshuser + "@localhost " + executor
 			}
 			if host := inst.files[arg]; host != "" {
 				args[i] = host
 			}
 		}
+		if inst.cfg.Snapshot && bin == "syz-fuzzer" {
+			// This also makes fuzzer use remote executor mode.
+			args = append(args,
+				"-snapshot_monitor="+inst.monitorFile(),
+				"-snapshot_conn="+inst.executorConnFile(),
+				fmt.Sprintf("-snapshot_every=%v", inst.cfg.SnapshotEvery),
+			)
+		}
 	} else {
 		args = []string{"ssh"}
 		args = append(args, sshArgs...)